@@ -0,0 +1,70 @@
+package main
+
+import (
+	"flag"
+	"log"
+
+	"github.com/rakeshkumar/ridesapp/pkg/config"
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// createadmin bootstraps an admin account. Admins cannot be created through the API,
+// so the first one is created (or an existing user promoted) by an operator with database access.
+func main() {
+	email := flag.String("email", "", "email of the admin account")
+	password := flag.String("password", "", "password for a new account (ignored when promoting an existing user)")
+	flag.Parse()
+
+	if *email == "" {
+		log.Fatal("Usage: createadmin -email admin@example.com [-password secret]")
+	}
+
+	// Load configuration
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+
+	// Initialize database
+	err = database.InitDB(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize database: %v", err)
+	}
+
+	userRepo := repository.NewUserRepository()
+
+	// Promote the user if the account already exists
+	if user, err := userRepo.GetUserByEmail(*email); err == nil {
+		if err := userRepo.UpdateUserRole(user.ID, models.RoleAdmin); err != nil {
+			log.Fatalf("Failed to promote user: %v", err)
+		}
+		log.Printf("Promoted user %d (%s) to admin", user.ID, user.Email)
+		return
+	}
+
+	if len(*password) < 8 {
+		log.Fatal("A password of at least 8 characters is required to create a new admin")
+	}
+
+	admin := &models.User{
+		Email:      *email,
+		Password:   *password,
+		FirstName:  "Admin",
+		LastName:   "User",
+		Role:       models.RoleAdmin,
+		IsVerified: true,
+	}
+
+	// Hash the password
+	if err := admin.HashPassword(); err != nil {
+		log.Fatalf("Failed to hash password: %v", err)
+	}
+
+	if err := userRepo.CreateUser(admin); err != nil {
+		log.Fatalf("Failed to create admin: %v", err)
+	}
+
+	log.Printf("Created admin user with ID: %d", admin.ID)
+}
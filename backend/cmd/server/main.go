@@ -2,12 +2,15 @@ package main
 
 import (
 	"log"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rakeshkumar/ridesapp/pkg/config"
 	"github.com/rakeshkumar/ridesapp/pkg/database"
 	"github.com/rakeshkumar/ridesapp/pkg/handlers"
 	"github.com/rakeshkumar/ridesapp/pkg/middleware"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/services"
 )
 
 func main() {
@@ -23,6 +26,16 @@ func main() {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 
+	// Start background jobs
+	services.RunPeriodically("account deletions", time.Hour, services.ProcessAccountDeletions)
+	services.RunPeriodically("hours of service", time.Minute, services.EnforceHoursOfService)
+	services.RunPeriodically("demand heatmap", 5*time.Minute, services.RefreshDemandHeatmap)
+	services.RunPeriodically("join request expiry", time.Minute, services.ExpireJoinRequests)
+	services.RunPeriodically("recurring rides", time.Hour, services.MaterializeRecurringRides)
+	services.RunPeriodically("waitlists", time.Minute, services.ProcessWaitlists)
+	services.RunPeriodically("seat holds", time.Minute, services.ExpireSeatHolds)
+	services.RunPeriodically("user ratings", 24*time.Hour, services.RefreshUserRatings)
+
 	// Create Gin router
 	router := gin.Default()
 
@@ -36,6 +49,8 @@ func main() {
 	// Auth routes
 	router.POST("/api/v1/auth/register", handlers.Register)
 	router.POST("/api/v1/auth/login", handlers.Login)
+	router.GET("/api/v1/auth/oauth/:provider", handlers.StartOAuthLogin)
+	router.GET("/api/v1/auth/oauth/:provider/callback", handlers.OAuthCallback)
 
 	// Protected routes
 	protected := router.Group("/api/v1")
@@ -44,21 +59,106 @@ func main() {
 		// User routes
 		protected.GET("/users/me", handlers.GetCurrentUser)
 		protected.PUT("/users/me", handlers.UpdateCurrentUser)
+		protected.PUT("/users/me/mode", handlers.SwitchMode)
+		protected.GET("/users/me/permissions", handlers.GetMyPermissions)
+		protected.GET("/users/me/export", handlers.ExportMyData)
+		protected.POST("/users/me/deletion", handlers.RequestAccountDeletion)
+		protected.DELETE("/users/me/deletion", handlers.CancelAccountDeletion)
+		protected.GET("/users/me/identities", handlers.GetMyIdentities)
+		protected.POST("/users/me/identities/:provider", handlers.StartIdentityLink)
+		protected.DELETE("/users/me/identities/:provider", handlers.UnlinkIdentity)
+		protected.GET("/users/:id/ratings/summary", handlers.GetUserRatingSummary)
 
 		// Ride routes - Static paths first
 		protected.POST("/rides", handlers.CreateRide)
 		protected.GET("/rides/my-rides", handlers.GetMyRides)
 		protected.GET("/rides/shared/available", handlers.GetAvailableSharedRides)
 		protected.GET("/rides/shared/upcoming", handlers.GetUpcomingSharedRides)
+		protected.GET("/rides/on-demand/available", middleware.RoleMiddleware(models.RoleDriver), handlers.GetAvailableOnDemandRides)
+		protected.GET("/rides/tiers", handlers.GetRideTiers)
 
 		// Ride routes - Dynamic paths with parameters
 		protected.GET("/rides/:id", handlers.GetRideByID)
 		protected.PUT("/rides/:id/status", handlers.UpdateRideStatus)
+		protected.POST("/rides/:id/accept", middleware.RoleMiddleware(models.RoleDriver), handlers.AcceptRide)
+		protected.POST("/rides/:id/decline", middleware.RoleMiddleware(models.RoleDriver), handlers.DeclineRide)
+		protected.POST("/rides/:id/tip", handlers.TipRide)
 		protected.POST("/rides/:id/join", handlers.JoinRide)
 		protected.GET("/rides/:id/passengers", handlers.GetRidePassengers)
 		protected.DELETE("/rides/:id/passengers/:passengerId", handlers.LeaveRide)
+		protected.POST("/rides/:id/passengers/:passengerId/accept", handlers.AcceptJoinRequest)
+		protected.POST("/rides/:id/passengers/:passengerId/decline", handlers.DeclineJoinRequest)
+		protected.GET("/rides/:id/waitlist", handlers.GetRideWaitlist)
+		protected.POST("/rides/:id/waitlist/confirm", handlers.ConfirmWaitlistOffer)
+		protected.DELETE("/rides/:id/waitlist", handlers.LeaveWaitlist)
+		protected.POST("/rides/:id/holds", handlers.HoldSeats)
+		protected.POST("/rides/:id/holds/:holdId/confirm", handlers.ConfirmSeatHold)
+		protected.DELETE("/rides/:id/holds/:holdId", handlers.ReleaseSeatHold)
+		protected.GET("/rides/:id/itinerary", handlers.GetRideItinerary)
+		protected.POST("/rides/:id/stops/:stopId/check-in", handlers.CheckInStop)
 		protected.POST("/rides/:id/rate", handlers.RateRide)
 		protected.GET("/rides/:id/ratings", handlers.GetRideRatings)
+		protected.GET("/ratings/tags", handlers.GetRatingTags)
+
+		// Recurring ride routes
+		protected.POST("/ride-series", handlers.CreateRideSeries)
+		protected.GET("/ride-series/my", handlers.GetMyRideSeries)
+		protected.GET("/ride-series/:id", handlers.GetRideSeries)
+		protected.DELETE("/ride-series/:id", handlers.EndRideSeries)
+		protected.DELETE("/ride-series/:id/occurrences/:date", handlers.CancelSeriesOccurrence)
+		protected.POST("/ride-series/:id/subscription", handlers.SubscribeToRideSeries)
+		protected.DELETE("/ride-series/:id/subscription", handlers.UnsubscribeFromRideSeries)
+
+		// Driver onboarding routes
+		protected.GET("/drivers/application", handlers.GetMyDriverApplication)
+		protected.POST("/drivers/application", handlers.StartDriverApplication)
+		protected.POST("/drivers/application/documents", handlers.UploadDriverDocument)
+		protected.POST("/drivers/application/submit", handlers.SubmitDriverApplication)
+		protected.GET("/drivers/status", middleware.RoleMiddleware(models.RoleDriver), handlers.GetDriverStatus)
+		protected.PUT("/drivers/status", middleware.RoleMiddleware(models.RoleDriver), handlers.SetDriverStatus)
+		protected.GET("/drivers/destination", middleware.RoleMiddleware(models.RoleDriver), handlers.GetDriverDestination)
+		protected.PUT("/drivers/destination", middleware.RoleMiddleware(models.RoleDriver), handlers.SetDriverDestination)
+		protected.DELETE("/drivers/destination", middleware.RoleMiddleware(models.RoleDriver), handlers.ClearDriverDestination)
+		protected.GET("/drivers/hours", middleware.RoleMiddleware(models.RoleDriver), handlers.GetHoursOfService)
+		protected.GET("/drivers/heatmap", middleware.RoleMiddleware(models.RoleDriver), handlers.GetDemandHeatmap)
+		protected.GET("/drivers/earnings", middleware.RoleMiddleware(models.RoleDriver), handlers.GetDriverEarnings)
+
+		// Notification routes
+		protected.GET("/notifications", handlers.GetMyNotifications)
+		protected.PUT("/notifications/:id/read", handlers.MarkNotificationRead)
+
+		// Vehicle routes
+		protected.GET("/vehicles", handlers.GetMyVehicles)
+		protected.POST("/vehicles", handlers.AddVehicle)
+		protected.PUT("/vehicles/:id", handlers.UpdateVehicle)
+		protected.PUT("/vehicles/:id/activate", handlers.ActivateVehicle)
+		protected.DELETE("/vehicles/:id", handlers.RemoveVehicle)
+
+		// Organization routes
+		protected.GET("/organizations", handlers.GetOrganizations)
+		protected.POST("/organizations", middleware.RequirePermission(models.PermissionOrganizationsManage), handlers.CreateOrganization)
+		protected.GET("/organizations/my", handlers.GetMyOrganizations)
+		protected.POST("/organizations/:id/join", handlers.JoinOrganization)
+		protected.POST("/organizations/:id/verify", handlers.VerifyOrganizationMembership)
+		protected.DELETE("/organizations/:id/membership", handlers.LeaveOrganization)
+
+		// Admin and support routes
+		admin := protected.Group("/admin")
+		{
+			admin.GET("/users", middleware.RequirePermission(models.PermissionUsersReadAny), handlers.GetUsers)
+			admin.GET("/users/:id", middleware.RequirePermission(models.PermissionUsersReadAny), handlers.GetUserByID)
+			admin.PUT("/users/:id/suspension", middleware.RequirePermission(models.PermissionUsersSuspend), handlers.SuspendUser)
+			admin.PUT("/users/:id/role", middleware.RequirePermission(models.PermissionUsersManageRoles), handlers.UpdateUserRole)
+			admin.PUT("/users/:id/gender", middleware.RequirePermission(models.PermissionUsersEditProfile), handlers.UpdateUserGender)
+			admin.GET("/rides", middleware.RequirePermission(models.PermissionRidesReadAny), handlers.GetAllRides)
+			admin.GET("/ratings/flagged", middleware.RequirePermission(models.PermissionRatingsModerate), handlers.GetFlaggedRatings)
+			admin.PUT("/ratings/:id/review", middleware.RequirePermission(models.PermissionRatingsModerate), handlers.ReviewRating)
+			admin.GET("/driver-applications", middleware.RequirePermission(models.PermissionDriversReview), handlers.GetDriverApplications)
+			admin.GET("/driver-applications/:id", middleware.RequirePermission(models.PermissionDriversReview), handlers.GetDriverApplication)
+			admin.PUT("/driver-applications/:id/review", middleware.RequirePermission(models.PermissionDriversReview), handlers.ReviewDriverApplication)
+			admin.GET("/driver-documents/:id/file", middleware.RequirePermission(models.PermissionDriversReview), handlers.GetDriverDocumentFile)
+			admin.PUT("/driver-documents/:id/review", middleware.RequirePermission(models.PermissionDriversReview), handlers.ReviewDriverDocument)
+		}
 	}
 
 	// Start server
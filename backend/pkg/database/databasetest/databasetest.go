@@ -0,0 +1,67 @@
+// Package databasetest connects tests to a disposable Postgres database
+package databasetest
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/rakeshkumar/ridesapp/pkg/config"
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"gorm.io/gorm"
+)
+
+var (
+	initOnce sync.Once
+	initErr  error
+)
+
+// Setup connects to the test database named by TEST_DB_NAME, migrates it once per test binary and empties
+// every table. Tests that need a database are skipped when TEST_DB_NAME is not set. The database is wiped,
+// so it must never be one holding real data.
+func Setup(t testing.TB) *gorm.DB {
+	t.Helper()
+
+	name := os.Getenv("TEST_DB_NAME")
+	if name == "" {
+		t.Skip("TEST_DB_NAME is not set")
+	}
+
+	initOnce.Do(func() {
+		initErr = database.InitDB(&config.Config{
+			DBHost:     getEnv("TEST_DB_HOST", "localhost"),
+			DBPort:     getEnv("TEST_DB_PORT", "5432"),
+			DBUser:     getEnv("TEST_DB_USER", "postgres"),
+			DBPassword: getEnv("TEST_DB_PASSWORD", "postgres"),
+			DBName:     name,
+		})
+	})
+	if initErr != nil {
+		t.Fatalf("failed to initialize test database: %v", initErr)
+	}
+
+	db := database.GetDB()
+	var tables []string
+	if err := db.Raw("SELECT tablename FROM pg_tables WHERE schemaname = current_schema()").Scan(&tables).Error; err != nil {
+		t.Fatalf("failed to list tables: %v", err)
+	}
+	for i, table := range tables {
+		tables[i] = strconv.Quote(table)
+	}
+	if len(tables) > 0 {
+		if err := db.Exec("TRUNCATE " + strings.Join(tables, ", ") + " RESTART IDENTITY CASCADE").Error; err != nil {
+			t.Fatalf("failed to empty tables: %v", err)
+		}
+	}
+	return db
+}
+
+func getEnv(key, defaultValue string) string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	return value
+}
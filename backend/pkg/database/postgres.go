@@ -21,6 +21,18 @@ func InitDB(cfg *config.Config) error {
 		return fmt.Errorf("failed to connect to database: %v", err)
 	}
 
+	// Checked before migrating, so that the driver capability is backfilled only when it is introduced
+	addingCanDrive := !db.Migrator().HasColumn(&models.User{}, "can_drive")
+	addingRandomPassword := !db.Migrator().HasColumn(&models.User{}, "random_password")
+
+	// Ratings used to be accepted any number of times, even for oneself. Before a user can rate another
+	// participant of a ride only once, self-ratings are removed and only the latest of repeated ratings is kept.
+	if db.Migrator().HasTable(&models.Rating{}) && !db.Migrator().HasIndex(&models.Rating{}, "idx_ratings_ride_from_to") {
+		if err := dedupeRatings(db); err != nil {
+			return fmt.Errorf("failed to migrate database: %v", err)
+		}
+	}
+
 	// Auto migrate the schema
 	err = db.AutoMigrate(
 		&models.User{},
@@ -28,11 +40,109 @@ func InitDB(cfg *config.Config) error {
 		&models.Location{},
 		&models.RidePassenger{},
 		&models.Rating{},
+		&models.RatingTag{},
+		&models.UserIdentity{},
+		&models.Organization{},
+		&models.OrganizationMember{},
+		&models.DriverApplication{},
+		&models.DriverDocument{},
+		&models.Vehicle{},
+		&models.VehicleFeature{},
+		&models.DriverSession{},
+		&models.RideDecline{},
+		&models.DemandCell{},
+		&models.DriverDestination{},
+		&models.Notification{},
+		&models.RideSeries{},
+		&models.RideSeriesSkip{},
+		&models.RideSeriesSubscription{},
+		&models.RideWaitlistEntry{},
+		&models.RideStop{},
+		&models.SeatHold{},
+		&models.RidePool{},
 	)
 	if err != nil {
 		return fmt.Errorf("failed to migrate database: %v", err)
 	}
 
+	// Accounts registered as drivers before riders could also drive keep the driver capability. This runs once:
+	// later, can_drive is only granted by approving a driver application, and revoked on account deletion.
+	if addingCanDrive {
+		if err := db.Model(&models.User{}).
+			Where("role = ? AND can_drive = ? AND anonymized_at IS NULL", models.RoleDriver, false).
+			Update("can_drive", true).Error; err != nil {
+			return fmt.Errorf("failed to migrate database: %v", err)
+		}
+	}
+
+	// Accounts created through social login were given a random password in the same transaction as their
+	// first identity. This runs once: later, the flag is set on creation and cleared when a password is set.
+	if addingRandomPassword {
+		if err := db.Exec(`UPDATE users SET random_password = TRUE
+			WHERE EXISTS (SELECT 1 FROM user_identities
+				WHERE user_identities.user_id = users.id
+					AND user_identities.created_at < users.created_at + INTERVAL '1 second')`).Error; err != nil {
+			return fmt.Errorf("failed to migrate database: %v", err)
+		}
+	}
+
+	// On-demand rides booked before tiers existed were economy rides
+	if err := db.Model(&models.Ride{}).
+		Where("ride_type = ? AND (tier IS NULL OR tier = '')", models.RideTypeOnDemand).
+		Update("tier", models.RideTierEconomy).Error; err != nil {
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	// Shared rides offered before route matching follow a straight line from pickup to dropoff
+	if err := db.Model(&models.Ride{}).
+		Where("ride_type = ? AND max_detour_minutes IS NULL", models.RideTypeShared).
+		Updates(map[string]interface{}{
+			"route_min_lat":      gorm.Expr("LEAST(pickup_lat, dropoff_lat)"),
+			"route_max_lat":      gorm.Expr("GREATEST(pickup_lat, dropoff_lat)"),
+			"route_min_lng":      gorm.Expr("LEAST(pickup_lng, dropoff_lng)"),
+			"route_max_lng":      gorm.Expr("GREATEST(pickup_lng, dropoff_lng)"),
+			"max_detour_minutes": models.DefaultMaxDetourMinutes,
+		}).Error; err != nil {
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	// Shared rides offered before owners could approve passengers were instant-book, and their passengers
+	// were booked on joining
+	if err := db.Model(&models.Ride{}).
+		Where("ride_type = ? AND (booking_mode IS NULL OR booking_mode = '')", models.RideTypeShared).
+		Update("booking_mode", models.BookingModeInstant).Error; err != nil {
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+	if err := db.Model(&models.RidePassenger{}).
+		Where("status = ? AND expires_at IS NULL", models.RideStatusPending).
+		Update("status", models.RideStatusAccepted).Error; err != nil {
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	// Passengers who joined before they could choose their stops get in and out at the ride's pickup and
+	// dropoff, all picked up before anyone is dropped off
+	if err := db.Exec(`UPDATE ride_passengers SET
+			pickup_lat = rides.pickup_lat, pickup_lng = rides.pickup_lng, pickup_address = rides.pickup_address,
+			dropoff_lat = rides.dropoff_lat, dropoff_lng = rides.dropoff_lng, dropoff_address = rides.dropoff_address
+		FROM rides WHERE rides.id = ride_passengers.ride_id AND ride_passengers.pickup_address IS NULL`).Error; err != nil {
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+	if err := db.Exec(`INSERT INTO ride_stops (ride_id, passenger_id, user_id, kind, sequence, seats, lat, lng, address, created_at, updated_at)
+		SELECT p.ride_id, p.id, p.user_id, kinds.kind, kinds.sequence, p.seats,
+			CASE kinds.kind WHEN ? THEN p.pickup_lat ELSE p.dropoff_lat END,
+			CASE kinds.kind WHEN ? THEN p.pickup_lng ELSE p.dropoff_lng END,
+			CASE kinds.kind WHEN ? THEN p.pickup_address ELSE p.dropoff_address END,
+			NOW(), NOW()
+		FROM ride_passengers p CROSS JOIN (VALUES (?, 1), (?, 2)) AS kinds(kind, sequence)
+		WHERE NOT EXISTS (SELECT 1 FROM ride_stops s WHERE s.passenger_id = p.id)`,
+		models.StopPickup, models.StopPickup, models.StopPickup, models.StopPickup, models.StopDropoff).Error; err != nil {
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+
+	if err := migrateUserVehicles(db); err != nil {
+		return fmt.Errorf("failed to migrate database: %v", err)
+	}
+
 	DB = db
 	log.Println("Database connection established")
 	return nil
@@ -41,3 +151,40 @@ func InitDB(cfg *config.Config) error {
 func GetDB() *gorm.DB {
 	return DB
 }
+
+// migrateUserVehicles moves the vehicle details that used to be stored on users into vehicles.
+// The capacity and class of those vehicles were never recorded, so they default to a four-seat sedan.
+func migrateUserVehicles(db *gorm.DB) error {
+	if !db.Migrator().HasColumn(&models.User{}, "vehicle_plate") {
+		return nil
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`INSERT INTO vehicles (driver_id, make, model, color, plate, seat_capacity, class, is_active, created_at, updated_at)
+			SELECT id, '', COALESCE(vehicle_model, ''), COALESCE(vehicle_color, ''), vehicle_plate, 4, ?, TRUE, NOW(), NOW()
+			FROM users WHERE vehicle_plate <> ''`, models.VehicleClassSedan).Error; err != nil {
+			return err
+		}
+		for _, column := range []string{"vehicle_model", "vehicle_color", "vehicle_plate"} {
+			if err := tx.Migrator().DropColumn(&models.User{}, column); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// dedupeRatings removes self-ratings and all but the latest rating each user gave another for the same ride,
+// with their tags
+func dedupeRatings(db *gorm.DB) error {
+	superseded := `SELECT id FROM ratings WHERE from_user_id = to_user_id OR id NOT IN
+		(SELECT MAX(id) FROM ratings GROUP BY ride_id, from_user_id, to_user_id)`
+	return db.Transaction(func(tx *gorm.DB) error {
+		if tx.Migrator().HasTable(&models.RatingTag{}) {
+			if err := tx.Exec("DELETE FROM rating_tags WHERE rating_id IN (" + superseded + ")").Error; err != nil {
+				return err
+			}
+		}
+		return tx.Exec("DELETE FROM ratings WHERE id IN (" + superseded + ")").Error
+	})
+}
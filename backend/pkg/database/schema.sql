@@ -6,14 +6,115 @@ CREATE TABLE IF NOT EXISTS users (
     first_name VARCHAR(100) NOT NULL,
     last_name VARCHAR(100) NOT NULL,
     phone VARCHAR(20) NOT NULL,
-    role VARCHAR(20) NOT NULL CHECK (role IN ('rider', 'driver')),
+    role VARCHAR(20) NOT NULL CHECK (role IN ('rider', 'driver', 'support', 'admin')),
     profile_picture VARCHAR(255),
-    rating DECIMAL(3,2) DEFAULT 5.0,
+    rating DECIMAL(3,2) DEFAULT 5.0, -- Bayesian average of all ratings received
+    rating_count INTEGER DEFAULT 0,
+    rider_rating DECIMAL(3,2) DEFAULT 5.0, -- Bayesian average of ratings received as rider
+    rider_rating_count INTEGER DEFAULT 0,
+    driver_rating DECIMAL(3,2) DEFAULT 5.0, -- Bayesian average of ratings received as driver
+    driver_rating_count INTEGER DEFAULT 0,
     is_verified BOOLEAN DEFAULT FALSE,
+    can_drive BOOLEAN DEFAULT FALSE, -- Whether the user may switch to driver mode
+    random_password BOOLEAN NOT NULL DEFAULT FALSE, -- Set for social login accounts until a password is chosen
+    gender VARCHAR(20) NOT NULL DEFAULT '' CHECK (gender IN ('', 'female', 'male', 'other')), -- Optional, needed for women-only rides
+    suspended_at TIMESTAMP WITH TIME ZONE,
+    deletion_due_at TIMESTAMP WITH TIME ZONE, -- When a requested account deletion takes effect
+    anonymized_at TIMESTAMP WITH TIME ZONE,
     license_number VARCHAR(50),
-    vehicle_model VARCHAR(100),
-    vehicle_color VARCHAR(50),
-    vehicle_plate VARCHAR(20),
+    -- Defaults for the rides the user requests or offers, and what they accept as a driver
+    pref_smoking VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_smoking IN ('', 'no_smoking', 'smoking')),
+    pref_pets VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_pets IN ('', 'no_pets', 'pets')),
+    pref_music VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_music IN ('', 'no_music', 'music')),
+    pref_conversation VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_conversation IN ('', 'quiet', 'some', 'chatty')),
+    pref_luggage VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_luggage IN ('', 'none', 'small', 'medium', 'large')),
+    pref_women_only BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create organizations table
+CREATE TABLE IF NOT EXISTS organizations (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    email_domain VARCHAR(255) UNIQUE NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create organization_members table
+CREATE TABLE IF NOT EXISTS organization_members (
+    id SERIAL PRIMARY KEY,
+    organization_id INTEGER NOT NULL REFERENCES organizations(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    email VARCHAR(255) NOT NULL, -- Organization email used to prove membership
+    verification_code VARCHAR(255), -- Hash of the code sent to email
+    code_sent_at TIMESTAMP WITH TIME ZONE,
+    code_expires_at TIMESTAMP WITH TIME ZONE,
+    code_attempts INTEGER NOT NULL DEFAULT 0, -- Wrong codes entered since the code was sent
+    verified_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (organization_id, user_id)
+);
+
+-- Create driver_applications table
+CREATE TABLE IF NOT EXISTS driver_applications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER UNIQUE NOT NULL REFERENCES users(id),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('draft', 'submitted', 'in_review', 'approved', 'rejected')),
+    rejection_reason TEXT,
+    submitted_at TIMESTAMP WITH TIME ZONE,
+    reviewed_at TIMESTAMP WITH TIME ZONE,
+    reviewed_by_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create driver_documents table
+CREATE TABLE IF NOT EXISTS driver_documents (
+    id SERIAL PRIMARY KEY,
+    application_id INTEGER NOT NULL REFERENCES driver_applications(id) ON DELETE CASCADE,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('license', 'insurance', 'registration')),
+    number VARCHAR(100),
+    file_path VARCHAR(255) NOT NULL,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'approved', 'rejected')),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create vehicles table
+CREATE TABLE IF NOT EXISTS vehicles (
+    id SERIAL PRIMARY KEY,
+    driver_id INTEGER NOT NULL REFERENCES users(id),
+    make VARCHAR(100) NOT NULL,
+    model VARCHAR(100) NOT NULL,
+    year INTEGER,
+    color VARCHAR(50),
+    plate VARCHAR(20) NOT NULL,
+    seat_capacity INTEGER NOT NULL CHECK (seat_capacity > 0), -- Passenger seats, not counting the driver
+    class VARCHAR(20) NOT NULL CHECK (class IN ('compact', 'sedan', 'suv', 'minivan', 'luxury')),
+    is_active BOOLEAN DEFAULT FALSE, -- The vehicle the driver is currently driving
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    deleted_at TIMESTAMP WITH TIME ZONE -- Removed vehicles are kept for the rides that used them
+);
+
+-- Create vehicle_features table
+CREATE TABLE IF NOT EXISTS vehicle_features (
+    id SERIAL PRIMARY KEY,
+    vehicle_id INTEGER NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    feature VARCHAR(50) NOT NULL,
+    UNIQUE (vehicle_id, feature)
+);
+
+-- Create ride_pools table, trips shared by riders of pooled on-demand rides
+CREATE TABLE IF NOT EXISTS ride_pools (
+    id SERIAL PRIMARY KEY,
+    tier VARCHAR(20) NOT NULL CHECK (tier IN ('economy', 'xl', 'premium', 'accessible')),
+    driver_id INTEGER REFERENCES users(id), -- Set once a driver accepts the pool
+    distance_km DECIMAL(10,2) NOT NULL DEFAULT 0, -- Straight-line length of the planned stops
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
@@ -22,6 +123,7 @@ CREATE TABLE IF NOT EXISTS users (
 CREATE TABLE IF NOT EXISTS rides (
     id SERIAL PRIMARY KEY,
     ride_type VARCHAR(20) NOT NULL CHECK (ride_type IN ('shared', 'on_demand')),
+    tier VARCHAR(20) CHECK (tier IN ('economy', 'xl', 'premium', 'accessible')), -- For on-demand rides
     rider_id INTEGER NOT NULL REFERENCES users(id),
     driver_id INTEGER REFERENCES users(id),
     pickup_lat DECIMAL(10,8) NOT NULL,
@@ -32,29 +134,223 @@ CREATE TABLE IF NOT EXISTS rides (
     dropoff_address TEXT NOT NULL,
     status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'accepted', 'started', 'completed', 'cancelled')),
     price DECIMAL(10,2) NOT NULL,
+    commission DECIMAL(10,2) DEFAULT 0, -- Platform commission, set when the ride is completed
+    tip DECIMAL(10,2) DEFAULT 0,
     distance DECIMAL(10,2) NOT NULL, -- in kilometers
     duration INTEGER NOT NULL, -- in minutes
     seats_available INTEGER, -- For shared rides
     seats_booked INTEGER DEFAULT 0, -- For shared rides
     departure_time TIMESTAMP WITH TIME ZONE, -- For shared rides
+    route_polyline TEXT, -- For shared rides, encoded polyline of the planned route
+    route_min_lat DECIMAL(10,8), -- Bounding box of the route, for matching
+    route_max_lat DECIMAL(10,8),
+    route_min_lng DECIMAL(11,8),
+    route_max_lng DECIMAL(11,8),
+    max_detour_minutes INTEGER, -- For shared rides, the longest detour the driver accepts for a passenger
+    booking_mode VARCHAR(20) CHECK (booking_mode IN ('instant', 'manual')), -- For shared rides
+    optimize_stops BOOLEAN DEFAULT FALSE, -- For shared rides, order passenger stops for the least detour instead of along the route
+    -- Preferences of the owner for shared rides, of the rider for on-demand rides
+    pref_smoking VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_smoking IN ('', 'no_smoking', 'smoking')),
+    pref_pets VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_pets IN ('', 'no_pets', 'pets')),
+    pref_music VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_music IN ('', 'no_music', 'music')),
+    pref_conversation VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_conversation IN ('', 'quiet', 'some', 'chatty')),
+    pref_luggage VARCHAR(20) NOT NULL DEFAULT '' CHECK (pref_luggage IN ('', 'none', 'small', 'medium', 'large')),
+    pref_women_only BOOLEAN NOT NULL DEFAULT FALSE,
+    pooled BOOLEAN DEFAULT FALSE, -- For on-demand rides, the rider agreed to share the car for a discount
+    pool_id INTEGER REFERENCES ride_pools(id), -- Pool a pooled on-demand ride was matched into
+    series_id INTEGER, -- Recurring series the ride was created from
+    occurrence_date DATE, -- Date of the series the ride runs on
+    organization_id INTEGER REFERENCES organizations(id), -- Restricts a shared ride to an organization's members
+    vehicle_id INTEGER REFERENCES vehicles(id), -- Vehicle the ride is driven with
     payment_method VARCHAR(20) NOT NULL CHECK (payment_method IN ('cash', 'card', 'wallet')),
+    accepted_at TIMESTAMP WITH TIME ZONE,
     started_at TIMESTAMP WITH TIME ZONE,
     completed_at TIMESTAMP WITH TIME ZONE,
+    cancelled_by_id INTEGER REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create ride_declines table
+CREATE TABLE IF NOT EXISTS ride_declines (
+    id SERIAL PRIMARY KEY,
+    ride_id INTEGER NOT NULL REFERENCES rides(id) ON DELETE CASCADE,
+    driver_id INTEGER NOT NULL REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (ride_id, driver_id)
+);
+
+-- Create driver_sessions table
+CREATE TABLE IF NOT EXISTS driver_sessions (
+    id SERIAL PRIMARY KEY,
+    driver_id INTEGER NOT NULL REFERENCES users(id),
+    started_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    ended_at TIMESTAMP WITH TIME ZONE, -- NULL while the driver is online
+    end_reason VARCHAR(20) -- Set when the platform took the driver offline
+);
+
+-- Create driver_destinations table
+CREATE TABLE IF NOT EXISTS driver_destinations (
+    id SERIAL PRIMARY KEY,
+    driver_id INTEGER NOT NULL REFERENCES users(id),
+    lat DECIMAL(10,8) NOT NULL,
+    lng DECIMAL(11,8) NOT NULL,
+    address TEXT,
+    activated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    deactivated_at TIMESTAMP WITH TIME ZONE -- NULL while destination mode is on
+);
+
+-- Create demand_cells table, recomputed periodically from recent ride requests
+CREATE TABLE IF NOT EXISTS demand_cells (
+    geohash VARCHAR(12) PRIMARY KEY,
+    lat DECIMAL(10,8) NOT NULL, -- Center of the cell
+    lng DECIMAL(11,8) NOT NULL,
+    requests INTEGER NOT NULL,
+    weight DOUBLE PRECISION NOT NULL, -- Requests weighted by how recent they are
+    intensity DOUBLE PRECISION NOT NULL, -- Weight relative to the busiest cell
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create ride_series table, templates of recurring shared rides
+CREATE TABLE IF NOT EXISTS ride_series (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL REFERENCES users(id),
+    pickup_lat DECIMAL(10,8) NOT NULL,
+    pickup_lng DECIMAL(11,8) NOT NULL,
+    dropoff_lat DECIMAL(10,8) NOT NULL,
+    dropoff_lng DECIMAL(11,8) NOT NULL,
+    pickup_address TEXT NOT NULL,
+    dropoff_address TEXT NOT NULL,
+    price DECIMAL(10,2) NOT NULL,
+    distance DECIMAL(10,2) NOT NULL,
+    duration INTEGER NOT NULL,
+    seats_available INTEGER NOT NULL,
+    payment_method VARCHAR(20) NOT NULL CHECK (payment_method IN ('cash', 'card', 'wallet')),
+    organization_id INTEGER REFERENCES organizations(id),
+    route_polyline TEXT,
+    route_min_lat DECIMAL(10,8),
+    route_max_lat DECIMAL(10,8),
+    route_min_lng DECIMAL(11,8),
+    route_max_lng DECIMAL(11,8),
+    max_detour_minutes INTEGER,
+    booking_mode VARCHAR(20) CHECK (booking_mode IN ('instant', 'manual')),
+    days_of_week VARCHAR(27) NOT NULL, -- Comma separated, e.g. 'mon,tue,wed'
+    departure_time VARCHAR(5) NOT NULL, -- Time of day in time_zone, e.g. '08:15'
+    time_zone VARCHAR(64) NOT NULL,
+    start_date DATE NOT NULL,
+    end_date DATE, -- Open-ended when NULL
+    is_active BOOLEAN DEFAULT TRUE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Create ride_series_skips table, dates a series does not run
+CREATE TABLE IF NOT EXISTS ride_series_skips (
+    id SERIAL PRIMARY KEY,
+    series_id INTEGER NOT NULL REFERENCES ride_series(id) ON DELETE CASCADE,
+    date DATE NOT NULL,
+    UNIQUE (series_id, date)
+);
+
+-- Create ride_series_subscriptions table
+CREATE TABLE IF NOT EXISTS ride_series_subscriptions (
+    id SERIAL PRIMARY KEY,
+    series_id INTEGER NOT NULL REFERENCES ride_series(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    seats INTEGER NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (series_id, user_id)
+);
+
 -- Create ride_passengers table
 CREATE TABLE IF NOT EXISTS ride_passengers (
     id SERIAL PRIMARY KEY,
     ride_id INTEGER NOT NULL REFERENCES rides(id) ON DELETE CASCADE,
     user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
     seats INTEGER NOT NULL,
-    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'accepted', 'started', 'completed', 'cancelled')),
+    status VARCHAR(20) NOT NULL CHECK (status IN ('pending', 'accepted', 'started', 'completed', 'cancelled', 'declined', 'expired')),
+    expires_at TIMESTAMP WITH TIME ZONE, -- When a pending join request lapses if the ride owner does not answer
+    subscription_id INTEGER REFERENCES ride_series_subscriptions(id), -- Set when booked through a series subscription
+    pickup_lat DECIMAL(10,8) NOT NULL, -- Where the passenger gets in
+    pickup_lng DECIMAL(11,8) NOT NULL,
+    pickup_address TEXT NOT NULL,
+    dropoff_lat DECIMAL(10,8) NOT NULL, -- Where the passenger gets out
+    dropoff_lng DECIMAL(11,8) NOT NULL,
+    dropoff_address TEXT NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Create ride_waitlist_entries table
+CREATE TABLE IF NOT EXISTS ride_waitlist_entries (
+    id SERIAL PRIMARY KEY,
+    ride_id INTEGER NOT NULL REFERENCES rides(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    seats INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('waiting', 'offered', 'confirmed', 'expired', 'cancelled')),
+    offer_expires_at TIMESTAMP WITH TIME ZONE, -- Set while seats are held for the passenger
+    passenger_id INTEGER REFERENCES ride_passengers(id) ON DELETE SET NULL, -- Set once the offer is confirmed
+    pickup_lat DECIMAL(10,8) NOT NULL, -- Stops the passenger asked for, kept for the booking
+    pickup_lng DECIMAL(11,8) NOT NULL,
+    pickup_address TEXT NOT NULL,
+    dropoff_lat DECIMAL(10,8) NOT NULL,
+    dropoff_lng DECIMAL(11,8) NOT NULL,
+    dropoff_address TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create ride_stops table, the pickup and dropoff points of passengers on shared rides and riders on pooled rides
+CREATE TABLE IF NOT EXISTS ride_stops (
+    id SERIAL PRIMARY KEY,
+    ride_id INTEGER NOT NULL REFERENCES rides(id) ON DELETE CASCADE,
+    passenger_id INTEGER REFERENCES ride_passengers(id) ON DELETE CASCADE, -- For shared rides
+    pool_id INTEGER REFERENCES ride_pools(id) ON DELETE CASCADE, -- For pooled rides, stops are ordered across the pool
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    kind VARCHAR(20) NOT NULL CHECK (kind IN ('pickup', 'dropoff')),
+    sequence INTEGER NOT NULL, -- Stops of a ride are visited in this order
+    seats INTEGER NOT NULL,
+    lat DECIMAL(10,8) NOT NULL,
+    lng DECIMAL(11,8) NOT NULL,
+    address TEXT NOT NULL,
+    checked_in_at TIMESTAMP WITH TIME ZONE, -- When the driver confirmed the passenger got in or out
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create seat_holds table, seats reserved on shared rides while passengers pay
+CREATE TABLE IF NOT EXISTS seat_holds (
+    id SERIAL PRIMARY KEY,
+    ride_id INTEGER NOT NULL REFERENCES rides(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id),
+    seats INTEGER NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('active', 'confirmed', 'expired', 'released')),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    passenger_id INTEGER REFERENCES ride_passengers(id) ON DELETE SET NULL, -- Set once the hold is confirmed
+    payment_reference VARCHAR(255),
+    pickup_lat DECIMAL(10,8) NOT NULL, -- Stops the passenger asked for, kept for the booking
+    pickup_lng DECIMAL(11,8) NOT NULL,
+    pickup_address TEXT NOT NULL,
+    dropoff_lat DECIMAL(10,8) NOT NULL,
+    dropoff_lng DECIMAL(11,8) NOT NULL,
+    dropoff_address TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Create notifications table
+CREATE TABLE IF NOT EXISTS notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(40) NOT NULL,
+    message TEXT,
+    ride_id INTEGER REFERENCES rides(id) ON DELETE CASCADE,
+    read_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
 -- Create locations table
 CREATE TABLE IF NOT EXISTS locations (
     id SERIAL PRIMARY KEY,
@@ -83,11 +379,39 @@ CREATE TABLE IF NOT EXISTS payments (
 CREATE TABLE IF NOT EXISTS ratings (
     id SERIAL PRIMARY KEY,
     ride_id INTEGER NOT NULL REFERENCES rides(id),
-    user_id INTEGER NOT NULL REFERENCES users(id),
+    from_user_id INTEGER NOT NULL REFERENCES users(id),
+    to_user_id INTEGER NOT NULL REFERENCES users(id),
+    to_user_role VARCHAR(20) CHECK (to_user_role IN ('rider', 'driver')), -- Capacity in which the rated user took part
+    user_id INTEGER REFERENCES users(id),
     rating INTEGER NOT NULL CHECK (rating >= 1 AND rating <= 5),
     comment TEXT,
+    moderation_status VARCHAR(20) DEFAULT 'approved' CHECK (moderation_status IN ('approved', 'pending_review', 'rejected')),
+    moderation_reason VARCHAR(255), -- Why the comment was flagged
+    reviewed_by_id INTEGER REFERENCES users(id),
+    reviewed_at TIMESTAMP WITH TIME ZONE,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT idx_ratings_ride_from_to UNIQUE (ride_id, from_user_id, to_user_id)
+);
+
+-- Create rating_tags table
+CREATE TABLE IF NOT EXISTS rating_tags (
+    id SERIAL PRIMARY KEY,
+    rating_id INTEGER NOT NULL REFERENCES ratings(id) ON DELETE CASCADE,
+    tag VARCHAR(50) NOT NULL,
+    UNIQUE (rating_id, tag)
+);
+
+-- Create user_identities table
+CREATE TABLE IF NOT EXISTS user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL, -- The provider's stable user ID
+    email VARCHAR(255),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, subject)
 );
 
 -- Create indexes
@@ -101,4 +425,39 @@ CREATE INDEX idx_ride_passengers_user_id ON ride_passengers(user_id);
 CREATE INDEX idx_locations_user_id ON locations(user_id);
 CREATE INDEX idx_payments_ride_id ON payments(ride_id);
 CREATE INDEX idx_ratings_ride_id ON ratings(ride_id);
-CREATE INDEX idx_ratings_user_id ON ratings(user_id); 
\ No newline at end of file
+CREATE INDEX idx_ratings_user_id ON ratings(user_id); 
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+CREATE INDEX idx_rides_organization_id ON rides(organization_id);
+CREATE INDEX idx_organization_members_user_id ON organization_members(user_id);
+CREATE INDEX idx_ratings_to_user_id ON ratings(to_user_id);
+CREATE INDEX idx_ratings_moderation_status ON ratings(moderation_status);
+CREATE INDEX idx_driver_applications_status ON driver_applications(status);
+CREATE INDEX idx_driver_documents_application_id ON driver_documents(application_id);
+CREATE INDEX idx_vehicles_driver_id ON vehicles(driver_id);
+CREATE UNIQUE INDEX idx_vehicles_active_driver ON vehicles(driver_id) WHERE is_active;
+CREATE INDEX idx_rides_vehicle_id ON rides(vehicle_id);
+CREATE INDEX idx_rides_tier ON rides(tier);
+CREATE INDEX idx_rides_driver_id_completed_at ON rides(driver_id, completed_at);
+CREATE INDEX idx_rides_driver_id_accepted_at ON rides(driver_id, accepted_at);
+CREATE INDEX idx_ride_declines_driver_id_created_at ON ride_declines(driver_id, created_at);
+CREATE INDEX idx_driver_sessions_driver_id_started_at ON driver_sessions(driver_id, started_at);
+CREATE INDEX idx_driver_sessions_open ON driver_sessions(driver_id) WHERE ended_at IS NULL;
+CREATE INDEX idx_rides_created_at ON rides(created_at);
+CREATE INDEX idx_demand_cells_lat_lng ON demand_cells(lat, lng);
+CREATE INDEX idx_driver_destinations_driver_id_activated_at ON driver_destinations(driver_id, activated_at);
+CREATE INDEX idx_rides_open_shared ON rides(departure_time) WHERE ride_type = 'shared' AND status = 'pending';
+CREATE INDEX idx_rides_pickup ON rides(pickup_lat, pickup_lng);
+CREATE INDEX idx_rides_dropoff ON rides(dropoff_lat, dropoff_lng);
+CREATE INDEX idx_ride_passengers_pending_expires_at ON ride_passengers(expires_at) WHERE status = 'pending';
+CREATE INDEX idx_notifications_user_id_created_at ON notifications(user_id, created_at);
+CREATE UNIQUE INDEX idx_rides_series_occurrence ON rides(series_id, occurrence_date);
+CREATE INDEX idx_ride_series_owner_id ON ride_series(owner_id);
+CREATE INDEX idx_ride_passengers_subscription_id ON ride_passengers(subscription_id);
+CREATE INDEX idx_ride_waitlist_entries_ride_status ON ride_waitlist_entries(ride_id, status, created_at);
+CREATE INDEX idx_ride_waitlist_entries_offer_expires_at ON ride_waitlist_entries(offer_expires_at) WHERE status = 'offered';
+CREATE INDEX idx_ride_stops_ride_id_sequence ON ride_stops(ride_id, sequence);
+CREATE INDEX idx_ride_stops_passenger_id ON ride_stops(passenger_id);
+CREATE INDEX idx_seat_holds_ride_user_status ON seat_holds(ride_id, user_id, status);
+CREATE INDEX idx_seat_holds_expires_at ON seat_holds(expires_at) WHERE status = 'active';
+CREATE INDEX idx_rides_pool_id ON rides(pool_id);
+CREATE INDEX idx_ride_stops_pool_id_sequence ON ride_stops(pool_id, sequence);
\ No newline at end of file
@@ -0,0 +1,159 @@
+package handlers
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// ExportMyData handles downloading all personal data held about the current user as JSON or ZIP
+func ExportMyData(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "zip" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Format must be json or zip"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	export, err := userRepo.ExportUserData(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export data"})
+		return
+	}
+
+	filename := fmt.Sprintf("ridesapp-export-%d-%s", userID.(uint), export.ExportedAt.Format("20060102"))
+
+	if format == "json" {
+		c.Header("Content-Disposition", "attachment; filename="+filename+".json")
+		c.JSON(http.StatusOK, export)
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", "attachment; filename="+filename+".zip")
+	c.Status(http.StatusOK)
+	if err := writeExportZip(c.Writer, export); err != nil {
+		c.Error(err)
+	}
+}
+
+// writeExportZip writes each section of the export as its own JSON file in a ZIP archive
+func writeExportZip(w http.ResponseWriter, export *models.UserDataExport) error {
+	files := []struct {
+		name string
+		data interface{}
+	}{
+		{"profile.json", export.Profile},
+		{"rides.json", export.Rides},
+		{"shared_bookings.json", export.SharedBookings},
+		{"ratings_given.json", export.RatingsGiven},
+		{"ratings_received.json", export.RatingsReceived},
+		{"locations.json", export.Locations},
+		{"identities.json", export.Identities},
+		{"organizations.json", export.Organizations},
+		{"vehicles.json", export.Vehicles},
+		{"notifications.json", export.Notifications},
+		{"ride_series.json", export.RideSeries},
+		{"series_subscriptions.json", export.Subscriptions},
+		{"waitlists.json", export.Waitlists},
+		{"driver_destinations.json", export.Destinations},
+		{"driver_application.json", export.DriverApplication},
+	}
+
+	archive := zip.NewWriter(w)
+	for _, file := range files {
+		f, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     file.name,
+			Method:   zip.Deflate,
+			Modified: export.ExportedAt,
+		})
+		if err != nil {
+			return err
+		}
+
+		encoder := json.NewEncoder(f)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(file.data); err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// RequestAccountDeletion handles scheduling deletion of the current user's account after a cooling-off period
+func RequestAccountDeletion(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if user.IsDeletionPending() {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":           "Account deletion already requested",
+			"deletion_due_at": user.DeletionDueAt,
+		})
+		return
+	}
+
+	dueAt := time.Now().Add(models.AccountDeletionCoolingOff)
+	if err := userRepo.ScheduleDeletion(user.ID, &dueAt); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to request account deletion"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":         "Account deletion scheduled; it can be cancelled until it takes effect",
+		"deletion_due_at": dueAt,
+	})
+}
+
+// CancelAccountDeletion handles cancelling a pending account deletion
+func CancelAccountDeletion(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if !user.IsDeletionPending() {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No pending account deletion"})
+		return
+	}
+
+	if err := userRepo.ScheduleDeletion(user.ID, nil); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel account deletion"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Account deletion cancelled"})
+}
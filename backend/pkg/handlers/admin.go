@@ -0,0 +1,252 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// GetUsers handles listing users for admin and support staff
+func GetUsers(c *gin.Context) {
+	userRepo := repository.NewUserRepository()
+	users, err := userRepo.GetUsers(models.UserRole(c.Query("role")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get users"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// GetUserByID handles retrieving any user's profile for admin and support staff
+func GetUserByID(c *gin.Context) {
+	// Get user ID from path
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
+// SuspendUserRequest represents the request body for suspending or reinstating a user
+type SuspendUserRequest struct {
+	Suspended *bool `json:"suspended" binding:"required"`
+}
+
+// SuspendUser handles suspending or reinstating a user
+func SuspendUser(c *gin.Context) {
+	// Get user ID from path
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req SuspendUserRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(uint(userID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Staff accounts can only be suspended by someone who can manage roles
+	if user.Role == models.RoleAdmin || user.Role == models.RoleSupport {
+		userRole, _ := c.Get("userRole")
+		if !models.HasPermission(models.UserRole(userRole.(string)), models.PermissionUsersManageRoles) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+	}
+
+	if err := userRepo.SetSuspended(user.ID, *req.Suspended); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	message := "User reinstated successfully"
+	if *req.Suspended {
+		message = "User suspended successfully"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// UpdateUserRoleRequest represents the request body for changing a user's role
+type UpdateUserRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=rider driver support admin"`
+}
+
+// UpdateUserRole handles changing a user's role
+func UpdateUserRole(c *gin.Context) {
+	// Get user ID from path
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req UpdateUserRoleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Admins cannot demote themselves, so the platform always keeps at least one admin
+	currentUserID, _ := c.Get("userID")
+	if currentUserID.(uint) == uint(userID) && models.UserRole(req.Role) != models.RoleAdmin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot change your own role"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	if _, err := userRepo.GetUserByID(uint(userID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := userRepo.UpdateUserRole(uint(userID), models.UserRole(req.Role)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user role"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User role updated successfully"})
+}
+
+// UpdateUserGenderRequest represents the request body for correcting a user's gender
+type UpdateUserGenderRequest struct {
+	Gender string `json:"gender" binding:"required,oneof=female male other"`
+}
+
+// UpdateUserGender handles correcting the gender on a user's profile, which users cannot change once set
+func UpdateUserGender(c *gin.Context) {
+	// Get user ID from path
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	var req UpdateUserGenderRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	if _, err := userRepo.GetUserByID(uint(userID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	if err := userRepo.SetGender(uint(userID), models.Gender(req.Gender)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User gender updated successfully"})
+}
+
+// GetAllRides handles listing all rides for admin and support staff
+func GetAllRides(c *gin.Context) {
+	rideRepo := repository.NewRideRepository()
+	rides, err := rideRepo.GetRides(models.RideStatus(c.Query("status")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rides"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rides)
+}
+
+// GetMyPermissions handles retrieving the permissions granted to the current user's role
+func GetMyPermissions(c *gin.Context) {
+	// Get user role from context (set by auth middleware)
+	userRole, exists := c.Get("userRole")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+		return
+	}
+
+	permissions := models.PermissionsForRole(models.UserRole(userRole.(string)))
+	if permissions == nil {
+		permissions = []models.Permission{}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"role":        userRole,
+		"permissions": permissions,
+	})
+}
+
+// GetFlaggedRatings handles listing ratings whose comments are waiting for review
+func GetFlaggedRatings(c *gin.Context) {
+	ratingRepo := repository.NewRatingRepository()
+	ratings, err := ratingRepo.GetRatingsPendingReview()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ratings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, ratings)
+}
+
+// ReviewRatingRequest represents the request body for reviewing a flagged rating
+type ReviewRatingRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+}
+
+// ReviewRating handles approving or rejecting a flagged rating comment
+func ReviewRating(c *gin.Context) {
+	// Get rating ID from path
+	ratingID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid rating ID"})
+		return
+	}
+
+	var req ReviewRatingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ratingRepo := repository.NewRatingRepository()
+	rating, err := ratingRepo.GetRatingByID(uint(ratingID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Rating not found"})
+		return
+	}
+
+	if rating.ModerationStatus != models.ModerationPendingReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "Rating is not waiting for review"})
+		return
+	}
+
+	reviewerID, _ := c.Get("userID")
+	if err := ratingRepo.ReviewRating(rating, reviewerID.(uint), req.Decision == "approve"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review rating"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Rating reviewed successfully",
+		"rating":  rating,
+	})
+}
@@ -39,14 +39,14 @@ func Register(c *gin.Context) {
 		return
 	}
 
-	// Create new user
+	// Create new user. Drivers start in rider mode until their driver application is approved.
 	user := models.User{
 		Email:     req.Email,
 		Password:  req.Password,
 		FirstName: req.FirstName,
 		LastName:  req.LastName,
 		Phone:     req.Phone,
-		Role:      models.UserRole(req.Role),
+		Role:      models.RoleRider,
 	}
 
 	// Hash password
@@ -61,6 +61,20 @@ func Register(c *gin.Context) {
 		return
 	}
 
+	// Registering as a driver starts driver onboarding
+	var driverApplication *models.DriverApplication
+	if req.Role == string(models.RoleDriver) {
+		driverApplication = &models.DriverApplication{
+			UserID: user.ID,
+			Status: models.DriverApplicationDraft,
+		}
+		driverRepo := repository.NewDriverRepository()
+		if err := driverRepo.CreateApplication(driverApplication); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start driver application"})
+			return
+		}
+	}
+
 	// Generate JWT token
 	token, err := utils.GenerateToken(&user)
 	if err != nil {
@@ -78,7 +92,8 @@ func Register(c *gin.Context) {
 			"phone":      user.Phone,
 			"role":       user.Role,
 		},
-		"token": token,
+		"driver_application": driverApplication,
+		"token":              token,
 	})
 }
 
@@ -104,6 +119,12 @@ func Login(c *gin.Context) {
 		return
 	}
 
+	// Suspended accounts cannot log in
+	if user.IsSuspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+		return
+	}
+
 	// Generate JWT token
 	token, err := utils.GenerateToken(user)
 	if err != nil {
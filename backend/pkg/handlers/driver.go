@@ -0,0 +1,699 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// maxDocumentSize is the largest driver document file accepted, in bytes
+const maxDocumentSize = 10 << 20
+
+// allowedDocumentExtensions are the file types accepted for driver documents
+var allowedDocumentExtensions = map[string]bool{".pdf": true, ".jpg": true, ".jpeg": true, ".png": true}
+
+// GetMyDriverApplication handles retrieving the current user's driver application
+func GetMyDriverApplication(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	app, err := driverRepo.GetApplicationByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver application not found"})
+		return
+	}
+
+	eligible := true
+	reason := ""
+	if err := app.CheckEligibility(time.Now()); err != nil {
+		eligible = false
+		reason = err.Error()
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"application":       app,
+		"can_accept_rides":  eligible,
+		"ineligible_reason": reason,
+	})
+}
+
+// StartDriverApplication handles a user starting driver onboarding
+func StartDriverApplication(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.Role != models.RoleRider && user.Role != models.RoleDriver {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Staff accounts cannot drive"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	if _, err := driverRepo.GetApplicationByUserID(user.ID); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Driver application already exists"})
+		return
+	}
+
+	app := &models.DriverApplication{
+		UserID: user.ID,
+		Status: models.DriverApplicationDraft,
+	}
+	if err := driverRepo.CreateApplication(app); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create driver application"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":     "Driver application started; upload your license, insurance and registration",
+		"application": app,
+	})
+}
+
+// UploadDriverDocument handles uploading a license, insurance or registration document.
+// Expects a multipart form with type, number, expires_at (YYYY-MM-DD) and file.
+func UploadDriverDocument(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	docType := models.DocumentType(c.PostForm("type"))
+	if docType != models.DocumentLicense && docType != models.DocumentInsurance && docType != models.DocumentRegistration {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Type must be license, insurance or registration"})
+		return
+	}
+
+	expiresAt, err := time.Parse("2006-01-02", c.PostForm("expires_at"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be a date in YYYY-MM-DD format"})
+		return
+	}
+	if !expiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Document has already expired"})
+		return
+	}
+
+	file, err := c.FormFile("file")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Document file is required"})
+		return
+	}
+	ext := strings.ToLower(filepath.Ext(file.Filename))
+	if !allowedDocumentExtensions[ext] {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Document must be a PDF, JPEG or PNG file"})
+		return
+	}
+	if file.Size > maxDocumentSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Document must be smaller than 10 MB"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	app, err := driverRepo.GetApplicationByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Start a driver application first"})
+		return
+	}
+	if app.Status == models.DriverApplicationSubmitted || app.Status == models.DriverApplicationInReview {
+		c.JSON(http.StatusConflict, gin.H{"error": "Documents cannot be changed while the application is being reviewed"})
+		return
+	}
+
+	// Store the file outside the web root
+	uploadDir := os.Getenv("UPLOAD_DIR")
+	if uploadDir == "" {
+		uploadDir = "uploads"
+	}
+	dir := filepath.Join(uploadDir, "driver-documents", strconv.FormatUint(uint64(userID.(uint)), 10))
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%d%s", docType, time.Now().UnixNano(), ext))
+	if err := c.SaveUploadedFile(file, path); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store document"})
+		return
+	}
+
+	doc := &models.DriverDocument{
+		ApplicationID: app.ID,
+		Type:          docType,
+		Number:        c.PostForm("number"),
+		FilePath:      path,
+		ExpiresAt:     expiresAt,
+		Status:        models.DocumentPending,
+	}
+	if err := driverRepo.AddDocument(doc); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save document"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":  "Document uploaded successfully",
+		"document": doc,
+	})
+}
+
+// SubmitDriverApplication handles submitting a driver application for review
+func SubmitDriverApplication(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	app, err := driverRepo.GetApplicationByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver application not found"})
+		return
+	}
+
+	if !app.Status.CanTransitionTo(models.DriverApplicationSubmitted) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Application cannot be submitted while " + string(app.Status)})
+		return
+	}
+	if !app.HasRequiredDocuments(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid license, insurance and registration are required"})
+		return
+	}
+
+	if err := driverRepo.SubmitApplication(app); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to submit application"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Application submitted for review",
+		"application": app,
+	})
+}
+
+// GetDriverApplications handles listing driver applications for reviewers
+func GetDriverApplications(c *gin.Context) {
+	driverRepo := repository.NewDriverRepository()
+	apps, err := driverRepo.GetApplications(models.DriverApplicationStatus(c.Query("status")))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get driver applications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, apps)
+}
+
+// GetDriverApplication handles retrieving a driver application for reviewers
+func GetDriverApplication(c *gin.Context) {
+	// Get application ID from path
+	appID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	app, err := driverRepo.GetApplicationByID(uint(appID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver application not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, app)
+}
+
+// GetDriverDocumentFile handles downloading an uploaded driver document for reviewers
+func GetDriverDocumentFile(c *gin.Context) {
+	// Get document ID from path
+	docID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	doc, err := driverRepo.GetDocumentByID(uint(docID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+
+	c.FileAttachment(doc.FilePath, filepath.Base(doc.FilePath))
+}
+
+// ReviewDriverApplicationRequest represents the request body for reviewing a driver application
+type ReviewDriverApplicationRequest struct {
+	Action string `json:"action" binding:"required,oneof=start_review approve reject"`
+	Reason string `json:"reason" binding:"required_if=Action reject"`
+}
+
+// ReviewDriverApplication handles moving a driver application through review
+func ReviewDriverApplication(c *gin.Context) {
+	// Get application ID from path
+	appID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid application ID"})
+		return
+	}
+
+	var req ReviewDriverApplicationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	app, err := driverRepo.GetApplicationByID(uint(appID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Driver application not found"})
+		return
+	}
+
+	next := map[string]models.DriverApplicationStatus{
+		"start_review": models.DriverApplicationInReview,
+		"approve":      models.DriverApplicationApproved,
+		"reject":       models.DriverApplicationRejected,
+	}[req.Action]
+	if !app.Status.CanTransitionTo(next) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Cannot " + req.Action + " an application that is " + string(app.Status)})
+		return
+	}
+
+	reviewerID, _ := c.Get("userID")
+	switch next {
+	case models.DriverApplicationInReview:
+		err = driverRepo.StartReview(app, reviewerID.(uint))
+	case models.DriverApplicationApproved:
+		err = driverRepo.ApproveApplication(app, reviewerID.(uint))
+	case models.DriverApplicationRejected:
+		err = driverRepo.RejectApplication(app, reviewerID.(uint), req.Reason)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review application"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Application updated successfully",
+		"application": app,
+	})
+}
+
+// ReviewDriverDocumentRequest represents the request body for reviewing a single document
+type ReviewDriverDocumentRequest struct {
+	Decision string `json:"decision" binding:"required,oneof=approve reject"`
+}
+
+// ReviewDriverDocument handles approving or rejecting a document uploaded after approval, e.g. a renewed insurance
+func ReviewDriverDocument(c *gin.Context) {
+	// Get document ID from path
+	docID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid document ID"})
+		return
+	}
+
+	var req ReviewDriverDocumentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	doc, err := driverRepo.GetDocumentByID(uint(docID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Document not found"})
+		return
+	}
+	if doc.Status != models.DocumentPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Document has already been reviewed"})
+		return
+	}
+
+	if err := driverRepo.ReviewDocument(doc, req.Decision == "approve"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to review document"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Document reviewed successfully",
+		"document": doc,
+	})
+}
+
+// GetDriverStatus handles retrieving whether the current driver is online
+func GetDriverStatus(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	session, err := driverRepo.GetOpenSession(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusOK, gin.H{"online": false})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"online":  true,
+		"session": session,
+	})
+}
+
+// SetDriverStatusRequest represents the request body for going online or offline
+type SetDriverStatusRequest struct {
+	Online *bool `json:"online" binding:"required"`
+}
+
+// SetDriverStatus handles the current driver going online or offline
+func SetDriverStatus(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SetDriverStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	session, err := driverRepo.GetOpenSession(userID.(uint))
+
+	if !*req.Online {
+		if err == nil {
+			if err := driverRepo.EndSession(session, ""); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to go offline"})
+				return
+			}
+		}
+		// Destination mode ends with the shift
+		if err := driverRepo.ClearDestination(userID.(uint)); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to turn off destination mode"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"message": "You are offline", "online": false})
+		return
+	}
+
+	if err == nil {
+		c.JSON(http.StatusOK, gin.H{"message": "You are online", "online": true, "session": session})
+		return
+	}
+
+	// Only drivers who could accept rides can go online
+	if err := driverRepo.CheckDriverEligibility(userID.(uint)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot go online: " + err.Error()})
+		return
+	}
+	vehicleRepo := repository.NewVehicleRepository()
+	if _, err := vehicleRepo.GetActiveVehicle(userID.(uint)); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Activate a vehicle before going online"})
+		return
+	}
+
+	// Hours-of-service limits
+	hours, err := driverRepo.GetHoursOfService(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check hours of service"})
+		return
+	}
+	if !hours.CanGoOnline {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error":            "You cannot go online: " + hours.Reason,
+			"hours_of_service": hours,
+		})
+		return
+	}
+
+	session, err = driverRepo.StartSession(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to go online"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "You are online", "online": true, "session": session})
+}
+
+// GetHoursOfService handles retrieving the current driver's online time and remaining allowance
+func GetHoursOfService(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	hours, err := driverRepo.GetHoursOfService(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get hours of service"})
+		return
+	}
+
+	c.JSON(http.StatusOK, hours)
+}
+
+// maxEarningsRangeDays is the longest date range an earnings report can cover
+const maxEarningsRangeDays = 366
+
+// GetDriverEarnings handles retrieving the current driver's earnings and trip statistics.
+// Accepts period (day, week or month), an inclusive from/to date range in YYYY-MM-DD format and the
+// IANA time zone (tz) the periods start in, defaulting to daily figures for the last 30 days in UTC.
+func GetDriverEarnings(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	period := models.EarningsPeriod(c.DefaultQuery("period", string(models.EarningsDaily)))
+	if period != models.EarningsDaily && period != models.EarningsWeekly && period != models.EarningsMonthly {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Period must be day, week or month"})
+		return
+	}
+
+	loc, err := time.LoadLocation(c.DefaultQuery("tz", "UTC"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tz must be an IANA time zone such as Europe/Berlin"})
+		return
+	}
+
+	today := models.EarningsDaily.Start(time.Now(), loc)
+	from := today.AddDate(0, 0, -29)
+	to := today
+	if v := c.Query("from"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date in YYYY-MM-DD format"})
+			return
+		}
+		from = d
+	}
+	if v := c.Query("to"); v != "" {
+		d, err := time.ParseInLocation("2006-01-02", v, loc)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date in YYYY-MM-DD format"})
+			return
+		}
+		to = d
+	}
+	if to.Before(from) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must not be before from"})
+		return
+	}
+	if from.AddDate(0, 0, maxEarningsRangeDays).Before(to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Date range cannot exceed %d days", maxEarningsRangeDays)})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	report, err := driverRepo.GetEarnings(userID.(uint), period, from, to.AddDate(0, 0, 1), loc)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get earnings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// maxHeatmapCells is the most heatmap cells returned at once
+const maxHeatmapCells = 500
+
+// GetDemandHeatmap handles retrieving recent ride demand by area so drivers can reposition.
+// Accepts an optional bounding box as min_lat, min_lng, max_lat and max_lng.
+func GetDemandHeatmap(c *gin.Context) {
+	bounds := []struct {
+		name  string
+		value float64
+	}{
+		{"min_lat", -90}, {"min_lng", -180}, {"max_lat", 90}, {"max_lng", 180},
+	}
+	for i, b := range bounds {
+		v := c.Query(b.name)
+		if v == "" {
+			continue
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + b.name})
+			return
+		}
+		bounds[i].value = f
+	}
+
+	heatmapRepo := repository.NewHeatmapRepository()
+	cells, err := heatmapRepo.GetDemandCells(bounds[0].value, bounds[1].value, bounds[2].value, bounds[3].value, maxHeatmapCells)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get heatmap"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"precision": models.HeatmapPrecision,
+		"cells":     cells,
+	})
+}
+
+// destinationModeSettings returns how many times a day drivers can turn on destination mode, and the share
+// by which a ride must reduce the driver's distance to their destination to be offered
+func destinationModeSettings() (int64, float64) {
+	uses, err := strconv.ParseInt(os.Getenv("DESTINATION_MODE_DAILY_USES"), 10, 64)
+	if err != nil || uses <= 0 {
+		uses = 2
+	}
+	progress, err := strconv.ParseFloat(os.Getenv("DESTINATION_MODE_MIN_PROGRESS"), 64)
+	if err != nil || progress <= 0 || progress >= 1 {
+		progress = 0.25
+	}
+	return uses, progress
+}
+
+// GetDriverDestination handles retrieving the current driver's destination mode
+func GetDriverDestination(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	dailyUses, _ := destinationModeSettings()
+	used, err := driverRepo.CountDestinationUses(userID.(uint), time.Now().Add(-24*time.Hour))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get destination mode"})
+		return
+	}
+	remaining := dailyUses - used
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	response := gin.H{"active": false, "remaining_uses": remaining}
+	if dest, err := driverRepo.GetActiveDestination(userID.(uint)); err == nil {
+		response["active"] = true
+		response["destination"] = dest
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// SetDriverDestinationRequest represents the request body for turning on destination mode
+type SetDriverDestinationRequest struct {
+	Lat     float64 `json:"lat" binding:"required,min=-90,max=90"`
+	Lng     float64 `json:"lng" binding:"required,min=-180,max=180"`
+	Address string  `json:"address"`
+}
+
+// SetDriverDestination handles turning on destination mode, limited to a number of uses in any 24 hours
+func SetDriverDestination(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SetDriverDestinationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	dailyUses, _ := destinationModeSettings()
+	used, err := driverRepo.CountDestinationUses(userID.(uint), time.Now().Add(-24*time.Hour))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set destination"})
+		return
+	}
+	if used >= dailyUses {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": fmt.Sprintf("Destination mode can be used %d times a day", dailyUses)})
+		return
+	}
+
+	dest := &models.DriverDestination{
+		DriverID: userID.(uint),
+		Lat:      req.Lat,
+		Lng:      req.Lng,
+		Address:  req.Address,
+	}
+	if err := driverRepo.SetDestination(dest); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to set destination"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":        "Destination mode on",
+		"destination":    dest,
+		"remaining_uses": dailyUses - used - 1,
+	})
+}
+
+// ClearDriverDestination handles turning off destination mode
+func ClearDriverDestination(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	driverRepo := repository.NewDriverRepository()
+	if err := driverRepo.ClearDestination(userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to turn off destination mode"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Destination mode off"})
+}
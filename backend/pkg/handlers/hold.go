@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/services"
+)
+
+// HoldSeats handles reserving seats on a shared ride while the passenger pays. The request body is the same
+// as for joining the ride.
+func HoldSeats(c *gin.Context) {
+	// Get request body
+	var req JoinRideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ride, actor, ok := authorizeRide(c, models.RideActionTakeUp)
+	if !ok {
+		return
+	}
+	passenger := passengerFromRequest(c, ride, actor.UserID, &req)
+	if passenger == nil {
+		return
+	}
+
+	holdRepo := repository.NewHoldRepository()
+	hold := ride.NewHold(passenger, services.SeatHoldDuration())
+	if err := holdRepo.CreateHold(hold, passenger); err != nil {
+		if errors.Is(err, models.ErrDetourTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Your stops are more than %d minutes off the ride's route", ride.MaxDetourMinutes)})
+			return
+		}
+		if errors.Is(err, models.ErrNotEnoughSeats) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Not enough seats are free on this ride"})
+			return
+		}
+		if errors.Is(err, models.ErrRideNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This ride is no longer open for joining"})
+			return
+		}
+		if errors.Is(err, models.ErrSeatsAlreadyHeld) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You already hold seats on this ride"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hold seats"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": fmt.Sprintf("%d seat(s) held until %s", hold.Seats, hold.ExpiresAt.Format("15:04")),
+		"hold":    hold,
+	})
+}
+
+// getOwnHold retrieves the hold in the path on the ride, responding with 404 unless it is the user's
+func getOwnHold(c *gin.Context, ride *models.Ride, userID uint) *models.SeatHold {
+	// Get hold ID from path
+	holdID, err := strconv.ParseUint(c.Param("holdId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid hold ID"})
+		return nil
+	}
+
+	holdRepo := repository.NewHoldRepository()
+	hold, err := holdRepo.GetHoldByID(uint(holdID))
+	if err != nil || hold.RideID != ride.ID || hold.UserID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Seat hold not found"})
+		return nil
+	}
+	return hold
+}
+
+// ConfirmSeatHoldRequest represents the request body for confirming a seat hold
+type ConfirmSeatHoldRequest struct {
+	PaymentReference string `json:"payment_reference" binding:"max=255"` // Optional, e.g. the payment intent ID
+}
+
+// ConfirmSeatHold handles turning held seats into a booking once the passenger has paid
+func ConfirmSeatHold(c *gin.Context) {
+	var req ConfirmSeatHoldRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ride, actor, ok := authorizeRide(c, models.RideActionView)
+	if !ok {
+		return
+	}
+	hold := getOwnHold(c, ride, actor.UserID)
+	if hold == nil {
+		return
+	}
+
+	// On manually approved rides the booking is a join request for the owner to answer
+	passenger := hold.Passenger(ride, services.JoinRequestExpiry())
+	holdRepo := repository.NewHoldRepository()
+	if err := holdRepo.ConfirmHold(hold, passenger, req.PaymentReference); err != nil {
+		if errors.Is(err, models.ErrHoldNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The seat hold has expired or was already used"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm seats"})
+		return
+	}
+
+	if passenger.Status == models.RideStatusPending {
+		services.Notify(ride.RiderID, models.NotificationJoinRequested, &ride.ID,
+			fmt.Sprintf("A passenger requested %d seat(s) on your ride to %s", passenger.Seats, ride.DropoffAddress))
+	} else {
+		services.Notify(ride.RiderID, models.NotificationPassengerJoined, &ride.ID,
+			fmt.Sprintf("A passenger booked %d seat(s) on your ride to %s", passenger.Seats, ride.DropoffAddress))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Seats confirmed",
+		"passenger": passenger,
+	})
+}
+
+// ReleaseSeatHold handles a passenger giving up seats they hold, for example when payment fails
+func ReleaseSeatHold(c *gin.Context) {
+	ride, actor, ok := authorizeRide(c, models.RideActionView)
+	if !ok {
+		return
+	}
+	hold := getOwnHold(c, ride, actor.UserID)
+	if hold == nil {
+		return
+	}
+
+	holdRepo := repository.NewHoldRepository()
+	if err := holdRepo.ReleaseHold(hold); err != nil {
+		if errors.Is(err, models.ErrHoldNotActive) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The seat hold is no longer active"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to release seats"})
+		return
+	}
+
+	// The freed seats go to the next passengers on the waitlist
+	services.PromoteWaitlist(ride.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Seats released"})
+}
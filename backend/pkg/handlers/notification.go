@@ -0,0 +1,55 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// GetMyNotifications handles listing the current user's most recent notifications
+func GetMyNotifications(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	unreadOnly := c.Query("unread") == "true"
+
+	notificationRepo := repository.NewNotificationRepository()
+	notifications, err := notificationRepo.GetNotificationsByUserID(userID.(uint), unreadOnly, 100)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get notifications"})
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// MarkNotificationRead handles marking one of the current user's notifications as read
+func MarkNotificationRead(c *gin.Context) {
+	// Get notification ID from path
+	notificationID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid notification ID"})
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	notificationRepo := repository.NewNotificationRepository()
+	if err := notificationRepo.MarkNotificationRead(uint(notificationID), userID.(uint)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Notification not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Notification marked as read"})
+}
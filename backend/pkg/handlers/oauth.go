@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
+)
+
+const (
+	// oauthStateCookie holds the state of the sign in started in the browser, so that a callback only
+	// completes a flow started in the same browser
+	oauthStateCookie     = "oauth_state"
+	oauthStateCookiePath = "/api/v1/auth/oauth"
+)
+
+// setOAuthStateCookie sets the state cookie, or clears it when maxAge is negative
+func setOAuthStateCookie(c *gin.Context, state string, maxAge int) {
+	secure := c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https"
+	// Lax so that the cookie is sent on the provider's redirect back
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oauthStateCookie, state, maxAge, oauthStateCookiePath, "", secure, true)
+}
+
+// StartOAuthLogin handles starting a social login with an OpenID Connect provider
+func StartOAuthLogin(c *gin.Context) {
+	startOAuth(c, 0)
+}
+
+// StartIdentityLink handles starting the flow that links a provider identity to the current user
+func StartIdentityLink(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	startOAuth(c, userID.(uint))
+}
+
+func startOAuth(c *gin.Context, linkUserID uint) {
+	provider, err := utils.GetOIDCProvider(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	state, nonce, err := utils.GenerateOAuthState(provider.Name, linkUserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate state"})
+		return
+	}
+
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, nonce)
+	if err != nil {
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+		return
+	}
+	setOAuthStateCookie(c, state, int(utils.OAuthStateLifetime.Seconds()))
+
+	c.JSON(http.StatusOK, gin.H{
+		"auth_url": authURL,
+		"state":    state,
+	})
+}
+
+// OAuthCallback handles the redirect back from an OpenID Connect provider
+func OAuthCallback(c *gin.Context) {
+	provider, err := utils.GetOIDCProvider(c.Param("provider"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	if errParam := c.Query("error"); errParam != "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Sign in was not completed: " + errParam})
+		return
+	}
+
+	code := c.Query("code")
+	if code == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Authorization code is required"})
+		return
+	}
+
+	// The state must come back to the browser that started the flow, or someone else's sign in could be
+	// completed in it
+	boundState, err := c.Cookie(oauthStateCookie)
+	if err != nil || subtle.ConstantTimeCompare([]byte(boundState), []byte(c.Query("state"))) != 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired state"})
+		return
+	}
+	setOAuthStateCookie(c, "", -1)
+
+	state, err := utils.ValidateOAuthState(c.Query("state"), provider.Name)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired state"})
+		return
+	}
+
+	claims, err := provider.Exchange(c.Request.Context(), code, state.Nonce)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Failed to verify identity"})
+		return
+	}
+
+	identityRepo := repository.NewIdentityRepository()
+	userRepo := repository.NewUserRepository()
+
+	// Identity already linked: log in as (or confirm the link for) its user
+	if identity, err := identityRepo.GetIdentity(provider.Name, claims.Subject); err == nil {
+		if state.LinkUserID != 0 && identity.UserID != state.LinkUserID {
+			c.JSON(http.StatusConflict, gin.H{"error": "Identity is already linked to another account"})
+			return
+		}
+
+		user, err := userRepo.GetUserByID(identity.UserID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		respondWithOAuthLogin(c, user, http.StatusOK, "Login successful")
+		return
+	}
+
+	identity := &models.UserIdentity{
+		Provider: provider.Name,
+		Subject:  claims.Subject,
+		Email:    claims.Email,
+	}
+
+	// Explicit linking by an authenticated user
+	if state.LinkUserID != 0 {
+		identity.UserID = state.LinkUserID
+		if err := identityRepo.LinkIdentity(identity); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message":  "Identity linked successfully",
+			"identity": identity,
+		})
+		return
+	}
+
+	if claims.Email == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Identity provider did not return an email address"})
+		return
+	}
+
+	// Existing account with the same email: link only when both the provider and this platform have verified
+	// the email. An unverified account may have been registered by someone else in anticipation, who would
+	// keep its password once the owner signs in.
+	if user, err := userRepo.GetUserByEmail(claims.Email); err == nil {
+		if !bool(claims.EmailVerified) || !user.IsVerified {
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already registered; sign in and link this provider from your profile"})
+			return
+		}
+
+		identity.UserID = user.ID
+		if err := identityRepo.LinkIdentity(identity); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to link identity"})
+			return
+		}
+		respondWithOAuthLogin(c, user, http.StatusOK, "Login successful")
+		return
+	}
+
+	// First login: create the account
+	password, err := utils.RandomPassword()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	user := &models.User{
+		Email:          claims.Email,
+		Password:       password,
+		FirstName:      claims.GivenName,
+		LastName:       claims.FamilyName,
+		ProfilePicture: claims.Picture,
+		Role:           models.RoleRider,
+		IsVerified:     bool(claims.EmailVerified),
+		RandomPassword: true,
+	}
+
+	if err := user.HashPassword(); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	if err := identityRepo.CreateUserWithIdentity(user, identity); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create user"})
+		return
+	}
+
+	respondWithOAuthLogin(c, user, http.StatusCreated, "User registered successfully")
+}
+
+func respondWithOAuthLogin(c *gin.Context, user *models.User, status int, message string) {
+	// Suspended accounts cannot log in
+	if user.IsSuspended() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+		return
+	}
+
+	// Generate JWT token
+	token, err := utils.GenerateToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(status, gin.H{
+		"message": message,
+		"user": gin.H{
+			"id":         user.ID,
+			"email":      user.Email,
+			"first_name": user.FirstName,
+			"last_name":  user.LastName,
+			"phone":      user.Phone,
+			"role":       user.Role,
+		},
+		"token": token,
+	})
+}
+
+// GetMyIdentities handles listing the identities linked to the current user
+func GetMyIdentities(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	identityRepo := repository.NewIdentityRepository()
+	identities, err := identityRepo.GetIdentitiesByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get identities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, identities)
+}
+
+// UnlinkIdentity handles removing a linked identity from the current user
+func UnlinkIdentity(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	identityRepo := repository.NewIdentityRepository()
+	if err := identityRepo.UnlinkIdentity(userID.(uint), c.Param("provider")); err != nil {
+		if errors.Is(err, models.ErrLastSignInMethod) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Set a password before unlinking your only sign-in provider"})
+			return
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Identity not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Identity unlinked successfully"})
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/database/databasetest"
+	"github.com/rakeshkumar/ridesapp/pkg/middleware"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
+	"github.com/rakeshkumar/ridesapp/pkg/utils/oidctest"
+)
+
+func newOAuthRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/api/v1/auth/oauth/:provider", StartOAuthLogin)
+	router.GET("/api/v1/auth/oauth/:provider/callback", OAuthCallback)
+	router.POST("/api/v1/users/me/identities/:provider", middleware.AuthMiddleware(), StartIdentityLink)
+	return router
+}
+
+// beginOAuth starts a sign in or link and returns the authorization URL, the state and the state cookie
+func beginOAuth(t *testing.T, router *gin.Engine, method, path, token string) (string, string, *http.Cookie) {
+	t.Helper()
+
+	req := httptest.NewRequest(method, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("%s %s: got %d: %s", method, path, w.Code, w.Body.String())
+	}
+
+	var body struct {
+		AuthURL string `json:"auth_url"`
+		State   string `json:"state"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+		t.Fatalf("failed to decode response: %v", err)
+	}
+	for _, cookie := range w.Result().Cookies() {
+		if cookie.Name == oauthStateCookie {
+			if !cookie.HttpOnly || cookie.Value != body.State {
+				t.Fatalf("state cookie is not bound to the state: %+v", cookie)
+			}
+			return body.AuthURL, body.State, cookie
+		}
+	}
+	t.Fatalf("no state cookie was set")
+	return "", "", nil
+}
+
+// oauthCallback completes a sign in as the provider's redirect would, with the state cookie if given
+func oauthCallback(router *gin.Engine, provider, code, state string, cookie *http.Cookie) *httptest.ResponseRecorder {
+	query := url.Values{"code": {code}, "state": {state}}
+	req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/oauth/"+provider+"/callback?"+query.Encode(), nil)
+	if cookie != nil {
+		req.AddCookie(cookie)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestOAuthLogin(t *testing.T) {
+	databasetest.Setup(t)
+	idp := oidctest.NewProvider(t)
+	idp.Configure(t, "mocklogin")
+	router := newOAuthRouter()
+
+	// The first sign in creates the account
+	authURL, state, cookie := beginOAuth(t, router, http.MethodGet, "/api/v1/auth/oauth/mocklogin", "")
+	w := oauthCallback(router, "mocklogin", idp.Authorize(authURL), state, cookie)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("first sign in: got %d: %s", w.Code, w.Body.String())
+	}
+	user, err := repository.NewUserRepository().GetUserByEmail(idp.Email)
+	if err != nil {
+		t.Fatalf("user was not created: %v", err)
+	}
+	identity, err := repository.NewIdentityRepository().GetIdentity("mocklogin", idp.Subject)
+	if err != nil || identity.UserID != user.ID {
+		t.Fatalf("identity was not linked to the new user: %v", err)
+	}
+
+	// Later sign ins log in as the same user
+	authURL, state, cookie = beginOAuth(t, router, http.MethodGet, "/api/v1/auth/oauth/mocklogin", "")
+	w = oauthCallback(router, "mocklogin", idp.Authorize(authURL), state, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("second sign in: got %d: %s", w.Code, w.Body.String())
+	}
+	var body struct {
+		Token string `json:"token"`
+	}
+	json.Unmarshal(w.Body.Bytes(), &body)
+	claims, err := utils.ValidateToken(body.Token)
+	if err != nil || claims.UserID != user.ID {
+		t.Errorf("sign in did not return a token for the user: %v", err)
+	}
+
+	// The account has no password, so its only identity cannot be unlinked
+	if err := repository.NewIdentityRepository().UnlinkIdentity(user.ID, "mocklogin"); !errors.Is(err, models.ErrLastSignInMethod) {
+		t.Errorf("unlinking the only identity: got %v, want ErrLastSignInMethod", err)
+	}
+}
+
+func TestOAuthLoginDoesNotTakeOverUnverifiedAccount(t *testing.T) {
+	databasetest.Setup(t)
+	idp := oidctest.NewProvider(t)
+	idp.Configure(t, "mockprehijack")
+	router := newOAuthRouter()
+
+	// Someone registered the provider account's email first, without proving they own it
+	userRepo := repository.NewUserRepository()
+	squatter := &models.User{Email: idp.Email, Password: "password", Role: models.RoleRider}
+	if err := userRepo.CreateUser(squatter); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	authURL, state, cookie := beginOAuth(t, router, http.MethodGet, "/api/v1/auth/oauth/mockprehijack", "")
+	w := oauthCallback(router, "mockprehijack", idp.Authorize(authURL), state, cookie)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("sign in with an unverified account's email: got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := repository.NewIdentityRepository().GetIdentity("mockprehijack", idp.Subject); err == nil {
+		t.Fatalf("identity was linked to the unverified account")
+	}
+
+	// Once the account's email is verified, signing in links the provider to it
+	if err := database.GetDB().Model(squatter).Update("is_verified", true).Error; err != nil {
+		t.Fatalf("failed to verify user: %v", err)
+	}
+	authURL, state, cookie = beginOAuth(t, router, http.MethodGet, "/api/v1/auth/oauth/mockprehijack", "")
+	w = oauthCallback(router, "mockprehijack", idp.Authorize(authURL), state, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("sign in with a verified account's email: got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOAuthLink(t *testing.T) {
+	databasetest.Setup(t)
+	idp := oidctest.NewProvider(t)
+	idp.Configure(t, "mocklink")
+	router := newOAuthRouter()
+
+	user := &models.User{Email: "linker@example.com", Password: "password", Role: models.RoleRider}
+	if err := repository.NewUserRepository().CreateUser(user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	token, err := utils.GenerateToken(user)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	identityRepo := repository.NewIdentityRepository()
+
+	// A link state completed in a browser that did not start the flow is refused
+	authURL, state, _ := beginOAuth(t, router, http.MethodPost, "/api/v1/users/me/identities/mocklink", token)
+	w := oauthCallback(router, "mocklink", idp.Authorize(authURL), state, nil)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("callback without the state cookie: got %d: %s", w.Code, w.Body.String())
+	}
+	if _, err := identityRepo.GetIdentity("mocklink", idp.Subject); err == nil {
+		t.Fatalf("identity was linked without the state cookie")
+	}
+
+	authURL, state, cookie := beginOAuth(t, router, http.MethodPost, "/api/v1/users/me/identities/mocklink", token)
+	w = oauthCallback(router, "mocklink", idp.Authorize(authURL), state, cookie)
+	if w.Code != http.StatusOK {
+		t.Fatalf("link: got %d: %s", w.Code, w.Body.String())
+	}
+	identity, err := identityRepo.GetIdentity("mocklink", idp.Subject)
+	if err != nil || identity.UserID != user.ID {
+		t.Fatalf("identity was not linked to the user: %v", err)
+	}
+}
+
+func TestOAuthCallbackRejectsWrongNonce(t *testing.T) {
+	idp := oidctest.NewProvider(t)
+	idp.Configure(t, "mockbadnonce")
+	idp.Nonce = "replayed-nonce"
+	router := newOAuthRouter()
+
+	authURL, state, cookie := beginOAuth(t, router, http.MethodGet, "/api/v1/auth/oauth/mockbadnonce", "")
+	w := oauthCallback(router, "mockbadnonce", idp.Authorize(authURL), state, cookie)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("got %d: %s", w.Code, w.Body.String())
+	}
+}
+
+func TestOAuthCallbackRejectsExpiredState(t *testing.T) {
+	t.Setenv("JWT_SECRET_KEY", "test-secret")
+	idp := oidctest.NewProvider(t)
+	idp.Configure(t, "mockexpired")
+	router := newOAuthRouter()
+
+	authURL, _, _ := beginOAuth(t, router, http.MethodGet, "/api/v1/auth/oauth/mockexpired", "")
+	state, err := jwt.NewWithClaims(jwt.SigningMethodHS256, &utils.OAuthStateClaims{
+		Provider: "mockexpired",
+		Nonce:    "nonce",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{"ridesapp:oauth_state"},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+		},
+	}).SignedString([]byte("test-secret"))
+	if err != nil {
+		t.Fatalf("failed to sign state: %v", err)
+	}
+
+	cookie := &http.Cookie{Name: oauthStateCookie, Value: state}
+	w := oauthCallback(router, "mockexpired", idp.Authorize(authURL), state, cookie)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("got %d: %s", w.Code, w.Body.String())
+	}
+}
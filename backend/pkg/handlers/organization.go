@@ -0,0 +1,284 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/services"
+)
+
+// CreateOrganizationRequest represents the request body for creating an organization
+type CreateOrganizationRequest struct {
+	Name        string `json:"name" binding:"required"`
+	EmailDomain string `json:"email_domain" binding:"required,fqdn"`
+}
+
+// CreateOrganization handles the creation of a new organization
+func CreateOrganization(c *gin.Context) {
+	var req CreateOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Member emails are compared in lower case
+	org := &models.Organization{
+		Name:        req.Name,
+		EmailDomain: strings.ToLower(strings.TrimSpace(req.EmailDomain)),
+	}
+
+	orgRepo := repository.NewOrganizationRepository()
+	if err := orgRepo.CreateOrganization(org); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create organization"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Organization created successfully",
+		"organization": org,
+	})
+}
+
+// GetOrganizations handles retrieving all organizations
+func GetOrganizations(c *gin.Context) {
+	orgRepo := repository.NewOrganizationRepository()
+	orgs, err := orgRepo.GetOrganizations()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get organizations"})
+		return
+	}
+
+	c.JSON(http.StatusOK, orgs)
+}
+
+// GetMyOrganizations handles retrieving the current user's organization memberships
+func GetMyOrganizations(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	orgRepo := repository.NewOrganizationRepository()
+	members, err := orgRepo.GetMembershipsByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get memberships"})
+		return
+	}
+
+	c.JSON(http.StatusOK, members)
+}
+
+// JoinOrganizationRequest represents the request body for joining an organization
+type JoinOrganizationRequest struct {
+	Email string `json:"email" binding:"required,email"` // Must belong to the organization's email domain
+}
+
+// JoinOrganization handles a user requesting membership of an organization
+func JoinOrganization(c *gin.Context) {
+	// Get organization ID from path
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req JoinOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orgRepo := repository.NewOrganizationRepository()
+	org, err := orgRepo.GetOrganizationByID(uint(orgID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Organization not found"})
+		return
+	}
+
+	// The membership email must be on the organization's domain
+	email := strings.ToLower(req.Email)
+	if !strings.HasSuffix(email, "@"+org.EmailDomain) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Email must belong to " + org.EmailDomain})
+		return
+	}
+
+	member, err := orgRepo.GetMember(org.ID, userID.(uint))
+	if err == nil && member.IsVerified() {
+		c.JSON(http.StatusConflict, gin.H{"error": "Already a member of this organization"})
+		return
+	}
+	if err != nil {
+		member = &models.OrganizationMember{
+			OrganizationID: org.ID,
+			UserID:         userID.(uint),
+		}
+	}
+	member.Email = email
+
+	// A verified account email on the organization's domain proves membership on its own
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if user.IsVerified && strings.EqualFold(user.Email, email) {
+		if err := orgRepo.SaveMember(member); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join organization"})
+			return
+		}
+		if err := orgRepo.VerifyMember(member); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join organization"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Joined organization successfully",
+			"member":  member,
+		})
+		return
+	}
+
+	// Otherwise send a verification code to the organization email, at most once a minute
+	if !member.CanResendCode(time.Now()) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "A verification code was just sent; wait a minute before asking for another"})
+		return
+	}
+	code, err := generateVerificationCode()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate verification code"})
+		return
+	}
+	if err := orgRepo.SetVerificationCode(member, code); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join organization"})
+		return
+	}
+
+	body := fmt.Sprintf("Your code to join %s is %s. It expires in %d minutes.",
+		org.Name, code, int(models.VerificationCodeLifetime.Minutes()))
+	if err := services.SendEmail(email, "Confirm your "+org.Name+" email", body); err != nil {
+		if errors.Is(err, services.ErrEmailUnavailable) {
+			c.JSON(http.StatusServiceUnavailable, gin.H{"error": "Verification emails cannot be sent at the moment"})
+			return
+		}
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to send verification code"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message": "Verification code sent to " + email,
+		"member":  member,
+	})
+}
+
+// VerifyOrganizationRequest represents the request body for confirming an organization email
+type VerifyOrganizationRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+// VerifyOrganizationMembership handles confirming an organization email with the code sent to it
+func VerifyOrganizationMembership(c *gin.Context) {
+	// Get organization ID from path
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req VerifyOrganizationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	orgRepo := repository.NewOrganizationRepository()
+	member, err := orgRepo.GetMember(uint(orgID), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Membership request not found"})
+		return
+	}
+	if member.IsVerified() {
+		c.JSON(http.StatusOK, gin.H{"message": "Membership already verified", "member": member})
+		return
+	}
+
+	if err := orgRepo.VerifyMemberCode(member, req.Code); err != nil {
+		if errors.Is(err, models.ErrInvalidVerificationCode) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid verification code"})
+			return
+		}
+		if errors.Is(err, models.ErrVerificationCodeExpired) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Verification code has expired; request a new one"})
+			return
+		}
+		if errors.Is(err, models.ErrTooManyVerificationAttempts) {
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many wrong codes; request a new one"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify membership"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Membership verified successfully",
+		"member":  member,
+	})
+}
+
+// LeaveOrganization handles a user leaving an organization
+func LeaveOrganization(c *gin.Context) {
+	// Get organization ID from path
+	orgID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid organization ID"})
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	orgRepo := repository.NewOrganizationRepository()
+	if err := orgRepo.RemoveMember(uint(orgID), userID.(uint)); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave organization"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left organization successfully"})
+}
+
+// generateVerificationCode returns a random six digit code
+func generateVerificationCode() (string, error) {
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%06d", n.Int64()), nil
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// rideActor returns the user making the request as an actor on the ride
+func rideActor(c *gin.Context, ride *models.Ride) (models.RideActor, error) {
+	// Get user ID and role from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		return models.RideActor{}, errors.New("user not authenticated")
+	}
+	userRole, _ := c.Get("userRole")
+	role, _ := userRole.(string)
+
+	actor := models.RideActor{UserID: userID.(uint), Role: models.UserRole(role)}
+	if ride.OrganizationID != nil {
+		orgRepo := repository.NewOrganizationRepository()
+		isMember, err := orgRepo.IsVerifiedMember(*ride.OrganizationID, actor.UserID)
+		if err != nil {
+			return models.RideActor{}, err
+		}
+		actor.Member = isMember
+	}
+	return actor, nil
+}
+
+// authorizeRide loads the ride in the path and checks that the user may perform the action on it. Users who
+// may not see the ride get 404, and users who may see it but not perform the action get 403. Returns false
+// after responding when the request cannot go on.
+func authorizeRide(c *gin.Context, action models.RideAction) (*models.Ride, models.RideActor, bool) {
+	// Get ride ID from path
+	rideID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID"})
+		return nil, models.RideActor{}, false
+	}
+
+	if _, exists := c.Get("userID"); !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, models.RideActor{}, false
+	}
+
+	rideRepo := repository.NewRideRepository()
+	ride, err := rideRepo.GetRideByID(uint(rideID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return nil, models.RideActor{}, false
+	}
+
+	actor, err := rideActor(c, ride)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check access to ride"})
+		return nil, models.RideActor{}, false
+	}
+
+	if err := models.AuthorizeRide(ride, actor, action); err != nil {
+		if errors.Is(err, models.ErrRideForbidden) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to do this on this ride"})
+			return nil, models.RideActor{}, false
+		}
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		return nil, models.RideActor{}, false
+	}
+	return ride, actor, true
+}
@@ -1,30 +1,44 @@
 package handlers
 
 import (
+	"errors"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/rakeshkumar/ridesapp/pkg/models"
 	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/services"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
 )
 
 // CreateRideRequest represents the request body for creating a ride
 type CreateRideRequest struct {
-	RideType       string    `json:"ride_type" binding:"required,oneof=shared on_demand"`
-	PickupLat      float64   `json:"pickup_lat" binding:"required"`
-	PickupLng      float64   `json:"pickup_lng" binding:"required"`
-	DropoffLat     float64   `json:"dropoff_lat" binding:"required"`
-	DropoffLng     float64   `json:"dropoff_lng" binding:"required"`
-	PickupAddress  string    `json:"pickup_address" binding:"required"`
-	DropoffAddress string    `json:"dropoff_address" binding:"required"`
-	Price          float64   `json:"price" binding:"required"`
-	Distance       float64   `json:"distance" binding:"required"`
-	Duration       int       `json:"duration" binding:"required"`
-	SeatsAvailable int       `json:"seats_available" binding:"required_if=RideType shared"`
-	DepartureTime  time.Time `json:"departure_time" binding:"required_if=RideType shared"`
-	PaymentMethod  string    `json:"payment_method" binding:"required,oneof=cash card wallet"`
+	RideType         string              `json:"ride_type" binding:"required,oneof=shared on_demand"`
+	Tier             string              `json:"tier" binding:"omitempty,oneof=economy xl premium accessible"` // For on-demand rides, defaults to economy
+	PickupLat        float64             `json:"pickup_lat" binding:"required"`
+	PickupLng        float64             `json:"pickup_lng" binding:"required"`
+	DropoffLat       float64             `json:"dropoff_lat" binding:"required"`
+	DropoffLng       float64             `json:"dropoff_lng" binding:"required"`
+	PickupAddress    string              `json:"pickup_address" binding:"required"`
+	DropoffAddress   string              `json:"dropoff_address" binding:"required"`
+	Price            float64             `json:"price" binding:"required_if=RideType shared"` // On-demand rides are priced from the tier's rate card
+	Distance         float64             `json:"distance" binding:"required"`
+	Duration         int                 `json:"duration" binding:"required"`
+	SeatsAvailable   int                 `json:"seats_available" binding:"required_if=RideType shared,min=0"`
+	DepartureTime    time.Time           `json:"departure_time" binding:"required_if=RideType shared"`
+	RoutePolyline    string              `json:"route_polyline"`                                        // For shared rides, the planned route; defaults to a straight line
+	MaxDetourMinutes *int                `json:"max_detour_minutes" binding:"omitempty,min=0,max=30"`   // For shared rides, defaults to 10
+	BookingMode      string              `json:"booking_mode" binding:"omitempty,oneof=instant manual"` // For shared rides, defaults to instant
+	OptimizeStops    bool                `json:"optimize_stops"`                                        // For shared rides, order passenger stops for the least detour
+	Pooled           bool                `json:"pooled"`                                                // For on-demand rides, share the car with other riders for a discount
+	PaymentMethod    string              `json:"payment_method" binding:"required,oneof=cash card wallet"`
+	OrganizationID   *uint               `json:"organization_id"` // Optional, restricts a shared ride to an organization's members
+	Preferences      *models.Preferences `json:"preferences"`     // Optional, defaults to the preferences on the user's profile
 }
 
 // CreateRide handles the creation of a new ride
@@ -42,6 +56,22 @@ func CreateRide(c *gin.Context) {
 		return
 	}
 
+	// Rides take the preferences on the user's profile unless they are given
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	preferences := user.Preferences
+	if req.Preferences != nil {
+		preferences = *req.Preferences
+	}
+	if err := preferences.Validate(user.Gender); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	// Create ride
 	ride := &models.Ride{
 		RideType:       models.RideType(req.RideType),
@@ -57,6 +87,23 @@ func CreateRide(c *gin.Context) {
 		Distance:       req.Distance,
 		Duration:       req.Duration,
 		PaymentMethod:  models.PaymentMethod(req.PaymentMethod),
+		Preferences:    preferences,
+	}
+
+	// On-demand rides are priced from the chosen tier's rate card
+	if req.RideType == string(models.RideTypeOnDemand) {
+		ride.Tier = models.RideTierEconomy
+		if req.Tier != "" {
+			ride.Tier = models.RideTier(req.Tier)
+		}
+		tier, _ := models.GetTierDefinition(ride.Tier)
+		ride.Price = tier.RateCard.Fare(req.Distance, req.Duration)
+
+		// Pooled riders get a discount for sharing the car, whether or not others join them
+		if req.Pooled {
+			ride.Pooled = true
+			ride.Price = math.Round(ride.Price*(1-services.PoolDiscount())*100) / 100
+		}
 	}
 
 	// Set shared ride specific fields
@@ -64,11 +111,75 @@ func CreateRide(c *gin.Context) {
 		ride.SeatsAvailable = req.SeatsAvailable
 		ride.SeatsBooked = 0
 		ride.DepartureTime = req.DepartureTime
+
+		// Passengers are matched against the planned route, within the detour the driver accepts
+		route, err := plannedRoute(req.RoutePolyline, req.PickupLat, req.PickupLng, req.DropoffLat, req.DropoffLng)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		ride.RoutePolyline = utils.EncodePolyline(route)
+		ride.RouteMinLat, ride.RouteMaxLat, ride.RouteMinLng, ride.RouteMaxLng = utils.Bounds(route)
+		ride.MaxDetourMinutes = models.DefaultMaxDetourMinutes
+		if req.MaxDetourMinutes != nil {
+			ride.MaxDetourMinutes = *req.MaxDetourMinutes
+		}
+		ride.BookingMode = models.BookingModeInstant
+		if req.BookingMode != "" {
+			ride.BookingMode = models.BookingMode(req.BookingMode)
+		}
+		ride.OptimizeStops = req.OptimizeStops
+
+		// Only approved drivers with valid documents can offer rides
+		driverRepo := repository.NewDriverRepository()
+		if err := driverRepo.CheckDriverEligibility(userID.(uint)); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You cannot offer rides: " + err.Error()})
+			return
+		}
+
+		// The ride is driven with the owner's active vehicle, which limits the seats on offer
+		vehicleRepo := repository.NewVehicleRepository()
+		vehicle, err := vehicleRepo.GetActiveVehicle(userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Add a vehicle before offering a shared ride"})
+			return
+		}
+		if req.SeatsAvailable > vehicle.SeatCapacity {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Your vehicle only has %d passenger seats", vehicle.SeatCapacity)})
+			return
+		}
+		ride.VehicleID = &vehicle.ID
+
+		// Only verified members can create rides scoped to an organization
+		if req.OrganizationID != nil {
+			orgRepo := repository.NewOrganizationRepository()
+			isMember, err := orgRepo.IsVerifiedMember(*req.OrganizationID, userID.(uint))
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check organization membership"})
+				return
+			}
+			if !isMember {
+				c.JSON(http.StatusForbidden, gin.H{"error": "You are not a verified member of this organization"})
+				return
+			}
+			ride.OrganizationID = req.OrganizationID
+		}
 	}
 
-	// Save ride to database
+	// Save ride to database. Pooled rides are saved together with their match into a pool of concurrent
+	// requests heading the same way.
 	rideRepo := repository.NewRideRepository()
-	if err := rideRepo.CreateRide(ride); err != nil {
+	if ride.Pooled {
+		ride.Rider = *user
+		if err := services.CreatePooledRide(ride); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pooled ride; try again or ride without pooling"})
+			return
+		}
+	} else if err := rideRepo.CreateRide(ride); err != nil {
+		if errors.Is(err, models.ErrVehicleOverbooked) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Your vehicle's capacity changed; check the seats on offer"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ride"})
 		return
 	}
@@ -79,27 +190,34 @@ func CreateRide(c *gin.Context) {
 	})
 }
 
+// plannedRoute decodes the planned route of a shared ride, defaulting to a straight line from pickup to dropoff
+func plannedRoute(polyline string, pickupLat, pickupLng, dropoffLat, dropoffLng float64) ([]utils.LatLng, error) {
+	if polyline == "" {
+		return []utils.LatLng{{Lat: pickupLat, Lng: pickupLng}, {Lat: dropoffLat, Lng: dropoffLng}}, nil
+	}
+	route, err := utils.DecodePolyline(polyline)
+	if err != nil || len(route) < 2 {
+		return nil, errors.New("invalid route polyline")
+	}
+	return route, nil
+}
+
 // GetRideByID handles retrieving a ride by ID
 func GetRideByID(c *gin.Context) {
-	// Get ride ID from path
-	rideID, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID"})
+	ride, actor, ok := authorizeRide(c, models.RideActionView)
+	if !ok {
 		return
 	}
 
-	// Get ride from database
-	rideRepo := repository.NewRideRepository()
-	ride, err := rideRepo.GetRideByID(uint(rideID))
-	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
-		return
+	// Users who can only find the ride do not see who is on it
+	if !ride.IsParticipant(actor) && !models.HasPermission(actor.Role, models.PermissionRidesReadAny) {
+		ride.HideContactDetails()
 	}
 
 	c.JSON(http.StatusOK, ride)
 }
 
-// GetMyRides handles retrieving all rides for the authenticated user
+// GetMyRides handles retrieving all rides for the authenticated user, as rider, driver and passenger
 func GetMyRides(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("userID")
@@ -108,50 +226,249 @@ func GetMyRides(c *gin.Context) {
 		return
 	}
 
-	// Get user role from context (set by auth middleware)
-	userRole, exists := c.Get("userRole")
+	// Get rides from database
+	rideRepo := repository.NewRideRepository()
+	rides, err := rideRepo.GetRidesByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rides"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rides)
+}
+
+// SearchSharedRidesRequest represents the query parameters for searching shared rides
+type SearchSharedRidesRequest struct {
+	Match               string     `form:"match" binding:"omitempty,oneof=radius route"` // Defaults to radius
+	OriginLat           *float64   `form:"origin_lat" binding:"omitempty,min=-90,max=90"`
+	OriginLng           *float64   `form:"origin_lng" binding:"omitempty,min=-180,max=180"`
+	OriginRadiusKm      float64    `form:"origin_radius_km" binding:"omitempty,gt=0,max=50"`
+	DestinationLat      *float64   `form:"destination_lat" binding:"omitempty,min=-90,max=90"`
+	DestinationLng      *float64   `form:"destination_lng" binding:"omitempty,min=-180,max=180"`
+	DestinationRadiusKm float64    `form:"destination_radius_km" binding:"omitempty,gt=0,max=50"`
+	DepartureTime       *time.Time `form:"departure_time" time_format:"2006-01-02T15:04:05Z07:00"` // Preferred departure
+	TimeWindowMinutes   int        `form:"time_window_minutes" binding:"omitempty,min=1,max=1440"`
+	Seats               int        `form:"seats" binding:"omitempty,min=1"`
+	Page                int        `form:"page" binding:"omitempty,min=1"`
+	PageSize            int        `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// Defaults for shared ride searches
+const (
+	defaultSearchRadiusKm      = 2.0
+	defaultSearchWindowMinutes = 60
+	defaultSearchPageSize      = 20
+	maxExcludedShown           = 10
+)
+
+// GetAvailableSharedRides handles searching available shared rides by origin, destination, departure time
+// and seats needed. By radius, matches are ranked by walking distance to the pickup and from the dropoff
+// plus the difference from the preferred departure. By route, rides must be able to pick up at the origin
+// and drop off at the destination within their maximum detour, and are ranked by detour plus the difference
+// from the preferred departure. Matches are paginated. Rides that do not suit the passenger's preferences are
+// left out; the first of them are listed separately with the preferences that excluded them.
+func GetAvailableSharedRides(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
 	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
 	}
 
-	// Get rides from database
-	rideRepo := repository.NewRideRepository()
-	var rides []models.Ride
-	var err error
+	var req SearchSharedRidesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	if userRole == string(models.RoleRider) {
-		rides, err = rideRepo.GetRidesByRiderID(userID.(uint))
-	} else if userRole == string(models.RoleDriver) {
-		rides, err = rideRepo.GetRidesByDriverID(userID.(uint))
+	if (req.OriginLat == nil) != (req.OriginLng == nil) || (req.DestinationLat == nil) != (req.DestinationLng == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Latitude and longitude must be given together"})
+		return
 	}
 
+	search := models.SharedRideSearch{
+		Mode:          models.MatchByRadius,
+		DepartureTime: req.DepartureTime,
+		TimeWindow:    defaultSearchWindowMinutes * time.Minute,
+		Seats:         1,
+		Page:          1,
+		PageSize:      defaultSearchPageSize,
+	}
+	if req.OriginLat != nil {
+		search.Origin = &models.GeoPoint{Lat: *req.OriginLat, Lng: *req.OriginLng, RadiusKm: defaultSearchRadiusKm}
+		if req.OriginRadiusKm > 0 {
+			search.Origin.RadiusKm = req.OriginRadiusKm
+		}
+	}
+	if req.DestinationLat != nil {
+		search.Destination = &models.GeoPoint{Lat: *req.DestinationLat, Lng: *req.DestinationLng, RadiusKm: defaultSearchRadiusKm}
+		if req.DestinationRadiusKm > 0 {
+			search.Destination.RadiusKm = req.DestinationRadiusKm
+		}
+	}
+	if req.Match == string(models.MatchByRoute) {
+		if search.Origin == nil || search.Destination == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Matching by route requires an origin and a destination"})
+			return
+		}
+		search.Mode = models.MatchByRoute
+	}
+	if req.TimeWindowMinutes > 0 {
+		search.TimeWindow = time.Duration(req.TimeWindowMinutes) * time.Minute
+	}
+	if req.Seats > 0 {
+		search.Seats = req.Seats
+	}
+	if req.Page > 0 {
+		search.Page = req.Page
+	}
+	if req.PageSize > 0 {
+		search.PageSize = req.PageSize
+	}
+
+	// Rides are matched against the preferences on the passenger's profile
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(userID.(uint))
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rides"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
+	search.Preferences, search.Gender = user.Preferences, user.Gender
 
-	c.JSON(http.StatusOK, rides)
+	rideRepo := repository.NewRideRepository()
+	matches, total, err := rideRepo.SearchSharedRides(userID.(uint), search)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get available rides"})
+		return
+	}
+
+	excludedSearch := search
+	excludedSearch.Excluded, excludedSearch.Page, excludedSearch.PageSize = true, 1, maxExcludedShown
+	excluded, excludedTotal, err := rideRepo.SearchSharedRides(userID.(uint), excludedSearch)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get available rides"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"matches":        matches,
+		"page":           search.Page,
+		"page_size":      search.PageSize,
+		"total":          total,
+		"excluded":       excluded,
+		"excluded_total": excludedTotal,
+	})
 }
 
-// GetAvailableSharedRides handles retrieving all available shared rides
-func GetAvailableSharedRides(c *gin.Context) {
-	// Get rides from database
+// maxDestinationLocationAge is how old a driver's last reported location can be and still be used as
+// their position in destination mode
+const maxDestinationLocationAge = 5 * time.Minute
+
+// destinationFilter returns whether a ride can be offered to the driver under destination mode: with a
+// destination set, the ride's dropoff must bring the driver sufficiently closer to it than they are now
+func destinationFilter(driverID uint) func(ride *models.Ride) bool {
+	driverRepo := repository.NewDriverRepository()
+	dest, err := driverRepo.GetActiveDestination(driverID)
+	if err != nil {
+		return func(*models.Ride) bool { return true }
+	}
+
+	_, minProgress := destinationModeSettings()
+	userRepo := repository.NewUserRepository()
+	location, err := userRepo.GetLatestLocation(driverID)
+	current := err == nil && time.Since(location.CreatedAt) <= maxDestinationLocationAge
+
+	return func(ride *models.Ride) bool {
+		// Without a recent position, measure from the pickup the driver is heading to
+		fromLat, fromLng := ride.PickupLat, ride.PickupLng
+		if current {
+			fromLat, fromLng = location.Latitude, location.Longitude
+		}
+		return utils.BringsCloser(fromLat, fromLng, ride.DropoffLat, ride.DropoffLng, dest.Lat, dest.Lng, minProgress)
+	}
+}
+
+// GetAvailableOnDemandRides handles listing the pending on-demand rides the current driver's active vehicle qualifies for
+func GetAvailableOnDemandRides(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Rides are only offered to online drivers
+	driverRepo := repository.NewDriverRepository()
+	if _, err := driverRepo.GetOpenSession(userID.(uint)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Go online to see available rides"})
+		return
+	}
+
+	vehicleRepo := repository.NewVehicleRepository()
+	vehicle, err := vehicleRepo.GetActiveVehicle(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Activate a vehicle before looking for rides"})
+		return
+	}
+
 	rideRepo := repository.NewRideRepository()
-	rides, err := rideRepo.GetAvailableSharedRides()
+	rides, err := rideRepo.GetAvailableOnDemandRides(userID.(uint), models.QualifyingTiers(vehicle, time.Now()))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get available rides"})
 		return
 	}
 
+	// Riders and the driver must suit each other's preferences
+	userRepo := repository.NewUserRepository()
+	driver, err := userRepo.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Pooled rides are offered once per pool, to drivers with a seat for each of its riders
+	offered := destinationFilter(userID.(uint))
+	filtered := make([]models.Ride, 0, len(rides))
+	pools := make(map[uint]bool)
+	for i := range rides {
+		if rides[i].PoolID != nil {
+			if pools[*rides[i].PoolID] {
+				continue
+			}
+			pools[*rides[i].PoolID] = true
+		}
+		trip := rides[i].TripRides()
+		if offered(&rides[i]) && len(trip) <= vehicle.SeatCapacity && len(tripConflicts(driver, trip)) == 0 {
+			filtered = append(filtered, rides[i])
+		}
+	}
+	rides = filtered
+
 	c.JSON(http.StatusOK, rides)
 }
 
+// tripConflicts returns the ways a driver fails the preferences of the riders on a trip. Riders must be loaded.
+func tripConflicts(driver *models.User, trip []models.Ride) []models.PreferenceConflict {
+	offer := driver.PreferenceOffer()
+	var conflicts []models.PreferenceConflict
+	for _, ride := range trip {
+		conflicts = append(conflicts, ride.Preferences.Conflicts(ride.Rider.Gender, offer)...)
+	}
+	return conflicts
+}
+
 // GetUpcomingSharedRides handles retrieving upcoming shared rides
 func GetUpcomingSharedRides(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
 	// Get rides from database
 	rideRepo := repository.NewRideRepository()
-	rides, err := rideRepo.GetUpcomingSharedRides()
+	rides, err := rideRepo.GetUpcomingSharedRides(userID.(uint))
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get upcoming rides"})
 		return
@@ -160,48 +477,59 @@ func GetUpcomingSharedRides(c *gin.Context) {
 	c.JSON(http.StatusOK, rides)
 }
 
-// UpdateRideStatus handles updating the status of a ride
+// UpdateRideStatus handles starting, completing or cancelling a ride. Only the driver or shared ride owner
+// starts and completes it, riders can only cancel before it starts, and completed or cancelled rides are final.
 func UpdateRideStatus(c *gin.Context) {
-	// Get ride ID from path
-	rideID, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID"})
-		return
-	}
-
 	// Get status from request body
 	var req struct {
-		Status string `json:"status" binding:"required,oneof=pending accepted started completed cancelled"`
+		Status string `json:"status" binding:"required,oneof=accepted started completed cancelled"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Update ride status in database
-	rideRepo := repository.NewRideRepository()
-	if err := rideRepo.UpdateRideStatus(uint(rideID), models.RideStatus(req.Status)); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride status"})
+	// Accepting a ride assigns the driver and is gated on driver approval
+	if req.Status == string(models.RideStatusAccepted) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use POST /rides/:id/accept to accept a ride"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Ride status updated successfully"})
-}
+	ride, actor, ok := authorizeRide(c, models.RideActionUpdateStatus)
+	if !ok {
+		return
+	}
 
-// JoinRideRequest represents the request body for joining a ride
-type JoinRideRequest struct {
-	Seats int `json:"seats" binding:"required,min=1"`
-}
+	status := models.RideStatus(req.Status)
+	from := ride.StatusTransitionsTo(actor, status)
+	if len(from) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot set this ride to " + req.Status})
+		return
+	}
 
-// JoinRide handles a user joining a shared ride
-func JoinRide(c *gin.Context) {
-	// Get ride ID from path
-	rideID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	// Update ride status in database; the ride may have moved on since it was loaded. Cancelling also ends
+	// the bookings in progress and tells the people on the ride.
+	var err error
+	if status == models.RideStatusCancelled {
+		err = services.CancelRide(ride, from, actor.UserID)
+	} else {
+		rideRepo := repository.NewRideRepository()
+		err = rideRepo.UpdateRideStatus(ride.ID, from, status, actor.UserID)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID"})
+		if errors.Is(err, models.ErrRideStatusTransition) {
+			c.JSON(http.StatusConflict, gin.H{"error": "A " + string(ride.Status) + " ride cannot be " + req.Status})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride status"})
 		return
 	}
 
+	c.JSON(http.StatusOK, gin.H{"message": "Ride status updated successfully"})
+}
+
+// AcceptRide handles a driver accepting a pending on-demand ride
+func AcceptRide(c *gin.Context) {
 	// Get user ID from context (set by auth middleware)
 	userID, exists := c.Get("userID")
 	if !exists {
@@ -209,6 +537,163 @@ func JoinRide(c *gin.Context) {
 		return
 	}
 
+	// Only approved drivers with valid documents can accept rides
+	driverRepo := repository.NewDriverRepository()
+	if err := driverRepo.CheckDriverEligibility(userID.(uint)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot accept rides: " + err.Error()})
+		return
+	}
+	if _, err := driverRepo.GetOpenSession(userID.(uint)); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Go online before accepting rides"})
+		return
+	}
+
+	ride, _, ok := authorizeRide(c, models.RideActionTakeUp)
+	if !ok {
+		return
+	}
+	if ride.RideType != models.RideTypeOnDemand {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only on-demand rides can be accepted"})
+		return
+	}
+
+	// The ride is driven with the driver's active vehicle
+	vehicleRepo := repository.NewVehicleRepository()
+	vehicle, err := vehicleRepo.GetActiveVehicle(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Activate a vehicle before accepting rides"})
+		return
+	}
+	tier, ok := models.GetTierDefinition(ride.Tier)
+	if !ok || !tier.Requirements.Qualifies(vehicle, time.Now()) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your vehicle does not qualify for this ride's tier"})
+		return
+	}
+	if !destinationFilter(userID.(uint))(ride) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Ride does not head towards your destination"})
+		return
+	}
+	userRepo := repository.NewUserRepository()
+	driver, err := userRepo.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Pooled rides are accepted together with the other rides of their pool
+	trip := []models.Ride{*ride}
+	poolRepo := repository.NewPoolRepository()
+	var pool *models.RidePool
+	if ride.PoolID != nil {
+		pool, err = poolRepo.GetPoolByID(*ride.PoolID)
+		if err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Ride is no longer available"})
+			return
+		}
+		trip = pool.Rides
+	}
+	if len(trip) > vehicle.SeatCapacity {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Your vehicle does not have a seat for every rider of this pool"})
+		return
+	}
+	if conflicts := tripConflicts(driver, trip); len(conflicts) > 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You and the rider do not suit each other's preferences", "conflicts": conflicts})
+		return
+	}
+
+	if pool != nil {
+		if _, err := poolRepo.AssignDriver(pool.ID, driver, vehicle); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Ride is no longer available"})
+			return
+		}
+	} else {
+		rideRepo := repository.NewRideRepository()
+		if err := rideRepo.AssignDriver(ride.ID, userID.(uint), vehicle.ID); err != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Ride is no longer available"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ride accepted successfully"})
+}
+
+// DeclineRide handles a driver turning down a pending on-demand ride so it is no longer offered to them
+func DeclineRide(c *gin.Context) {
+	ride, actor, ok := authorizeRide(c, models.RideActionTakeUp)
+	if !ok {
+		return
+	}
+	if ride.RideType != models.RideTypeOnDemand {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only on-demand rides can be declined"})
+		return
+	}
+
+	// Declining a pooled ride declines every ride of its pool
+	rideIDs := []uint{ride.ID}
+	if ride.PoolID != nil {
+		poolRepo := repository.NewPoolRepository()
+		if pool, err := poolRepo.GetPoolByID(*ride.PoolID); err == nil {
+			for _, pooled := range pool.Rides {
+				if pooled.ID != ride.ID {
+					rideIDs = append(rideIDs, pooled.ID)
+				}
+			}
+		}
+	}
+
+	rideRepo := repository.NewRideRepository()
+	for _, rideID := range rideIDs {
+		if err := rideRepo.DeclineRide(rideID, actor.UserID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to decline ride"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ride declined"})
+}
+
+// TipRideRequest represents the request body for tipping the driver of a ride
+type TipRideRequest struct {
+	Amount float64 `json:"amount" binding:"required,gt=0,lte=500"`
+}
+
+// TipRide handles a rider tipping the driver of a completed ride
+func TipRide(c *gin.Context) {
+	var req TipRideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	ride, actor, ok := authorizeRide(c, models.RideActionTip)
+	if !ok {
+		return
+	}
+
+	// Tip to cents
+	amount := math.Round(req.Amount*100) / 100
+	rideRepo := repository.NewRideRepository()
+	if err := rideRepo.AddTip(ride.ID, actor.UserID, amount); err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only the rider of a completed ride can tip its driver, once"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tip added successfully"})
+}
+
+// JoinRideRequest represents the request body for joining a ride
+type JoinRideRequest struct {
+	Seats          int      `json:"seats" binding:"required,min=1"`
+	PickupLat      *float64 `json:"pickup_lat" binding:"omitempty,min=-90,max=90"` // Optional, defaults to the ride's pickup
+	PickupLng      *float64 `json:"pickup_lng" binding:"omitempty,min=-180,max=180"`
+	PickupAddress  string   `json:"pickup_address"`
+	DropoffLat     *float64 `json:"dropoff_lat" binding:"omitempty,min=-90,max=90"` // Optional, defaults to the ride's dropoff
+	DropoffLng     *float64 `json:"dropoff_lng" binding:"omitempty,min=-180,max=180"`
+	DropoffAddress string   `json:"dropoff_address"`
+}
+
+// JoinRide handles a user joining a shared ride
+func JoinRide(c *gin.Context) {
 	// Get request body
 	var req JoinRideRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -216,25 +701,195 @@ func JoinRide(c *gin.Context) {
 		return
 	}
 
-	// Create passenger
-	passenger := &models.RidePassenger{
-		RideID: uint(rideID),
-		UserID: userID.(uint),
-		Seats:  req.Seats,
-		Status: models.RideStatusPending,
+	// Organization rides can only be found and joined by verified members
+	ride, actor, ok := authorizeRide(c, models.RideActionTakeUp)
+	if !ok {
+		return
+	}
+
+	// Create passenger. On manually approved rides this is a join request for the owner to answer.
+	passenger := passengerFromRequest(c, ride, actor.UserID, &req)
+	if passenger == nil {
+		return
 	}
 
-	// Add passenger to ride
+	// Add passenger to ride, or to its waitlist when the ride is full
 	rideRepo := repository.NewRideRepository()
 	if err := rideRepo.AddPassenger(passenger); err != nil {
+		if errors.Is(err, models.ErrDetourTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Your stops are more than %d minutes off the ride's route", ride.MaxDetourMinutes)})
+			return
+		}
+		if errors.Is(err, models.ErrNotEnoughSeats) {
+			joinWaitlist(c, ride, passenger)
+			return
+		}
+		if errors.Is(err, models.ErrRideNotOpen) {
+			c.JSON(http.StatusConflict, gin.H{"error": "This ride is no longer open for joining"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join ride: " + err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Joined ride successfully"})
+	if passenger.Status == models.RideStatusPending {
+		services.Notify(ride.RiderID, models.NotificationJoinRequested, &ride.ID,
+			fmt.Sprintf("A passenger requested %d seat(s) on your ride to %s", passenger.Seats, ride.DropoffAddress))
+		c.JSON(http.StatusOK, gin.H{
+			"message":   "Join request sent to the ride owner",
+			"passenger": passenger,
+		})
+		return
+	}
+
+	services.Notify(ride.RiderID, models.NotificationPassengerJoined, &ride.ID,
+		fmt.Sprintf("A passenger booked %d seat(s) on your ride to %s", passenger.Seats, ride.DropoffAddress))
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Joined ride successfully",
+		"passenger": passenger,
+	})
+}
+
+// passengerFromRequest returns the passenger a request to join a ride makes. Responds and returns nil when the
+// ride cannot be joined.
+func passengerFromRequest(c *gin.Context, ride *models.Ride, userID uint, req *JoinRideRequest) *models.RidePassenger {
+	if ride.RideType != models.RideTypeShared || ride.Status != models.RideStatusPending || !ride.DepartureTime.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This ride is not open for joining"})
+		return nil
+	}
+	if ride.RiderID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot join your own ride"})
+		return nil
+	}
+	if ride.Preferences.WomenOnly {
+		userRepo := repository.NewUserRepository()
+		user, err := userRepo.GetUserByID(userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return nil
+		}
+		if user.Gender != models.GenderFemale {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This ride only takes women riders"})
+			return nil
+		}
+	}
+	if (req.PickupLat == nil) != (req.PickupLng == nil) || (req.DropoffLat == nil) != (req.DropoffLng == nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stops need both a latitude and a longitude"})
+		return nil
+	}
+
+	passenger := ride.NewPassenger(userID, req.Seats, services.JoinRequestExpiry())
+	if req.PickupLat != nil {
+		passenger.PickupLat, passenger.PickupLng, passenger.PickupAddress = *req.PickupLat, *req.PickupLng, req.PickupAddress
+	}
+	if req.DropoffLat != nil {
+		passenger.DropoffLat, passenger.DropoffLng, passenger.DropoffAddress = *req.DropoffLat, *req.DropoffLng, req.DropoffAddress
+	}
+	return passenger
+}
+
+// joinWaitlist puts the passenger on the waitlist of a full ride and responds with their position
+func joinWaitlist(c *gin.Context, ride *models.Ride, passenger *models.RidePassenger) {
+	if passenger.Seats > ride.SeatsAvailable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("This ride only offers %d seats", ride.SeatsAvailable)})
+		return
+	}
+
+	waitlistRepo := repository.NewWaitlistRepository()
+	entry := &models.RideWaitlistEntry{
+		RideID:         ride.ID,
+		UserID:         passenger.UserID,
+		Seats:          passenger.Seats,
+		PickupLat:      passenger.PickupLat,
+		PickupLng:      passenger.PickupLng,
+		PickupAddress:  passenger.PickupAddress,
+		DropoffLat:     passenger.DropoffLat,
+		DropoffLng:     passenger.DropoffLng,
+		DropoffAddress: passenger.DropoffAddress,
+	}
+	if err := waitlistRepo.AddToWaitlist(entry); err != nil {
+		if errors.Is(err, models.ErrAlreadyWaitlisted) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You are already on the waitlist for this ride"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join waitlist"})
+		return
+	}
+
+	// Seats may have been freed since the ride was found full
+	services.PromoteWaitlist(ride.ID)
+	entry, err := waitlistRepo.GetOpenEntry(ride.ID, passenger.UserID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get waitlist position"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"message":  "The ride is full; you have been added to its waitlist",
+		"waitlist": entry,
+	})
 }
 
-// LeaveRide handles a user leaving a shared ride
+// AcceptJoinRequest handles the ride owner accepting a pending join request
+func AcceptJoinRequest(c *gin.Context) {
+	respondToJoinRequest(c, true)
+}
+
+// DeclineJoinRequest handles the ride owner declining a pending join request, releasing its seats
+func DeclineJoinRequest(c *gin.Context) {
+	respondToJoinRequest(c, false)
+}
+
+// respondToJoinRequest answers the join request in the path on behalf of the ride owner and notifies the passenger
+func respondToJoinRequest(c *gin.Context, accept bool) {
+	// Get passenger ID from path
+	passengerID, err := strconv.ParseUint(c.Param("passengerId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid passenger ID"})
+		return
+	}
+
+	ride, _, ok := authorizeRide(c, models.RideActionManagePassengers)
+	if !ok {
+		return
+	}
+
+	rideRepo := repository.NewRideRepository()
+	passenger, err := rideRepo.GetPassengerByID(uint(passengerID))
+	if err != nil || passenger.RideID != ride.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Join request not found"})
+		return
+	}
+
+	if err := rideRepo.RespondToJoinRequest(passenger, accept); err != nil {
+		if errors.Is(err, models.ErrJoinRequestNotPending) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Join request is no longer pending"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to answer join request"})
+		return
+	}
+	if !accept {
+		services.PromoteWaitlist(passenger.RideID)
+	}
+
+	message := "Join request accepted"
+	if accept {
+		services.Notify(passenger.UserID, models.NotificationJoinAccepted, &passenger.RideID,
+			fmt.Sprintf("Your request to join the ride to %s was accepted", passenger.Ride.DropoffAddress))
+	} else {
+		message = "Join request declined"
+		services.Notify(passenger.UserID, models.NotificationJoinDeclined, &passenger.RideID,
+			fmt.Sprintf("Your request to join the ride to %s was declined", passenger.Ride.DropoffAddress))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   message,
+		"passenger": passenger,
+	})
+}
+
+// LeaveRide handles a user leaving a shared ride, or the ride owner removing one of its passengers
 func LeaveRide(c *gin.Context) {
 	// Get passenger ID from path
 	passengerID, err := strconv.ParseUint(c.Param("passengerId"), 10, 32)
@@ -243,28 +898,48 @@ func LeaveRide(c *gin.Context) {
 		return
 	}
 
-	// Remove passenger from ride
+	ride, actor, ok := authorizeRide(c, models.RideActionView)
+	if !ok {
+		return
+	}
+
 	rideRepo := repository.NewRideRepository()
-	if err := rideRepo.RemovePassenger(uint(passengerID)); err != nil {
+	passenger, err := rideRepo.GetPassengerByID(uint(passengerID))
+	if err != nil || passenger.RideID != ride.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Passenger not found"})
+		return
+	}
+
+	// Passengers can only take themselves off the ride
+	if passenger.UserID != actor.UserID {
+		if err := models.AuthorizeRide(ride, actor, models.RideActionManagePassengers); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not allowed to do this on this ride"})
+			return
+		}
+	}
+
+	// Remove passenger from ride
+	if err := rideRepo.RemovePassenger(passenger.ID); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave ride"})
 		return
 	}
 
+	// The freed seats go to the next passengers on the waitlist
+	services.PromoteWaitlist(passenger.RideID)
+
 	c.JSON(http.StatusOK, gin.H{"message": "Left ride successfully"})
 }
 
 // GetRidePassengers handles retrieving all passengers for a ride
 func GetRidePassengers(c *gin.Context) {
-	// Get ride ID from path
-	rideID, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID"})
+	ride, _, ok := authorizeRide(c, models.RideActionViewPassengers)
+	if !ok {
 		return
 	}
 
 	// Get passengers from database
 	rideRepo := repository.NewRideRepository()
-	passengers, err := rideRepo.GetPassengersByRideID(uint(rideID))
+	passengers, err := rideRepo.GetPassengersByRideID(ride.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get passengers"})
 		return
@@ -275,71 +950,135 @@ func GetRidePassengers(c *gin.Context) {
 
 // RateRideRequest represents the request body for rating a ride
 type RateRideRequest struct {
-	Rating  int    `json:"rating" binding:"required,min=1,max=5"`
-	Comment string `json:"comment"`
+	Rating   int      `json:"rating" binding:"required,min=1,max=5"`
+	Comment  string   `json:"comment" binding:"max=1000"`
+	Tags     []string `json:"tags" binding:"max=10"` // Predefined feedback tags for the rated user's role
+	ToUserID *uint    `json:"to_user_id"`            // Required when there is more than one person to rate, e.g. passengers of a shared ride
 }
 
 // RateRide handles rating a ride
 func RateRide(c *gin.Context) {
-	// Get ride ID from path
-	rideID, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID"})
+	// Get request body
+	var req RateRideRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Get user ID from context (set by auth middleware)
-	userID, exists := c.Get("userID")
-	if !exists {
-		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+	// Get ride with its participants
+	ride, actor, ok := authorizeRide(c, models.RideActionView)
+	if !ok {
 		return
 	}
+	userID := actor.UserID
+	rideRepo := repository.NewRideRepository()
 
-	// Get request body
-	var req RateRideRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	// Only completed rides can be rated, and only for a limited time
+	if ride.Status != models.RideStatusCompleted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only completed rides can be rated"})
+		return
+	}
+	completedAt := ride.UpdatedAt
+	if ride.CompletedAt != nil {
+		completedAt = *ride.CompletedAt
+	}
+	if time.Since(completedAt) > models.RatingPeriod {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The rating period for this ride has ended"})
 		return
 	}
 
-	// Get ride to find the rider
-	rideRepo := repository.NewRideRepository()
-	ride, err := rideRepo.GetRideByID(uint(rideID))
+	// Work out who is being rated from the user's participation in the ride
+	targets := ride.RatingTargets(userID)
+	if len(targets) == 0 {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You did not take part in this ride"})
+		return
+	}
+
+	var toUserID uint
+	if req.ToUserID != nil {
+		toUserID = *req.ToUserID
+		if _, ok := targets[toUserID]; !ok {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot rate this user for this ride"})
+			return
+		}
+	} else if len(targets) == 1 {
+		for id := range targets {
+			toUserID = id
+		}
+	} else {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to_user_id is required for rides with several participants"})
+		return
+	}
+
+	// Each user can rate each other participant once per ride
+	rated, err := rideRepo.HasRated(ride.ID, userID, toUserID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Ride not found"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add rating"})
+		return
+	}
+	if rated {
+		c.JSON(http.StatusConflict, gin.H{"error": "You have already rated this user for this ride"})
 		return
 	}
 
 	// Create rating
 	rating := &models.Rating{
-		RideID:     uint(rideID),
-		FromUserID: userID.(uint),
-		ToUserID:   ride.RiderID,
-		Rating:     req.Rating,
-		Comment:    req.Comment,
+		RideID:           ride.ID,
+		FromUserID:       userID,
+		ToUserID:         toUserID,
+		ToUserRole:       targets[toUserID],
+		Rating:           req.Rating,
+		Comment:          req.Comment,
+		ModerationStatus: models.ModerationApproved,
+	}
+
+	// Tags must be ones offered for the rated user's role
+	seen := make(map[models.FeedbackTag]bool)
+	for _, t := range req.Tags {
+		tag := models.FeedbackTag(t)
+		if !models.IsValidFeedbackTag(rating.ToUserRole, tag) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tag for a " + string(rating.ToUserRole) + ": " + t})
+			return
+		}
+		if !seen[tag] {
+			seen[tag] = true
+			rating.Tags = append(rating.Tags, models.RatingTag{Tag: tag})
+		}
+	}
+
+	// Hold comments with abusive language or personal information for review
+	if reasons := utils.ModerateComment(req.Comment); len(reasons) > 0 {
+		rating.ModerationStatus = models.ModerationPendingReview
+		rating.ModerationReason = strings.Join(reasons, ",")
 	}
 
 	// Save rating to database
 	if err := rideRepo.AddRating(rating); err != nil {
+		if errors.Is(err, models.ErrAlreadyRated) {
+			c.JSON(http.StatusConflict, gin.H{"error": "You have already rated this user for this ride"})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add rating"})
 		return
 	}
 
+	if rating.ModerationStatus == models.ModerationPendingReview {
+		c.JSON(http.StatusOK, gin.H{"message": "Rating added successfully; the comment will be shown once it has been reviewed"})
+		return
+	}
 	c.JSON(http.StatusOK, gin.H{"message": "Rating added successfully"})
 }
 
 // GetRideRatings handles retrieving all ratings for a ride
 func GetRideRatings(c *gin.Context) {
-	// Get ride ID from path
-	rideID, err := strconv.ParseUint(c.Param("id"), 10, 32)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid ride ID"})
+	ride, _, ok := authorizeRide(c, models.RideActionView)
+	if !ok {
 		return
 	}
 
 	// Get ratings from database
 	rideRepo := repository.NewRideRepository()
-	ratings, err := rideRepo.GetRatingsByRideID(uint(rideID))
+	ratings, err := rideRepo.GetRatingsByRideID(ride.ID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ratings"})
 		return
@@ -347,3 +1086,13 @@ func GetRideRatings(c *gin.Context) {
 
 	c.JSON(http.StatusOK, ratings)
 }
+
+// GetRatingTags handles listing the feedback tags that can be given to riders and drivers
+func GetRatingTags(c *gin.Context) {
+	c.JSON(http.StatusOK, models.FeedbackTagsByRole)
+}
+
+// GetRideTiers handles listing the on-demand ride tiers with their rate cards and vehicle requirements
+func GetRideTiers(c *gin.Context) {
+	c.JSON(http.StatusOK, models.RideTiers)
+}
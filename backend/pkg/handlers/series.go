@@ -0,0 +1,509 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/services"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
+)
+
+// CreateRideSeriesRequest represents the request body for offering a recurring shared ride
+type CreateRideSeriesRequest struct {
+	PickupLat        float64  `json:"pickup_lat" binding:"required"`
+	PickupLng        float64  `json:"pickup_lng" binding:"required"`
+	DropoffLat       float64  `json:"dropoff_lat" binding:"required"`
+	DropoffLng       float64  `json:"dropoff_lng" binding:"required"`
+	PickupAddress    string   `json:"pickup_address" binding:"required"`
+	DropoffAddress   string   `json:"dropoff_address" binding:"required"`
+	Price            float64  `json:"price" binding:"required"`
+	Distance         float64  `json:"distance" binding:"required"`
+	Duration         int      `json:"duration" binding:"required"`
+	SeatsAvailable   int      `json:"seats_available" binding:"required,min=1"`
+	PaymentMethod    string   `json:"payment_method" binding:"required,oneof=cash card wallet"`
+	OrganizationID   *uint    `json:"organization_id"`
+	RoutePolyline    string   `json:"route_polyline"`
+	MaxDetourMinutes *int     `json:"max_detour_minutes" binding:"omitempty,min=0,max=30"`
+	BookingMode      string   `json:"booking_mode" binding:"omitempty,oneof=instant manual"`
+	DaysOfWeek       []string `json:"days_of_week" binding:"required,min=1,dive,oneof=mon tue wed thu fri sat sun"`
+	DepartureTime    string   `json:"departure_time" binding:"required"` // Time of day, e.g. "08:15"
+	TimeZone         string   `json:"time_zone"`                         // IANA name, defaults to UTC
+	StartDate        string   `json:"start_date" binding:"required"`     // e.g. "2024-09-02"
+	EndDate          string   `json:"end_date"`                          // Open-ended when empty
+	SkipDates        []string `json:"skip_dates"`                        // Dates the ride does not run, such as holidays
+}
+
+// getOwnSeries loads the series in the path, responding with an error unless it belongs to the current user
+func getOwnSeries(c *gin.Context, seriesRepo *repository.SeriesRepository) (*models.RideSeries, bool) {
+	// Get series ID from path
+	seriesID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return nil, false
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	series, err := seriesRepo.GetSeriesByID(uint(seriesID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride series not found"})
+		return nil, false
+	}
+	if series.OwnerID != userID.(uint) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the owner can change this ride series"})
+		return nil, false
+	}
+	return series, true
+}
+
+// CreateRideSeries handles offering a shared ride that repeats on a schedule. Its rides are created ahead of
+// time and can be joined like any shared ride.
+func CreateRideSeries(c *gin.Context) {
+	var req CreateRideSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Validate the recurrence rule
+	if _, err := time.Parse(models.ClockLayout, req.DepartureTime); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "departure_time must be a time of day such as 08:15"})
+		return
+	}
+	if req.TimeZone == "" {
+		req.TimeZone = "UTC"
+	}
+	if _, err := time.LoadLocation(req.TimeZone); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown time zone"})
+		return
+	}
+	startDate, err := time.Parse(models.DateLayout, req.StartDate)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "start_date must be a date such as 2024-09-02"})
+		return
+	}
+	var endDate *time.Time
+	if req.EndDate != "" {
+		date, err := time.Parse(models.DateLayout, req.EndDate)
+		if err != nil || date.Before(startDate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "end_date must be a date on or after start_date"})
+			return
+		}
+		endDate = &date
+	}
+	var skipDates []models.RideSeriesSkip
+	seen := make(map[string]bool)
+	for _, d := range req.SkipDates {
+		date, err := time.Parse(models.DateLayout, d)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid skip date: " + d})
+			return
+		}
+		if !seen[d] {
+			seen[d] = true
+			skipDates = append(skipDates, models.RideSeriesSkip{Date: date})
+		}
+	}
+
+	route, err := plannedRoute(req.RoutePolyline, req.PickupLat, req.PickupLng, req.DropoffLat, req.DropoffLng)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Only approved drivers with valid documents can offer rides
+	driverRepo := repository.NewDriverRepository()
+	if err := driverRepo.CheckDriverEligibility(userID.(uint)); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You cannot offer rides: " + err.Error()})
+		return
+	}
+
+	// Rides are driven with the owner's active vehicle, which limits the seats on offer
+	vehicleRepo := repository.NewVehicleRepository()
+	vehicle, err := vehicleRepo.GetActiveVehicle(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Add a vehicle before offering a shared ride"})
+		return
+	}
+	if req.SeatsAvailable > vehicle.SeatCapacity {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Your vehicle only has %d passenger seats", vehicle.SeatCapacity)})
+		return
+	}
+
+	// Only verified members can offer rides scoped to an organization
+	if req.OrganizationID != nil {
+		orgRepo := repository.NewOrganizationRepository()
+		isMember, err := orgRepo.IsVerifiedMember(*req.OrganizationID, userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check organization membership"})
+			return
+		}
+		if !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a verified member of this organization"})
+			return
+		}
+	}
+
+	series := &models.RideSeries{
+		OwnerID:          userID.(uint),
+		PickupLat:        req.PickupLat,
+		PickupLng:        req.PickupLng,
+		DropoffLat:       req.DropoffLat,
+		DropoffLng:       req.DropoffLng,
+		PickupAddress:    req.PickupAddress,
+		DropoffAddress:   req.DropoffAddress,
+		Price:            req.Price,
+		Distance:         req.Distance,
+		Duration:         req.Duration,
+		SeatsAvailable:   req.SeatsAvailable,
+		PaymentMethod:    models.PaymentMethod(req.PaymentMethod),
+		OrganizationID:   req.OrganizationID,
+		RoutePolyline:    utils.EncodePolyline(route),
+		MaxDetourMinutes: models.DefaultMaxDetourMinutes,
+		BookingMode:      models.BookingModeInstant,
+		DaysOfWeek:       strings.Join(req.DaysOfWeek, ","),
+		DepartureTime:    req.DepartureTime,
+		TimeZone:         req.TimeZone,
+		StartDate:        startDate,
+		EndDate:          endDate,
+		IsActive:         true,
+		SkipDates:        skipDates,
+	}
+	series.RouteMinLat, series.RouteMaxLat, series.RouteMinLng, series.RouteMaxLng = utils.Bounds(route)
+	if req.MaxDetourMinutes != nil {
+		series.MaxDetourMinutes = *req.MaxDetourMinutes
+	}
+	if req.BookingMode != "" {
+		series.BookingMode = models.BookingMode(req.BookingMode)
+	}
+
+	seriesRepo := repository.NewSeriesRepository()
+	if err := seriesRepo.CreateSeries(series); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create ride series"})
+		return
+	}
+
+	// Create the first rides straight away; the background job keeps adding rides as time goes on
+	if err := services.MaterializeSeries(series); err != nil {
+		log.Printf("Failed to create rides for series %d: %v", series.ID, err)
+	}
+	rides, err := seriesRepo.GetUpcomingRides(series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rides of the series"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Ride series created successfully",
+		"series":  series,
+		"rides":   rides,
+	})
+}
+
+// GetMyRideSeries handles listing the series the current user offers and the series they are subscribed to
+func GetMyRideSeries(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	seriesRepo := repository.NewSeriesRepository()
+	owned, err := seriesRepo.GetSeriesByOwnerID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get ride series"})
+		return
+	}
+	subscriptions, err := seriesRepo.GetSubscriptionsByUserID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get subscriptions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"owned":         owned,
+		"subscriptions": subscriptions,
+	})
+}
+
+// GetRideSeries handles retrieving a ride series with its upcoming rides
+func GetRideSeries(c *gin.Context) {
+	// Get series ID from path
+	seriesID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	seriesRepo := repository.NewSeriesRepository()
+	series, err := seriesRepo.GetSeriesByID(uint(seriesID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride series not found"})
+		return
+	}
+
+	// Organization series are only shown to verified members; others are reported as missing
+	if series.OrganizationID != nil && series.OwnerID != userID.(uint) {
+		orgRepo := repository.NewOrganizationRepository()
+		isMember, err := orgRepo.IsVerifiedMember(*series.OrganizationID, userID.(uint))
+		if err != nil || !isMember {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Ride series not found"})
+			return
+		}
+	}
+
+	rides, err := seriesRepo.GetUpcomingRides(series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rides of the series"})
+		return
+	}
+
+	response := gin.H{
+		"series": series,
+		"rides":  rides,
+	}
+	if subscription, err := seriesRepo.GetSubscription(series.ID, userID.(uint)); err == nil {
+		response["subscription"] = subscription
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// EndRideSeries handles the owner ending a series: no more rides are created and upcoming rides are cancelled
+func EndRideSeries(c *gin.Context) {
+	seriesRepo := repository.NewSeriesRepository()
+	series, ok := getOwnSeries(c, seriesRepo)
+	if !ok {
+		return
+	}
+	if !series.IsActive {
+		c.JSON(http.StatusConflict, gin.H{"error": "Ride series has already ended"})
+		return
+	}
+
+	cancelled, err := seriesRepo.EndSeries(series)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to end ride series"})
+		return
+	}
+	for i := range cancelled {
+		services.NotifyRideCancelled(&cancelled[i])
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":         "Ride series ended",
+		"cancelled_rides": len(cancelled),
+	})
+}
+
+// CancelSeriesOccurrence handles the owner cancelling the ride of one date without changing the rest of the
+// series. The date is skipped whether or not its ride has been created yet.
+func CancelSeriesOccurrence(c *gin.Context) {
+	seriesRepo := repository.NewSeriesRepository()
+	series, ok := getOwnSeries(c, seriesRepo)
+	if !ok {
+		return
+	}
+
+	date, err := time.Parse(models.DateLayout, c.Param("date"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid date"})
+		return
+	}
+
+	ride, err := seriesRepo.GetOccurrence(series.ID, date)
+	if err == nil && ride.Status != models.RideStatusPending && ride.Status != models.RideStatusCancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "This ride has already started"})
+		return
+	}
+
+	if err := seriesRepo.SkipDate(series.ID, date); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel ride"})
+		return
+	}
+
+	if ride != nil && ride.Status == models.RideStatusPending {
+		if err := services.CancelRide(ride, []models.RideStatus{models.RideStatusPending}, series.OwnerID); err != nil {
+			if errors.Is(err, models.ErrRideStatusTransition) {
+				c.JSON(http.StatusConflict, gin.H{"error": "This ride has already started"})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel ride"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Ride on " + date.Format(models.DateLayout) + " cancelled"})
+}
+
+// SubscribeRideSeriesRequest represents the request body for subscribing to a ride series
+type SubscribeRideSeriesRequest struct {
+	Seats int `json:"seats" binding:"required,min=1"`
+}
+
+// SubscribeToRideSeries handles booking the current user on every ride of a series, including its upcoming
+// rides. On manually approved series each ride sends the owner a join request.
+func SubscribeToRideSeries(c *gin.Context) {
+	// Get series ID from path
+	seriesID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SubscribeRideSeriesRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	seriesRepo := repository.NewSeriesRepository()
+	series, err := seriesRepo.GetSeriesByID(uint(seriesID))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Ride series not found"})
+		return
+	}
+	if !series.IsActive {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "This ride series has ended"})
+		return
+	}
+	if series.OwnerID == userID.(uint) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot subscribe to your own ride series"})
+		return
+	}
+	if req.Seats > series.SeatsAvailable {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("This ride only offers %d seats", series.SeatsAvailable)})
+		return
+	}
+
+	// Organization series can only be joined by verified members
+	if series.OrganizationID != nil {
+		orgRepo := repository.NewOrganizationRepository()
+		isMember, err := orgRepo.IsVerifiedMember(*series.OrganizationID, userID.(uint))
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check organization membership"})
+			return
+		}
+		if !isMember {
+			c.JSON(http.StatusForbidden, gin.H{"error": "This ride is only open to members of its organization"})
+			return
+		}
+	}
+
+	if _, err := seriesRepo.GetSubscription(series.ID, userID.(uint)); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "You are already subscribed to this ride series"})
+		return
+	}
+
+	subscription := &models.RideSeriesSubscription{
+		SeriesID: series.ID,
+		UserID:   userID.(uint),
+		Seats:    req.Seats,
+	}
+	if err := seriesRepo.Subscribe(subscription); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to subscribe to ride series"})
+		return
+	}
+
+	// Book the rides already created, except those the user joined on their own
+	rides, err := seriesRepo.GetUpcomingRides(series.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to book rides of the series"})
+		return
+	}
+	rideRepo := repository.NewRideRepository()
+	for i := range rides {
+		passengers, err := rideRepo.GetPassengersByRideID(rides[i].ID)
+		if err != nil {
+			log.Printf("Failed to get passengers of ride %d: %v", rides[i].ID, err)
+			continue
+		}
+		joined := false
+		for _, p := range passengers {
+			if p.UserID == userID.(uint) && p.Status.HoldsSeats() {
+				joined = true
+				break
+			}
+		}
+		if !joined {
+			services.BookSubscriber(&rides[i], subscription)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message":      "Subscribed to ride series",
+		"subscription": subscription,
+	})
+}
+
+// UnsubscribeFromRideSeries handles the current user ending their subscription to a series. They leave the
+// upcoming rides the subscription booked; rides joined on their own are kept.
+func UnsubscribeFromRideSeries(c *gin.Context) {
+	// Get series ID from path
+	seriesID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid series ID"})
+		return
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	seriesRepo := repository.NewSeriesRepository()
+	subscription, err := seriesRepo.GetSubscription(uint(seriesID), userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not subscribed to this ride series"})
+		return
+	}
+
+	rideIDs, err := seriesRepo.Unsubscribe(subscription)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsubscribe from ride series"})
+		return
+	}
+
+	// The freed seats go to the next passengers on the waitlists
+	for _, rideID := range rideIDs {
+		services.PromoteWaitlist(rideID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unsubscribed from ride series"})
+}
@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// GetRideItinerary handles retrieving the stops of a shared ride, or of a pooled ride's pool, in order, for
+// its driver
+func GetRideItinerary(c *gin.Context) {
+	ride, _, ok := authorizeRide(c, models.RideActionDrive)
+	if !ok {
+		return
+	}
+
+	stopRepo := repository.NewStopRepository()
+	if ride.PoolID != nil {
+		// The driver starts at the pool's first pickup
+		stops, err := stopRepo.GetPoolStops(*ride.PoolID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get itinerary"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"ride_id": ride.ID,
+			"pool_id": *ride.PoolID,
+			"stops":   stops,
+		})
+		return
+	}
+	if ride.RideType != models.RideTypeShared {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only shared and pooled rides have an itinerary"})
+		return
+	}
+
+	stops, err := stopRepo.GetStops(ride.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get itinerary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"ride_id": ride.ID,
+		"stops":   ride.Itinerary(stops),
+	})
+}
+
+// CheckInStop handles the driver confirming that a passenger got in or out at a stop. Pooled rides start
+// when their rider is picked up and complete when they are dropped off.
+func CheckInStop(c *gin.Context) {
+	// Get stop ID from path
+	stopID, err := strconv.ParseUint(c.Param("stopId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid stop ID"})
+		return
+	}
+
+	ride, actor, ok := authorizeRide(c, models.RideActionDrive)
+	if !ok {
+		return
+	}
+	pooled := ride.PoolID != nil
+	if pooled && ride.Status != models.RideStatusAccepted && ride.Status != models.RideStatusStarted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stops can only be checked in once the ride has been accepted"})
+		return
+	}
+	if !pooled && ride.Status != models.RideStatusStarted {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Stops can only be checked in once the ride has started"})
+		return
+	}
+
+	stopRepo := repository.NewStopRepository()
+	stop, err := stopRepo.GetStopByID(uint(stopID))
+	if err != nil || stop.RideID != ride.ID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Stop not found"})
+		return
+	}
+
+	if err := stopRepo.CheckIn(stop); err != nil {
+		if errors.Is(err, models.ErrStopCheckedIn) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Stop already checked in"})
+			return
+		}
+		if errors.Is(err, models.ErrPickupNotCheckedIn) {
+			c.JSON(http.StatusConflict, gin.H{"error": "The passenger has not been picked up yet"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check in stop"})
+		return
+	}
+
+	if pooled {
+		from, status := models.RideStatusAccepted, models.RideStatusStarted
+		if stop.Kind == models.StopDropoff {
+			from, status = models.RideStatusStarted, models.RideStatusCompleted
+		}
+		rideRepo := repository.NewRideRepository()
+		if err := rideRepo.UpdateRideStatus(ride.ID, []models.RideStatus{from}, status, actor.UserID); err != nil {
+			if errors.Is(err, models.ErrRideStatusTransition) {
+				c.JSON(http.StatusConflict, gin.H{"error": "The ride is no longer " + string(from)})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update ride status"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Stop checked in",
+		"stop":    stop,
+	})
+}
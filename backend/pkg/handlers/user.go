@@ -7,6 +7,8 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/rakeshkumar/ridesapp/pkg/models"
 	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/services"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
 )
 
 type UserHandler struct {
@@ -96,6 +98,7 @@ func (h *UserHandler) UpdateUser(c *gin.Context) {
 	// If password is provided, update it
 	if updateData.Password != "" {
 		existingUser.Password = updateData.Password
+		existingUser.RandomPassword = false
 		if err := existingUser.HashPassword(); err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process password"})
 			return
@@ -122,7 +125,7 @@ func (h *UserHandler) DeleteUser(c *gin.Context) {
 		return
 	}
 
-	if err := h.userRepo.DeleteUser(uint(userID)); err != nil {
+	if err := services.DeleteAccount(uint(userID)); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
@@ -168,16 +171,12 @@ func GetCurrentUser(c *gin.Context) {
 
 // UpdateCurrentUserRequest represents the request body for updating a user
 type UpdateCurrentUserRequest struct {
-	FirstName      string `json:"first_name"`
-	LastName       string `json:"last_name"`
-	Phone          string `json:"phone"`
-	ProfilePicture string `json:"profile_picture"`
-
-	// For drivers
-	LicenseNumber string `json:"license_number,omitempty"`
-	VehicleModel  string `json:"vehicle_model,omitempty"`
-	VehicleColor  string `json:"vehicle_color,omitempty"`
-	VehiclePlate  string `json:"vehicle_plate,omitempty"`
+	FirstName      string              `json:"first_name"`
+	LastName       string              `json:"last_name"`
+	Phone          string              `json:"phone"`
+	ProfilePicture string              `json:"profile_picture"`
+	Gender         string              `json:"gender" binding:"omitempty,oneof=female male other"`
+	Preferences    *models.Preferences `json:"preferences"` // Replaces all preferences when given
 }
 
 // UpdateCurrentUser handles updating the current user's profile
@@ -217,21 +216,20 @@ func UpdateCurrentUser(c *gin.Context) {
 	if req.ProfilePicture != "" {
 		user.ProfilePicture = req.ProfilePicture
 	}
-
-	// Update driver-specific fields if user is a driver
-	if user.Role == models.RoleDriver {
-		if req.LicenseNumber != "" {
-			user.LicenseNumber = req.LicenseNumber
-		}
-		if req.VehicleModel != "" {
-			user.VehicleModel = req.VehicleModel
-		}
-		if req.VehicleColor != "" {
-			user.VehicleColor = req.VehicleColor
-		}
-		if req.VehiclePlate != "" {
-			user.VehiclePlate = req.VehiclePlate
+	// Gender decides who can take women-only rides, so once stated only staff can change it
+	if req.Gender != "" && models.Gender(req.Gender) != user.Gender {
+		if user.Gender != "" {
+			c.JSON(http.StatusConflict, gin.H{"error": "Gender cannot be changed once set; contact support to correct it"})
+			return
 		}
+		user.Gender = models.Gender(req.Gender)
+	}
+	if req.Preferences != nil {
+		user.Preferences = *req.Preferences
+	}
+	if err := user.Preferences.Validate(user.Gender); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
 
 	// Save user to database
@@ -245,3 +243,94 @@ func UpdateCurrentUser(c *gin.Context) {
 		"user":    user,
 	})
 }
+
+// SwitchModeRequest represents the request body for switching between rider and driver mode
+type SwitchModeRequest struct {
+	Mode string `json:"mode" binding:"required,oneof=rider driver"`
+}
+
+// SwitchMode handles switching the current user's active mode between rider and driver
+func SwitchMode(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req SwitchModeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	user, err := userRepo.GetUserByID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	// Staff roles are not modes and cannot be switched away from
+	if user.Role != models.RoleRider && user.Role != models.RoleDriver {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Staff accounts cannot switch mode"})
+		return
+	}
+
+	mode := models.UserRole(req.Mode)
+	if mode == models.RoleDriver && !user.CanDrive {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Complete driver onboarding before switching to driver mode"})
+		return
+	}
+
+	if err := userRepo.UpdateUserRole(user.ID, mode); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to switch mode"})
+		return
+	}
+	user.Role = mode
+
+	// The role is carried in the token, so issue a new one for the active mode
+	token, err := utils.GenerateToken(user)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Switched to " + req.Mode + " mode",
+		"mode":    mode,
+		"token":   token,
+	})
+}
+
+// GetUserRatingSummary handles retrieving a user's rating averages and star distribution
+func GetUserRatingSummary(c *gin.Context) {
+	// Get user ID from path
+	userID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
+	// Optional rolling window in days
+	windowDays, err := strconv.Atoi(c.DefaultQuery("window_days", "0"))
+	if err != nil || windowDays < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid window_days"})
+		return
+	}
+
+	userRepo := repository.NewUserRepository()
+	if _, err := userRepo.GetUserByID(uint(userID)); err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+
+	ratingRepo := repository.NewRatingRepository()
+	summary, err := ratingRepo.GetRatingSummary(uint(userID), windowDays)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get rating summary"})
+		return
+	}
+
+	c.JSON(http.StatusOK, summary)
+}
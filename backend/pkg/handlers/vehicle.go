@@ -0,0 +1,213 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// VehicleRequest represents the request body for registering or updating a vehicle
+type VehicleRequest struct {
+	Make                  string   `json:"make" binding:"required"`
+	Model                 string   `json:"model" binding:"required"`
+	Year                  int      `json:"year" binding:"required,min=1950"`
+	Color                 string   `json:"color" binding:"required"`
+	Plate                 string   `json:"plate" binding:"required"`
+	SeatCapacity          int      `json:"seat_capacity" binding:"required,min=1,max=14"` // Passenger seats, not counting the driver
+	Class                 string   `json:"class" binding:"required,oneof=compact sedan suv minivan luxury"`
+	AccessibilityFeatures []string `json:"accessibility_features"`
+}
+
+// vehicleFeatures validates the requested accessibility features, dropping duplicates
+func vehicleFeatures(requested []string) ([]models.VehicleFeature, error) {
+	var features []models.VehicleFeature
+	seen := make(map[models.AccessibilityFeature]bool)
+	for _, f := range requested {
+		feature := models.AccessibilityFeature(f)
+		if !models.IsValidAccessibilityFeature(feature) {
+			return nil, errors.New("invalid accessibility feature: " + f)
+		}
+		if !seen[feature] {
+			seen[feature] = true
+			features = append(features, models.VehicleFeature{Feature: feature})
+		}
+	}
+	return features, nil
+}
+
+// getOwnVehicle loads the vehicle in the path, responding with an error unless it belongs to the current user
+func getOwnVehicle(c *gin.Context, vehicleRepo *repository.VehicleRepository) (*models.Vehicle, bool) {
+	// Get vehicle ID from path
+	vehicleID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid vehicle ID"})
+		return nil, false
+	}
+
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return nil, false
+	}
+
+	// Other drivers' vehicles are reported as missing
+	vehicle, err := vehicleRepo.GetVehicleByID(uint(vehicleID))
+	if err != nil || vehicle.DriverID != userID.(uint) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Vehicle not found"})
+		return nil, false
+	}
+	return vehicle, true
+}
+
+// GetMyVehicles handles listing the current user's vehicles
+func GetMyVehicles(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	vehicleRepo := repository.NewVehicleRepository()
+	vehicles, err := vehicleRepo.GetVehiclesByDriverID(userID.(uint))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get vehicles"})
+		return
+	}
+
+	c.JSON(http.StatusOK, vehicles)
+}
+
+// AddVehicle handles registering a vehicle for the current user
+func AddVehicle(c *gin.Context) {
+	// Get user ID from context (set by auth middleware)
+	userID, exists := c.Get("userID")
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req VehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	features, err := vehicleFeatures(req.AccessibilityFeatures)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vehicle := &models.Vehicle{
+		DriverID:     userID.(uint),
+		Make:         req.Make,
+		Model:        req.Model,
+		Year:         req.Year,
+		Color:        req.Color,
+		Plate:        req.Plate,
+		SeatCapacity: req.SeatCapacity,
+		Class:        models.VehicleClass(req.Class),
+		Features:     features,
+	}
+
+	vehicleRepo := repository.NewVehicleRepository()
+	if err := vehicleRepo.CreateVehicle(vehicle); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Vehicle added successfully",
+		"vehicle": vehicle,
+	})
+}
+
+// UpdateVehicle handles updating one of the current user's vehicles
+func UpdateVehicle(c *gin.Context) {
+	vehicleRepo := repository.NewVehicleRepository()
+	vehicle, ok := getOwnVehicle(c, vehicleRepo)
+	if !ok {
+		return
+	}
+
+	var req VehicleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	features, err := vehicleFeatures(req.AccessibilityFeatures)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	vehicle.Make = req.Make
+	vehicle.Model = req.Model
+	vehicle.Year = req.Year
+	vehicle.Color = req.Color
+	vehicle.Plate = req.Plate
+	vehicle.SeatCapacity = req.SeatCapacity
+	vehicle.Class = models.VehicleClass(req.Class)
+	vehicle.Features = features
+
+	if err := vehicleRepo.UpdateVehicle(vehicle); err != nil {
+		if errors.Is(err, models.ErrVehicleOverbooked) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Open shared rides offer more seats than this capacity"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vehicle updated successfully",
+		"vehicle": vehicle,
+	})
+}
+
+// ActivateVehicle handles switching the vehicle the current user is driving
+func ActivateVehicle(c *gin.Context) {
+	vehicleRepo := repository.NewVehicleRepository()
+	vehicle, ok := getOwnVehicle(c, vehicleRepo)
+	if !ok {
+		return
+	}
+
+	if err := vehicleRepo.SetActiveVehicle(vehicle.DriverID, vehicle.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to activate vehicle"})
+		return
+	}
+	vehicle.IsActive = true
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vehicle activated successfully",
+		"vehicle": vehicle,
+	})
+}
+
+// RemoveVehicle handles removing one of the current user's vehicles
+func RemoveVehicle(c *gin.Context) {
+	vehicleRepo := repository.NewVehicleRepository()
+	vehicle, ok := getOwnVehicle(c, vehicleRepo)
+	if !ok {
+		return
+	}
+
+	if err := vehicleRepo.DeleteVehicle(vehicle); err != nil {
+		if errors.Is(err, models.ErrVehicleInUse) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Vehicle is assigned to open rides"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove vehicle"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Vehicle removed successfully"})
+}
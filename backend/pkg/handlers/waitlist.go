@@ -0,0 +1,107 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/services"
+)
+
+// GetRideWaitlist handles retrieving a ride's waitlist. The ride owner sees every entry, others only their own.
+func GetRideWaitlist(c *gin.Context) {
+	ride, actor, ok := authorizeRide(c, models.RideActionView)
+	if !ok {
+		return
+	}
+
+	waitlistRepo := repository.NewWaitlistRepository()
+	if models.AuthorizeRide(ride, actor, models.RideActionManagePassengers) == nil {
+		entries, err := waitlistRepo.GetWaitlist(ride.ID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get waitlist"})
+			return
+		}
+		c.JSON(http.StatusOK, entries)
+		return
+	}
+
+	entry, err := waitlistRepo.GetOpenEntry(ride.ID, actor.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not on the waitlist for this ride"})
+		return
+	}
+	c.JSON(http.StatusOK, entry)
+}
+
+// ConfirmWaitlistOffer handles a waitlisted passenger taking the seats offered to them
+func ConfirmWaitlistOffer(c *gin.Context) {
+	ride, actor, ok := authorizeRide(c, models.RideActionTakeUp)
+	if !ok {
+		return
+	}
+
+	waitlistRepo := repository.NewWaitlistRepository()
+	entry, err := waitlistRepo.GetOpenEntry(ride.ID, actor.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not on the waitlist for this ride"})
+		return
+	}
+
+	passenger := ride.NewPassenger(actor.UserID, entry.Seats, services.JoinRequestExpiry())
+	passenger.PickupLat, passenger.PickupLng, passenger.PickupAddress = entry.PickupLat, entry.PickupLng, entry.PickupAddress
+	passenger.DropoffLat, passenger.DropoffLng, passenger.DropoffAddress = entry.DropoffLat, entry.DropoffLng, entry.DropoffAddress
+	if err := waitlistRepo.ConfirmOffer(entry, passenger); err != nil {
+		if errors.Is(err, models.ErrNoWaitlistOffer) {
+			c.JSON(http.StatusConflict, gin.H{"error": "No seats are on offer to you for this ride"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to confirm seats"})
+		return
+	}
+
+	// Let the owner know, and ask for approval on manually approved rides
+	if passenger.Status == models.RideStatusPending {
+		services.Notify(ride.RiderID, models.NotificationJoinRequested, &ride.ID,
+			fmt.Sprintf("A waitlisted passenger requested %d seat(s) on your ride to %s", passenger.Seats, ride.DropoffAddress))
+	} else {
+		services.Notify(ride.RiderID, models.NotificationPassengerJoined, &ride.ID,
+			fmt.Sprintf("A waitlisted passenger booked %d seat(s) on your ride to %s", passenger.Seats, ride.DropoffAddress))
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":   "Seats confirmed",
+		"passenger": passenger,
+	})
+}
+
+// LeaveWaitlist handles a passenger leaving a ride's waitlist, giving up any seats on offer to them
+func LeaveWaitlist(c *gin.Context) {
+	ride, actor, ok := authorizeRide(c, models.RideActionView)
+	if !ok {
+		return
+	}
+
+	waitlistRepo := repository.NewWaitlistRepository()
+	entry, err := waitlistRepo.GetOpenEntry(ride.ID, actor.UserID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "You are not on the waitlist for this ride"})
+		return
+	}
+
+	released, err := waitlistRepo.LeaveWaitlist(entry)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave waitlist"})
+		return
+	}
+
+	// Seats given up go to the next passengers in line
+	if released {
+		services.PromoteWaitlist(entry.RideID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Left waitlist successfully"})
+}
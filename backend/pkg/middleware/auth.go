@@ -6,10 +6,12 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
 	"github.com/rakeshkumar/ridesapp/pkg/utils"
 )
 
-// AuthMiddleware authenticates requests using JWT
+// AuthMiddleware authenticates requests using JWT. The user is loaded on every request, so suspensions,
+// deletions and role changes apply to tokens issued before them.
 func AuthMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		// Get the Authorization header
@@ -36,9 +38,22 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
-		// Set user ID and role in context
-		c.Set("userID", claims.UserID)
-		c.Set("userRole", claims.Role)
+		userRepo := repository.NewUserRepository()
+		user, err := userRepo.GetUserByID(claims.UserID)
+		if err != nil || user.AnonymizedAt != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+			c.Abort()
+			return
+		}
+		if user.IsSuspended() {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Account is suspended"})
+			c.Abort()
+			return
+		}
+
+		// Set user ID and current role in context
+		c.Set("userID", user.ID)
+		c.Set("userRole", string(user.Role))
 
 		// Continue to the next handler
 		c.Next()
@@ -75,3 +90,28 @@ func RoleMiddleware(roles ...models.UserRole) gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// RequirePermission checks if the user's role grants all of the required permissions
+func RequirePermission(permissions ...models.Permission) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// Get user role from context (set by auth middleware)
+		userRole, exists := c.Get("userRole")
+		if !exists {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "User role not found"})
+			c.Abort()
+			return
+		}
+
+		// Check if the role grants every required permission
+		for _, permission := range permissions {
+			if !models.HasPermission(models.UserRole(userRole.(string)), permission) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+				c.Abort()
+				return
+			}
+		}
+
+		// Continue to the next handler
+		c.Next()
+	}
+}
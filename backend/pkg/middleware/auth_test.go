@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rakeshkumar/ridesapp/pkg/database/databasetest"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
+)
+
+func TestAuthMiddlewareUsesCurrentAccount(t *testing.T) {
+	db := databasetest.Setup(t)
+
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin", AuthMiddleware(), RequirePermission(models.PermissionUsersSuspend), func(c *gin.Context) {
+		c.Status(http.StatusNoContent)
+	})
+	request := func(token string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin", nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	userRepo := repository.NewUserRepository()
+	admin := &models.User{Email: "admin@example.com", Password: "password", Role: models.RoleAdmin}
+	if err := userRepo.CreateUser(admin); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	token, err := utils.GenerateToken(admin)
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	if code := request(token); code != http.StatusNoContent {
+		t.Fatalf("admin: got %d", code)
+	}
+
+	// A demoted admin loses their permissions before their token expires
+	if err := userRepo.UpdateUserRole(admin.ID, models.RoleRider); err != nil {
+		t.Fatalf("UpdateUserRole: %v", err)
+	}
+	if code := request(token); code != http.StatusForbidden {
+		t.Errorf("demoted admin: got %d, want 403", code)
+	}
+
+	// So does a suspended one
+	if err := userRepo.UpdateUserRole(admin.ID, models.RoleAdmin); err != nil {
+		t.Fatalf("UpdateUserRole: %v", err)
+	}
+	if err := userRepo.SetSuspended(admin.ID, true); err != nil {
+		t.Fatalf("SetSuspended: %v", err)
+	}
+	if code := request(token); code != http.StatusForbidden {
+		t.Errorf("suspended admin: got %d, want 403", code)
+	}
+
+	// Tokens of deleted accounts stop working
+	if err := db.Model(&models.User{}).Where("id = ?", admin.ID).
+		Updates(map[string]interface{}{"suspended_at": nil, "anonymized_at": time.Now()}).Error; err != nil {
+		t.Fatalf("failed to anonymize user: %v", err)
+	}
+	if code := request(token); code != http.StatusUnauthorized {
+		t.Errorf("anonymized user: got %d, want 401", code)
+	}
+}
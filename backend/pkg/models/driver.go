@@ -0,0 +1,160 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDriverNotApproved    = errors.New("driver application has not been approved")
+	ErrDriverDocumentsStale = errors.New("driver documents are missing or expired")
+)
+
+// DriverApplicationStatus is the state of a driver's onboarding application
+type DriverApplicationStatus string
+
+const (
+	DriverApplicationDraft     DriverApplicationStatus = "draft"     // Documents are being uploaded
+	DriverApplicationSubmitted DriverApplicationStatus = "submitted" // Waiting for a reviewer
+	DriverApplicationInReview  DriverApplicationStatus = "in_review"
+	DriverApplicationApproved  DriverApplicationStatus = "approved"
+	DriverApplicationRejected  DriverApplicationStatus = "rejected" // Can be corrected and resubmitted
+)
+
+// driverApplicationTransitions lists the statuses each status can move to
+var driverApplicationTransitions = map[DriverApplicationStatus][]DriverApplicationStatus{
+	DriverApplicationDraft:     {DriverApplicationSubmitted},
+	DriverApplicationSubmitted: {DriverApplicationInReview},
+	DriverApplicationInReview:  {DriverApplicationApproved, DriverApplicationRejected},
+	DriverApplicationRejected:  {DriverApplicationSubmitted},
+}
+
+// CanTransitionTo reports whether an application in this status can move to next
+func (s DriverApplicationStatus) CanTransitionTo(next DriverApplicationStatus) bool {
+	for _, allowed := range driverApplicationTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// DocumentType is a kind of document a driver must provide
+type DocumentType string
+
+const (
+	DocumentLicense      DocumentType = "license"
+	DocumentInsurance    DocumentType = "insurance"
+	DocumentRegistration DocumentType = "registration"
+)
+
+// RequiredDriverDocuments are the documents every driver must keep valid
+var RequiredDriverDocuments = []DocumentType{DocumentLicense, DocumentInsurance, DocumentRegistration}
+
+// DocumentStatus is the review state of a single document
+type DocumentStatus string
+
+const (
+	DocumentPending  DocumentStatus = "pending"
+	DocumentApproved DocumentStatus = "approved"
+	DocumentRejected DocumentStatus = "rejected"
+)
+
+// DriverApplication tracks a user's onboarding as a driver
+type DriverApplication struct {
+	ID              uint                    `json:"id" gorm:"primaryKey"`
+	UserID          uint                    `json:"user_id" gorm:"uniqueIndex;not null"`
+	Status          DriverApplicationStatus `json:"status" gorm:"not null"`
+	RejectionReason string                  `json:"rejection_reason,omitempty"`
+	SubmittedAt     *time.Time              `json:"submitted_at"`
+	ReviewedAt      *time.Time              `json:"reviewed_at"`
+	ReviewedByID    *uint                   `json:"reviewed_by_id,omitempty"`
+	CreatedAt       time.Time               `json:"created_at"`
+	UpdatedAt       time.Time               `json:"updated_at"`
+
+	// Relationships
+	User      User             `json:"user" gorm:"foreignKey:UserID"`
+	Documents []DriverDocument `json:"documents" gorm:"foreignKey:ApplicationID"`
+}
+
+// DriverDocument is an uploaded document with its expiry date
+type DriverDocument struct {
+	ID            uint           `json:"id" gorm:"primaryKey"`
+	ApplicationID uint           `json:"application_id" gorm:"not null;index"`
+	Type          DocumentType   `json:"type" gorm:"not null"`
+	Number        string         `json:"number"` // e.g. license or policy number
+	FilePath      string         `json:"-"`      // Location of the uploaded file
+	ExpiresAt     time.Time      `json:"expires_at" gorm:"not null"`
+	Status        DocumentStatus `json:"status" gorm:"not null"`
+	CreatedAt     time.Time      `json:"created_at"`
+	UpdatedAt     time.Time      `json:"updated_at"`
+}
+
+// CurrentDocument returns the latest non-rejected document of the given type
+func (a *DriverApplication) CurrentDocument(docType DocumentType) *DriverDocument {
+	var current *DriverDocument
+	for i := range a.Documents {
+		doc := &a.Documents[i]
+		if doc.Type != docType || doc.Status == DocumentRejected {
+			continue
+		}
+		if current == nil || doc.CreatedAt.After(current.CreatedAt) {
+			current = doc
+		}
+	}
+	return current
+}
+
+// HasRequiredDocuments reports whether every required document has been uploaded and is unexpired
+func (a *DriverApplication) HasRequiredDocuments(now time.Time) bool {
+	for _, docType := range RequiredDriverDocuments {
+		doc := a.CurrentDocument(docType)
+		if doc == nil || !doc.ExpiresAt.After(now) {
+			return false
+		}
+	}
+	return true
+}
+
+// CheckEligibility returns an error unless the driver is approved and holds an approved,
+// unexpired copy of every required document
+func (a *DriverApplication) CheckEligibility(now time.Time) error {
+	if a.Status != DriverApplicationApproved {
+		return ErrDriverNotApproved
+	}
+
+	for _, docType := range RequiredDriverDocuments {
+		valid := false
+		for _, doc := range a.Documents {
+			if doc.Type == docType && doc.Status == DocumentApproved && doc.ExpiresAt.After(now) {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return ErrDriverDocumentsStale
+		}
+	}
+	return nil
+}
+
+// DriverSession is a period during which a driver was online and available for rides
+type DriverSession struct {
+	ID        uint       `json:"id" gorm:"primaryKey"`
+	DriverID  uint       `json:"driver_id" gorm:"not null;index"`
+	StartedAt time.Time  `json:"started_at" gorm:"not null"`
+	EndedAt   *time.Time `json:"ended_at"`             // Nil while the driver is online
+	EndReason string     `json:"end_reason,omitempty"` // Set when the platform took the driver offline
+}
+
+// DriverDestination is where a driver in destination mode is heading, e.g. home at the end of a shift.
+// Only rides that bring the driver closer to it are offered.
+type DriverDestination struct {
+	ID            uint       `json:"id" gorm:"primaryKey"`
+	DriverID      uint       `json:"driver_id" gorm:"not null;index"`
+	Lat           float64    `json:"lat"`
+	Lng           float64    `json:"lng"`
+	Address       string     `json:"address"`
+	ActivatedAt   time.Time  `json:"activated_at" gorm:"not null"`
+	DeactivatedAt *time.Time `json:"deactivated_at"` // Nil while destination mode is on
+}
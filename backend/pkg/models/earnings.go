@@ -0,0 +1,111 @@
+package models
+
+import (
+	"time"
+)
+
+// EarningsPeriod is the length of the buckets in an earnings report
+type EarningsPeriod string
+
+const (
+	EarningsDaily   EarningsPeriod = "day"
+	EarningsWeekly  EarningsPeriod = "week"
+	EarningsMonthly EarningsPeriod = "month"
+)
+
+// Start returns the start of the period containing t in the given time zone. Weeks start on Monday,
+// as with Postgres date_trunc.
+func (p EarningsPeriod) Start(t time.Time, loc *time.Location) time.Time {
+	t = t.In(loc)
+	year, month, day := t.Date()
+	switch p {
+	case EarningsWeekly:
+		return time.Date(year, month, day-(int(t.Weekday())+6)%7, 0, 0, 0, 0, loc)
+	case EarningsMonthly:
+		return time.Date(year, month, 1, 0, 0, 0, 0, loc)
+	default:
+		return time.Date(year, month, day, 0, 0, 0, 0, loc)
+	}
+}
+
+// Next returns the start of the period after the one starting at start
+func (p EarningsPeriod) Next(start time.Time) time.Time {
+	switch p {
+	case EarningsWeekly:
+		return start.AddDate(0, 0, 7)
+	case EarningsMonthly:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// SplitHours divides the time between start and end among the periods it falls in, keyed by period start
+func (p EarningsPeriod) SplitHours(start, end time.Time, loc *time.Location) map[time.Time]float64 {
+	hours := make(map[time.Time]float64)
+	for periodStart := p.Start(start, loc); start.Before(end); periodStart = p.Next(periodStart) {
+		until := p.Next(periodStart)
+		if end.Before(until) {
+			until = end
+		}
+		hours[periodStart] += until.Sub(start).Hours()
+		start = until
+	}
+	return hours
+}
+
+// EarningsBucket aggregates a driver's trips and time online over one period
+type EarningsBucket struct {
+	PeriodStart      time.Time `json:"period_start"`
+	Trips            int64     `json:"trips"`
+	GrossFares       float64   `json:"gross_fares"`
+	Commission       float64   `json:"commission"` // Platform commission on the fares
+	Tips             float64   `json:"tips"`
+	NetEarnings      float64   `json:"net_earnings"` // Fares less commission, plus tips
+	CashFares        float64   `json:"cash_fares"`
+	CashlessFares    float64   `json:"cashless_fares"`
+	OnlineHours      float64   `json:"online_hours"`
+	Accepted         int64     `json:"accepted"`
+	Declined         int64     `json:"declined"`
+	Cancelled        int64     `json:"cancelled"`         // Accepted rides the driver cancelled
+	AcceptanceRate   float64   `json:"acceptance_rate"`   // Accepted out of accepted and declined rides
+	CancellationRate float64   `json:"cancellation_rate"` // Cancelled out of accepted rides
+}
+
+// Add adds the counts and sums of another bucket to this one
+func (b *EarningsBucket) Add(other EarningsBucket) {
+	b.Trips += other.Trips
+	b.GrossFares += other.GrossFares
+	b.Commission += other.Commission
+	b.Tips += other.Tips
+	b.CashFares += other.CashFares
+	b.OnlineHours += other.OnlineHours
+	b.Accepted += other.Accepted
+	b.Declined += other.Declined
+	b.Cancelled += other.Cancelled
+}
+
+// Finish calculates the derived amounts and rates from the counts and sums
+func (b *EarningsBucket) Finish() {
+	b.NetEarnings = b.GrossFares - b.Commission + b.Tips
+	b.CashlessFares = b.GrossFares - b.CashFares
+	b.AcceptanceRate = 0
+	if offered := b.Accepted + b.Declined; offered > 0 {
+		b.AcceptanceRate = float64(b.Accepted) / float64(offered)
+	}
+	b.CancellationRate = 0
+	if b.Accepted > 0 {
+		b.CancellationRate = float64(b.Cancelled) / float64(b.Accepted)
+	}
+}
+
+// EarningsReport is a driver's earnings over a date range, split into periods
+type EarningsReport struct {
+	DriverID uint             `json:"driver_id"`
+	Period   EarningsPeriod   `json:"period"`
+	TimeZone string           `json:"time_zone"` // Periods start at midnight in this zone
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"` // Exclusive
+	Totals   EarningsBucket   `json:"totals"`
+	Buckets  []EarningsBucket `json:"buckets"`
+}
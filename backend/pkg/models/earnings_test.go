@@ -0,0 +1,64 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEarningsPeriodStart(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	// 23:30 UTC on Sunday 2024-03-31 is already Monday 2024-04-01 in Berlin
+	at := time.Date(2024, 3, 31, 23, 30, 0, 0, time.UTC)
+	tests := []struct {
+		period EarningsPeriod
+		loc    *time.Location
+		want   time.Time
+	}{
+		{EarningsDaily, time.UTC, time.Date(2024, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{EarningsDaily, berlin, time.Date(2024, 4, 1, 0, 0, 0, 0, berlin)},
+		{EarningsWeekly, time.UTC, time.Date(2024, 3, 25, 0, 0, 0, 0, time.UTC)},
+		{EarningsWeekly, berlin, time.Date(2024, 4, 1, 0, 0, 0, 0, berlin)},
+		{EarningsMonthly, time.UTC, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{EarningsMonthly, berlin, time.Date(2024, 4, 1, 0, 0, 0, 0, berlin)},
+	}
+	for _, tt := range tests {
+		if got := tt.period.Start(at, tt.loc); !got.Equal(tt.want) {
+			t.Errorf("%s in %s: got %v, want %v", tt.period, tt.loc, got, tt.want)
+		}
+	}
+}
+
+func TestEarningsPeriodSplitHours(t *testing.T) {
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Skipf("time zone data unavailable: %v", err)
+	}
+
+	// A session from 22:00 to 02:00 counts two hours towards each day
+	start := time.Date(2024, 5, 6, 22, 0, 0, 0, berlin)
+	hours := EarningsDaily.SplitHours(start, start.Add(4*time.Hour), berlin)
+	if len(hours) != 2 {
+		t.Fatalf("got %d buckets, want 2: %v", len(hours), hours)
+	}
+	if got := hours[time.Date(2024, 5, 6, 0, 0, 0, 0, berlin)]; got != 2 {
+		t.Errorf("first day: got %v hours, want 2", got)
+	}
+	if got := hours[time.Date(2024, 5, 7, 0, 0, 0, 0, berlin)]; got != 2 {
+		t.Errorf("second day: got %v hours, want 2", got)
+	}
+
+	// The night clocks go forward has one hour fewer
+	start = time.Date(2024, 3, 30, 12, 0, 0, 0, berlin)
+	hours = EarningsDaily.SplitHours(start, start.Add(48*time.Hour), berlin)
+	if got := hours[time.Date(2024, 3, 31, 0, 0, 0, 0, berlin)]; got != 23 {
+		t.Errorf("day of the clock change: got %v hours, want 23", got)
+	}
+
+	if hours := EarningsWeekly.SplitHours(start, start, berlin); len(hours) != 0 {
+		t.Errorf("empty session: got %v", hours)
+	}
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"time"
+)
+
+// UserDataExport is a copy of the personal data held about a user
+type UserDataExport struct {
+	ExportedAt        time.Time                `json:"exported_at"`
+	Profile           User                     `json:"profile"`
+	Rides             []Ride                   `json:"rides"`           // Rides as rider or driver
+	SharedBookings    []RidePassenger          `json:"shared_bookings"` // Seats booked on other users' shared rides
+	RatingsGiven      []Rating                 `json:"ratings_given"`
+	RatingsReceived   []Rating                 `json:"ratings_received"`
+	Locations         []Location               `json:"locations"`
+	Identities        []UserIdentity           `json:"identities"`
+	Organizations     []OrganizationMember     `json:"organizations"`
+	Vehicles          []Vehicle                `json:"vehicles"`
+	Notifications     []Notification           `json:"notifications"`
+	RideSeries        []RideSeries             `json:"ride_series"`          // Recurring rides offered
+	Subscriptions     []RideSeriesSubscription `json:"series_subscriptions"` // Recurring rides subscribed to
+	Waitlists         []RideWaitlistEntry      `json:"waitlists"`            // Places on full rides' waitlists
+	Destinations      []DriverDestination      `json:"driver_destinations"`  // Destinations set in destination mode
+	DriverApplication *DriverApplication       `json:"driver_application"`   // Onboarding application and documents, if any
+}
@@ -0,0 +1,19 @@
+package models
+
+import (
+	"time"
+)
+
+// HeatmapPrecision is the geohash length of heatmap cells; six characters is roughly 1.2 km by 0.6 km
+const HeatmapPrecision = 6
+
+// DemandCell is the recent demand for rides picking up in one heatmap cell, recomputed periodically
+type DemandCell struct {
+	Geohash   string    `json:"geohash" gorm:"primaryKey;size:12"`
+	Lat       float64   `json:"lat"` // Center of the cell
+	Lng       float64   `json:"lng"`
+	Requests  int64     `json:"requests"`  // Ride requests in the heatmap window
+	Weight    float64   `json:"weight"`    // Requests weighted by how recent they are
+	Intensity float64   `json:"intensity"` // Weight relative to the busiest cell, from 0 to 1
+	UpdatedAt time.Time `json:"updated_at"`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrSeatsAlreadyHeld = errors.New("seats already held on this ride")
+	ErrHoldNotActive    = errors.New("seat hold is no longer active")
+)
+
+// SeatHoldStatus is the state of a seat hold
+type SeatHoldStatus string
+
+const (
+	SeatHoldActive    SeatHoldStatus = "active"    // Seats are reserved until the hold expires
+	SeatHoldConfirmed SeatHoldStatus = "confirmed" // Turned into a booking
+	SeatHoldExpired   SeatHoldStatus = "expired"   // Not confirmed in time or before the ride closed, seats released
+	SeatHoldReleased  SeatHoldStatus = "released"  // Given up by the passenger, seats released
+)
+
+// SeatHold reserves seats on a shared ride for a short time while the passenger pays. The seats count as
+// booked until the hold is confirmed into a booking, released or expires.
+type SeatHold struct {
+	ID               uint           `json:"id" gorm:"primaryKey"`
+	RideID           uint           `json:"ride_id" gorm:"not null;index"`
+	UserID           uint           `json:"user_id" gorm:"not null;index"`
+	Seats            int            `json:"seats"`
+	Status           SeatHoldStatus `json:"status" gorm:"not null"`
+	ExpiresAt        time.Time      `json:"expires_at"`
+	PassengerID      *uint          `json:"passenger_id,omitempty"`      // Set once confirmed
+	PaymentReference string         `json:"payment_reference,omitempty"` // Given by the client when confirming, e.g. a payment intent ID
+	PickupLat        float64        `json:"pickup_lat"`                  // Stops the passenger asked for, kept for the booking
+	PickupLng        float64        `json:"pickup_lng"`
+	PickupAddress    string         `json:"pickup_address"`
+	DropoffLat       float64        `json:"dropoff_lat"`
+	DropoffLng       float64        `json:"dropoff_lng"`
+	DropoffAddress   string         `json:"dropoff_address"`
+	CreatedAt        time.Time      `json:"created_at"`
+	UpdatedAt        time.Time      `json:"updated_at"`
+}
+
+// NewHold returns a hold on seats of the ride for the passenger, lasting at most duration and never past
+// departure
+func (r *Ride) NewHold(passenger *RidePassenger, duration time.Duration) *SeatHold {
+	expiresAt := time.Now().Add(duration)
+	if r.DepartureTime.Before(expiresAt) {
+		expiresAt = r.DepartureTime
+	}
+	return &SeatHold{
+		RideID:         r.ID,
+		UserID:         passenger.UserID,
+		Seats:          passenger.Seats,
+		Status:         SeatHoldActive,
+		ExpiresAt:      expiresAt,
+		PickupLat:      passenger.PickupLat,
+		PickupLng:      passenger.PickupLng,
+		PickupAddress:  passenger.PickupAddress,
+		DropoffLat:     passenger.DropoffLat,
+		DropoffLng:     passenger.DropoffLng,
+		DropoffAddress: passenger.DropoffAddress,
+	}
+}
+
+// IsActive reports whether the hold still reserves its seats
+func (h *SeatHold) IsActive(now time.Time) bool {
+	return h.Status == SeatHoldActive && h.ExpiresAt.After(now)
+}
+
+// Passenger returns the booking the hold turns into. On manually approved rides this is a join request.
+func (h *SeatHold) Passenger(ride *Ride, expiry time.Duration) *RidePassenger {
+	passenger := ride.NewPassenger(h.UserID, h.Seats, expiry)
+	passenger.PickupLat, passenger.PickupLng, passenger.PickupAddress = h.PickupLat, h.PickupLng, h.PickupAddress
+	passenger.DropoffLat, passenger.DropoffLng, passenger.DropoffAddress = h.DropoffLat, h.DropoffLng, h.DropoffAddress
+	return passenger
+}
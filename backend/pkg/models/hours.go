@@ -0,0 +1,179 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// Reasons a driver session ended other than the driver going offline
+const (
+	SessionEndForcedBreak = "forced_break" // Continuous driving limit reached
+	SessionEndDailyLimit  = "daily_limit"  // Daily online limit reached
+)
+
+// HoursOfServiceLimits are the safety limits on how long drivers can stay online
+type HoursOfServiceLimits struct {
+	MaxContinuous time.Duration // Online time allowed before a break is required
+	MinBreak      time.Duration // Offline time that counts as a break
+	MaxDaily      time.Duration // Online time allowed in any 24 hours
+}
+
+// HoursOfServiceStatus is a driver's online time and remaining allowance
+type HoursOfServiceStatus struct {
+	OnlineMinutes              int        `json:"online_minutes"`  // Online in the last 24 hours
+	OnTripMinutes              int        `json:"on_trip_minutes"` // On trips in the last 24 hours
+	ContinuousMinutes          int        `json:"continuous_minutes"`
+	MaxContinuousMinutes       int        `json:"max_continuous_minutes"`
+	MinBreakMinutes            int        `json:"min_break_minutes"`
+	MaxDailyMinutes            int        `json:"max_daily_minutes"`
+	RemainingContinuousMinutes int        `json:"remaining_continuous_minutes"`
+	RemainingDailyMinutes      int        `json:"remaining_daily_minutes"`
+	RemainingMinutes           int        `json:"remaining_minutes"`     // Until the driver must go offline
+	BreakUntil                 *time.Time `json:"break_until,omitempty"` // Set while a required break is in progress
+	CanGoOnline                bool       `json:"can_go_online"`
+	Reason                     string     `json:"reason,omitempty"` // Why the driver cannot go online
+}
+
+// overlap returns how much of the interval from start to end falls after since
+func overlap(start, end, since time.Time) time.Duration {
+	if start.Before(since) {
+		start = since
+	}
+	if end.Before(start) {
+		return 0
+	}
+	return end.Sub(start)
+}
+
+// interval is a stretch of time a driver spent working
+type interval struct {
+	start, end time.Time
+}
+
+// workIntervals merges the time a driver was online with the time they spent on trips, from accepting a
+// ride until completing it, so staying on a trip after going offline does not count as a break
+func workIntervals(sessions []DriverSession, trips []Ride, now time.Time) []interval {
+	var intervals []interval
+	for _, s := range sessions {
+		end := now
+		if s.EndedAt != nil {
+			end = *s.EndedAt
+		}
+		intervals = append(intervals, interval{s.StartedAt, end})
+	}
+	for _, t := range trips {
+		start := t.AcceptedAt
+		if start == nil {
+			start = t.StartedAt
+		}
+		if start == nil {
+			continue
+		}
+		end := now
+		if t.CompletedAt != nil {
+			end = *t.CompletedAt
+		}
+		intervals = append(intervals, interval{*start, end})
+	}
+
+	sort.Slice(intervals, func(i, j int) bool {
+		return intervals[i].start.Before(intervals[j].start)
+	})
+	var merged []interval
+	for _, iv := range intervals {
+		if n := len(merged); n > 0 && !iv.start.After(merged[n-1].end) {
+			if iv.end.After(merged[n-1].end) {
+				merged[n-1].end = iv.end
+			}
+			continue
+		}
+		merged = append(merged, iv)
+	}
+	return merged
+}
+
+// ComputeHoursOfService works out a driver's status from their sessions and trips. Sessions and trips
+// must cover at least the last 24 hours; trips must have been accepted or started.
+func ComputeHoursOfService(limits HoursOfServiceLimits, sessions []DriverSession, trips []Ride, now time.Time) HoursOfServiceStatus {
+	dayAgo := now.Add(-24 * time.Hour)
+
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].StartedAt.Before(sessions[j].StartedAt)
+	})
+
+	var online time.Duration
+	for _, s := range sessions {
+		end := now
+		if s.EndedAt != nil {
+			end = *s.EndedAt
+		}
+		online += overlap(s.StartedAt, end, dayAgo)
+	}
+
+	var onTrip time.Duration
+	for _, t := range trips {
+		if t.StartedAt == nil {
+			continue
+		}
+		end := now
+		if t.CompletedAt != nil {
+			end = *t.CompletedAt
+		}
+		onTrip += overlap(*t.StartedAt, end, dayAgo)
+	}
+
+	// Continuous time is the time online or on trips since the last break, where gaps shorter than a
+	// break do not count
+	var continuous time.Duration
+	var blockEnd time.Time
+	work := workIntervals(sessions, trips, now)
+	for i := len(work) - 1; i >= 0; i-- {
+		w := work[i]
+		if i == len(work)-1 {
+			if now.Sub(w.end) >= limits.MinBreak {
+				break
+			}
+			blockEnd = w.end
+		} else if work[i+1].start.Sub(w.end) >= limits.MinBreak {
+			break
+		}
+		continuous += w.end.Sub(w.start)
+	}
+
+	status := HoursOfServiceStatus{
+		OnlineMinutes:        int(online.Minutes()),
+		OnTripMinutes:        int(onTrip.Minutes()),
+		ContinuousMinutes:    int(continuous.Minutes()),
+		MaxContinuousMinutes: int(limits.MaxContinuous.Minutes()),
+		MinBreakMinutes:      int(limits.MinBreak.Minutes()),
+		MaxDailyMinutes:      int(limits.MaxDaily.Minutes()),
+		CanGoOnline:          true,
+	}
+
+	remainingContinuous := limits.MaxContinuous - continuous
+	if remainingContinuous < 0 {
+		remainingContinuous = 0
+	}
+	remainingDaily := limits.MaxDaily - online
+	if remainingDaily < 0 {
+		remainingDaily = 0
+	}
+	status.RemainingContinuousMinutes = int(remainingContinuous.Minutes())
+	status.RemainingDailyMinutes = int(remainingDaily.Minutes())
+	status.RemainingMinutes = status.RemainingContinuousMinutes
+	if status.RemainingDailyMinutes < status.RemainingMinutes {
+		status.RemainingMinutes = status.RemainingDailyMinutes
+	}
+
+	if remainingContinuous == 0 {
+		breakUntil := blockEnd.Add(limits.MinBreak)
+		status.BreakUntil = &breakUntil
+		status.CanGoOnline = false
+		status.Reason = "A break is required after driving continuously"
+	}
+	if remainingDaily == 0 {
+		status.CanGoOnline = false
+		status.Reason = "Daily online limit reached"
+	}
+	return status
+}
@@ -0,0 +1,47 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestComputeHoursOfServiceCountsTripsAsDriving(t *testing.T) {
+	limits := HoursOfServiceLimits{MaxContinuous: 4 * time.Hour, MinBreak: 30 * time.Minute, MaxDaily: 12 * time.Hour}
+	now := time.Date(2024, 5, 6, 12, 0, 0, 0, time.UTC)
+	at := func(hour, minute int) *time.Time {
+		t := time.Date(2024, 5, 6, hour, minute, 0, 0, time.UTC)
+		return &t
+	}
+
+	// Online 08:00-10:00, then offline while finishing a trip accepted at 09:50 until 11:00, and back
+	// online at 11:20. The 20 minutes after the trip are too short to be a break.
+	sessions := []DriverSession{
+		{StartedAt: *at(8, 0), EndedAt: at(10, 0)},
+		{StartedAt: *at(11, 20)},
+	}
+	trips := []Ride{
+		{AcceptedAt: at(9, 50), StartedAt: at(10, 5), CompletedAt: at(11, 0)},
+	}
+
+	status := ComputeHoursOfService(limits, sessions, trips, now)
+	if status.ContinuousMinutes != 3*60+40 {
+		t.Errorf("got %d continuous minutes, want 220", status.ContinuousMinutes)
+	}
+
+	// Without the trip the hour offline is a break
+	status = ComputeHoursOfService(limits, sessions, nil, now)
+	if status.ContinuousMinutes != 40 {
+		t.Errorf("without the trip: got %d continuous minutes, want 40", status.ContinuousMinutes)
+	}
+
+	// A trip still in progress after the session ended keeps the stretch going
+	sessions = []DriverSession{{StartedAt: *at(8, 0), EndedAt: at(11, 0)}}
+	trips = []Ride{{AcceptedAt: at(10, 30), StartedAt: at(10, 45)}}
+	status = ComputeHoursOfService(limits, sessions, trips, now)
+	if status.ContinuousMinutes != 4*60 || status.CanGoOnline {
+		t.Errorf("trip in progress: got %d continuous minutes, can go online %v", status.ContinuousMinutes, status.CanGoOnline)
+	}
+	if status.BreakUntil == nil || !status.BreakUntil.Equal(now.Add(limits.MinBreak)) {
+		t.Errorf("trip in progress: got break until %v, want %v", status.BreakUntil, now.Add(limits.MinBreak))
+	}
+}
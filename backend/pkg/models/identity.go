@@ -0,0 +1,23 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrLastSignInMethod is returned when unlinking the only identity of an account without a password
+var ErrLastSignInMethod = errors.New("identity is the only way to sign in")
+
+// UserIdentity links an external OpenID Connect identity to a user
+type UserIdentity struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"not null;index"`
+	Provider  string    `json:"provider" gorm:"not null;uniqueIndex:idx_identity_provider_subject"`
+	Subject   string    `json:"subject" gorm:"not null;uniqueIndex:idx_identity_provider_subject"` // The provider's stable user ID ("sub" claim)
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationship
+	User User `json:"-" gorm:"foreignKey:UserID"`
+}
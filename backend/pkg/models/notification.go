@@ -0,0 +1,31 @@
+package models
+
+import (
+	"time"
+)
+
+// NotificationType is what a notification is about
+type NotificationType string
+
+const (
+	NotificationJoinRequested   NotificationType = "join_requested"   // To the ride owner, waiting for an answer
+	NotificationPassengerJoined NotificationType = "passenger_joined" // To the ride owner, on instant-book rides
+	NotificationJoinAccepted    NotificationType = "join_accepted"
+	NotificationJoinDeclined    NotificationType = "join_declined"
+	NotificationJoinExpired     NotificationType = "join_expired"     // To both sides
+	NotificationRideCancelled   NotificationType = "ride_cancelled"   // To passengers, when the owner cancels
+	NotificationSeriesRideFull  NotificationType = "series_ride_full" // To subscribers, when a ride of the series has no room for them
+	NotificationWaitlistOffer   NotificationType = "waitlist_offer"   // To waitlisted passengers, when seats are held for them
+	NotificationWaitlistExpired NotificationType = "waitlist_expired" // To waitlisted passengers, when they did not confirm in time
+)
+
+// Notification is a message in a user's in-app inbox
+type Notification struct {
+	ID        uint             `json:"id" gorm:"primaryKey"`
+	UserID    uint             `json:"user_id" gorm:"not null;index"`
+	Type      NotificationType `json:"type" gorm:"not null"`
+	Message   string           `json:"message"`
+	RideID    *uint            `json:"ride_id,omitempty"`
+	ReadAt    *time.Time       `json:"read_at"`
+	CreatedAt time.Time        `json:"created_at"`
+}
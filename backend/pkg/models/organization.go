@@ -0,0 +1,56 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidVerificationCode     = errors.New("invalid verification code")
+	ErrVerificationCodeExpired     = errors.New("verification code has expired")
+	ErrTooManyVerificationAttempts = errors.New("too many verification attempts")
+)
+
+const (
+	VerificationCodeLifetime       = 15 * time.Minute // How long an emailed verification code can be used
+	VerificationCodeResendInterval = time.Minute      // Shortest time between two codes sent for a membership
+	MaxVerificationAttempts        = 5                // Wrong codes allowed before a new code must be requested
+)
+
+// Organization represents a campus or company whose members can share rides privately
+type Organization struct {
+	ID          uint      `json:"id" gorm:"primaryKey"`
+	Name        string    `json:"name" gorm:"not null"`
+	EmailDomain string    `json:"email_domain" gorm:"unique;not null"` // e.g. "university.edu"
+	CreatedAt   time.Time `json:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// OrganizationMember represents a user's membership in an organization
+type OrganizationMember struct {
+	ID               uint       `json:"id" gorm:"primaryKey"`
+	OrganizationID   uint       `json:"organization_id" gorm:"not null;uniqueIndex:idx_org_member"`
+	UserID           uint       `json:"user_id" gorm:"not null;uniqueIndex:idx_org_member"`
+	Email            string     `json:"email"`       // Organization email used to prove membership
+	VerificationCode string     `json:"-"`           // Hashed code sent to Email
+	CodeSentAt       *time.Time `json:"-"`           // When the code was sent
+	CodeExpiresAt    *time.Time `json:"-"`           // The code cannot be used after this
+	CodeAttempts     int        `json:"-"`           // Wrong codes entered since the code was sent
+	VerifiedAt       *time.Time `json:"verified_at"` // Nil until the email is confirmed
+	CreatedAt        time.Time  `json:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at"`
+
+	// Relationships
+	Organization Organization `json:"organization" gorm:"foreignKey:OrganizationID"`
+	User         User         `json:"-" gorm:"foreignKey:UserID"`
+}
+
+// CanResendCode reports whether a new verification code may be sent for the membership
+func (m *OrganizationMember) CanResendCode(now time.Time) bool {
+	return m.CodeSentAt == nil || now.Sub(*m.CodeSentAt) >= VerificationCodeResendInterval
+}
+
+// IsVerified reports whether the member has confirmed their organization email
+func (m *OrganizationMember) IsVerified() bool {
+	return m.VerifiedAt != nil
+}
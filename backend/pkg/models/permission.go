@@ -0,0 +1,54 @@
+package models
+
+// Permission is an action a role is allowed to perform
+type Permission string
+
+const (
+	PermissionRidesReadAny        Permission = "rides:read_any"       // View any ride
+	PermissionRidesUpdateAny      Permission = "rides:update_any"     // Change the status of any ride
+	PermissionUsersReadAny        Permission = "users:read_any"       // View any user's profile
+	PermissionUsersSuspend        Permission = "users:suspend"        // Suspend and reinstate accounts
+	PermissionUsersManageRoles    Permission = "users:manage_roles"   // Change a user's role
+	PermissionUsersEditProfile    Permission = "users:edit_profile"   // Correct profile details users cannot change themselves
+	PermissionOrganizationsManage Permission = "organizations:manage" // Create and edit organizations
+	PermissionRatingsModerate     Permission = "ratings:moderate"     // Review flagged rating comments
+	PermissionDriversReview       Permission = "drivers:review"       // Review driver applications and documents
+)
+
+// rolePermissions maps each role to the permissions it grants
+var rolePermissions = map[UserRole][]Permission{
+	RoleSupport: {
+		PermissionRidesReadAny,
+		PermissionUsersReadAny,
+		PermissionUsersSuspend,
+		PermissionUsersEditProfile,
+		PermissionRatingsModerate,
+		PermissionDriversReview,
+	},
+	RoleAdmin: {
+		PermissionRidesReadAny,
+		PermissionRidesUpdateAny,
+		PermissionUsersReadAny,
+		PermissionUsersSuspend,
+		PermissionUsersManageRoles,
+		PermissionUsersEditProfile,
+		PermissionOrganizationsManage,
+		PermissionRatingsModerate,
+		PermissionDriversReview,
+	},
+}
+
+// HasPermission reports whether the role grants the permission
+func HasPermission(role UserRole, permission Permission) bool {
+	for _, p := range rolePermissions[role] {
+		if p == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// PermissionsForRole returns the permissions granted to a role
+func PermissionsForRole(role UserRole) []Permission {
+	return rolePermissions[role]
+}
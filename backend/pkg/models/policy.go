@@ -0,0 +1,173 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrRideNotFound         = errors.New("ride not found")
+	ErrRideForbidden        = errors.New("not allowed on this ride")
+	ErrRideStatusTransition = errors.New("ride cannot move to this status")
+)
+
+// RideAction is something a user can do with a ride
+type RideAction string
+
+const (
+	RideActionView             RideAction = "view"              // See the ride, its ratings and waitlist place
+	RideActionViewPassengers   RideAction = "view_passengers"   // List the passengers of a shared ride
+	RideActionUpdateStatus     RideAction = "update_status"     // Start, complete or cancel the ride
+	RideActionManagePassengers RideAction = "manage_passengers" // Answer join requests, remove passengers, see the waitlist
+	RideActionDrive            RideAction = "drive"             // Follow the itinerary and check in stops
+	RideActionTakeUp           RideAction = "take_up"           // Join an open shared ride, or accept or decline an on-demand request
+	RideActionTip              RideAction = "tip"               // Tip the driver
+)
+
+// RideRelation is the part a user plays in a ride
+type RideRelation string
+
+const (
+	RideRelationRider     RideRelation = "rider"     // Requested an on-demand ride
+	RideRelationOwner     RideRelation = "owner"     // Offers a shared ride and drives it
+	RideRelationDriver    RideRelation = "driver"    // Assigned to drive the ride
+	RideRelationPassenger RideRelation = "passenger" // Holds seats on a shared ride, or asked for them
+	RideRelationProspect  RideRelation = "prospect"  // May take the ride up: an open shared ride they can see, or a request waiting for a driver
+)
+
+// ridePolicy maps each action to the relations allowed to perform it
+var ridePolicy = map[RideAction][]RideRelation{
+	RideActionView: {
+		RideRelationRider, RideRelationOwner, RideRelationDriver, RideRelationPassenger, RideRelationProspect,
+	},
+	RideActionViewPassengers:   {RideRelationOwner, RideRelationDriver, RideRelationPassenger},
+	RideActionUpdateStatus:     {RideRelationRider, RideRelationOwner, RideRelationDriver},
+	RideActionManagePassengers: {RideRelationOwner},
+	RideActionDrive:            {RideRelationOwner, RideRelationDriver},
+	RideActionTakeUp:           {RideRelationProspect},
+	RideActionTip:              {RideRelationRider},
+}
+
+// rideStaffPermissions maps actions to the permission that allows staff to perform them on any ride
+var rideStaffPermissions = map[RideAction]Permission{
+	RideActionView:           PermissionRidesReadAny,
+	RideActionViewPassengers: PermissionRidesReadAny,
+	RideActionUpdateStatus:   PermissionRidesUpdateAny,
+}
+
+// rideTransitions maps each status a ride can be moved to by hand to the statuses each relation can move it
+// from. Only whoever drives the ride starts and completes it, and riders can only cancel before it starts.
+// Completed and cancelled rides are final.
+var rideTransitions = map[RideStatus]map[RideRelation][]RideStatus{
+	RideStatusStarted: {
+		RideRelationOwner:  {RideStatusPending},
+		RideRelationDriver: {RideStatusAccepted},
+	},
+	RideStatusCompleted: {
+		RideRelationOwner:  {RideStatusStarted},
+		RideRelationDriver: {RideStatusStarted},
+	},
+	RideStatusCancelled: {
+		RideRelationRider:  {RideStatusPending, RideStatusAccepted},
+		RideRelationOwner:  {RideStatusPending},
+		RideRelationDriver: {RideStatusAccepted},
+	},
+}
+
+// openRideStatuses are the statuses staff can move a ride from
+var openRideStatuses = []RideStatus{RideStatusPending, RideStatusAccepted, RideStatusStarted}
+
+// RideActor is a user acting on a ride
+type RideActor struct {
+	UserID uint
+	Role   UserRole
+	Member bool // Whether the user is a verified member of the ride's organization, if it has one
+}
+
+// RelationsTo returns the parts the actor plays in the ride. Passengers must be loaded.
+func (r *Ride) RelationsTo(actor RideActor) map[RideRelation]bool {
+	relations := make(map[RideRelation]bool)
+	if r.DriverID != nil && *r.DriverID == actor.UserID {
+		relations[RideRelationDriver] = true
+	}
+
+	if r.RideType == RideTypeOnDemand {
+		if r.RiderID == actor.UserID {
+			relations[RideRelationRider] = true
+		} else if r.Status == RideStatusPending && r.DriverID == nil && actor.Role == RoleDriver {
+			relations[RideRelationProspect] = true
+		}
+		return relations
+	}
+
+	if r.RiderID == actor.UserID {
+		relations[RideRelationOwner] = true
+		return relations
+	}
+	for _, p := range r.Passengers {
+		if p.UserID == actor.UserID && p.Status.HoldsSeats() {
+			relations[RideRelationPassenger] = true
+		}
+	}
+	if r.Status == RideStatusPending && r.DepartureTime.After(time.Now()) && (r.OrganizationID == nil || actor.Member) {
+		relations[RideRelationProspect] = true
+	}
+	return relations
+}
+
+// IsParticipant reports whether the actor takes part in the ride, rather than only being able to find it
+func (r *Ride) IsParticipant(actor RideActor) bool {
+	relations := r.RelationsTo(actor)
+	delete(relations, RideRelationProspect)
+	return len(relations) > 0
+}
+
+// AuthorizeRide decides whether the actor may perform an action on a ride. Actors who may not even view the
+// ride get ErrRideNotFound, so that rides they have no part in are not disclosed; actors who may view it but
+// not perform the action get ErrRideForbidden. Passengers must be loaded.
+func AuthorizeRide(ride *Ride, actor RideActor, action RideAction) error {
+	if rideAllows(ride, actor, action) {
+		return nil
+	}
+	if action != RideActionView && rideAllows(ride, actor, RideActionView) {
+		return ErrRideForbidden
+	}
+	return ErrRideNotFound
+}
+
+// rideAllows reports whether the actor's relations to the ride or their role allow the action
+func rideAllows(ride *Ride, actor RideActor, action RideAction) bool {
+	if permission, ok := rideStaffPermissions[action]; ok && HasPermission(actor.Role, permission) {
+		return true
+	}
+	relations := ride.RelationsTo(actor)
+	for _, relation := range ridePolicy[action] {
+		if relations[relation] {
+			return true
+		}
+	}
+	return false
+}
+
+// StatusTransitionsTo returns the statuses from which the actor may move the ride to the given status, or nil
+// if they may not set it at all. Passengers must be loaded.
+func (r *Ride) StatusTransitionsTo(actor RideActor, status RideStatus) []RideStatus {
+	if _, ok := rideTransitions[status]; ok && HasPermission(actor.Role, PermissionRidesUpdateAny) {
+		return openRideStatuses
+	}
+	var from []RideStatus
+	for relation := range r.RelationsTo(actor) {
+		from = append(from, rideTransitions[status][relation]...)
+	}
+	return from
+}
+
+// HideContactDetails removes the contact details of the people on the ride and its passenger list, for users
+// who can find the ride but do not take part in it
+func (r *Ride) HideContactDetails() {
+	r.Rider.Email, r.Rider.Phone = "", ""
+	if r.Driver != nil {
+		r.Driver.Email, r.Driver.Phone = "", ""
+	}
+	r.Passengers = nil
+}
@@ -0,0 +1,148 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var allRideActions = []RideAction{
+	RideActionView,
+	RideActionViewPassengers,
+	RideActionUpdateStatus,
+	RideActionManagePassengers,
+	RideActionDrive,
+	RideActionTakeUp,
+	RideActionTip,
+}
+
+// policyCase is an actor on a ride and the actions they are allowed. Every other action must be refused
+// with ErrRideForbidden when the actor can view the ride, and ErrRideNotFound otherwise.
+type policyCase struct {
+	name    string
+	ride    Ride
+	actor   RideActor
+	allowed []RideAction
+}
+
+func TestAuthorizeRide(t *testing.T) {
+	future := time.Now().Add(2 * time.Hour)
+	past := time.Now().Add(-2 * time.Hour)
+	driverID := uint(20)
+	orgID := uint(7)
+
+	openShared := Ride{ID: 1, RideType: RideTypeShared, RiderID: 1, Status: RideStatusPending, DepartureTime: future,
+		Passengers: []RidePassenger{{UserID: 2, Status: RideStatusAccepted}}}
+	startedShared := Ride{ID: 2, RideType: RideTypeShared, RiderID: 1, Status: RideStatusStarted, DepartureTime: past,
+		Passengers: []RidePassenger{{UserID: 2, Status: RideStatusAccepted}, {UserID: 5, Status: RideStatusCancelled}}}
+	openRequest := Ride{ID: 3, RideType: RideTypeOnDemand, RiderID: 10, Status: RideStatusPending}
+	acceptedRequest := Ride{ID: 4, RideType: RideTypeOnDemand, RiderID: 10, DriverID: &driverID, Status: RideStatusAccepted}
+	orgShared := openShared
+	orgShared.ID = 5
+	orgShared.OrganizationID = &orgID
+
+	cases := []policyCase{
+		// One case per relation
+		{"rider", acceptedRequest, RideActor{UserID: 10, Role: RoleRider},
+			[]RideAction{RideActionView, RideActionUpdateStatus, RideActionTip}},
+		{"owner", startedShared, RideActor{UserID: 1, Role: RoleDriver},
+			[]RideAction{RideActionView, RideActionViewPassengers, RideActionUpdateStatus, RideActionManagePassengers, RideActionDrive}},
+		{"driver", acceptedRequest, RideActor{UserID: 20, Role: RoleDriver},
+			[]RideAction{RideActionView, RideActionViewPassengers, RideActionUpdateStatus, RideActionDrive}},
+		{"passenger", startedShared, RideActor{UserID: 2, Role: RoleRider},
+			[]RideAction{RideActionView, RideActionViewPassengers}},
+		{"prospect on a shared ride", openShared, RideActor{UserID: 3, Role: RoleRider},
+			[]RideAction{RideActionView, RideActionTakeUp}},
+		{"prospect on a request", openRequest, RideActor{UserID: 20, Role: RoleDriver},
+			[]RideAction{RideActionView, RideActionTakeUp}},
+
+		// Passengers of open rides can also see it as prospects
+		{"passenger of an open ride", openShared, RideActor{UserID: 2, Role: RoleRider},
+			[]RideAction{RideActionView, RideActionViewPassengers, RideActionTakeUp}},
+
+		// No relation
+		{"stranger on a started ride", startedShared, RideActor{UserID: 4, Role: RoleRider}, nil},
+		{"cancelled passenger", startedShared, RideActor{UserID: 5, Role: RoleRider}, nil},
+		{"rider on someone else's request", openRequest, RideActor{UserID: 11, Role: RoleRider}, nil},
+		{"driver on an accepted request", acceptedRequest, RideActor{UserID: 21, Role: RoleDriver}, nil},
+
+		// Staff act through their permissions only
+		{"support", startedShared, RideActor{UserID: 30, Role: RoleSupport},
+			[]RideAction{RideActionView, RideActionViewPassengers}},
+		{"admin", startedShared, RideActor{UserID: 31, Role: RoleAdmin},
+			[]RideAction{RideActionView, RideActionViewPassengers, RideActionUpdateStatus}},
+
+		// Organization rides are hidden from non-members
+		{"organization member", orgShared, RideActor{UserID: 3, Role: RoleRider, Member: true},
+			[]RideAction{RideActionView, RideActionTakeUp}},
+		{"organization non-member", orgShared, RideActor{UserID: 3, Role: RoleRider}, nil},
+		{"organization ride owner", orgShared, RideActor{UserID: 1, Role: RoleDriver},
+			[]RideAction{RideActionView, RideActionViewPassengers, RideActionUpdateStatus, RideActionManagePassengers, RideActionDrive}},
+		{"organization ride passenger", orgShared, RideActor{UserID: 2, Role: RoleRider},
+			[]RideAction{RideActionView, RideActionViewPassengers}},
+	}
+
+	for _, tc := range cases {
+		allowed := make(map[RideAction]bool)
+		for _, action := range tc.allowed {
+			allowed[action] = true
+		}
+		for _, action := range allRideActions {
+			t.Run(fmt.Sprintf("%s/%s", tc.name, action), func(t *testing.T) {
+				ride := tc.ride
+				var want error
+				switch {
+				case allowed[action]:
+					want = nil
+				case allowed[RideActionView]:
+					want = ErrRideForbidden
+				default:
+					want = ErrRideNotFound
+				}
+				if got := AuthorizeRide(&ride, tc.actor, action); !errors.Is(got, want) {
+					t.Errorf("got %v, want %v", got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestStatusTransitionsTo(t *testing.T) {
+	driverID := uint(20)
+	shared := Ride{RideType: RideTypeShared, RiderID: 1, Status: RideStatusPending, DepartureTime: time.Now().Add(time.Hour),
+		Passengers: []RidePassenger{{UserID: 2, Status: RideStatusAccepted}}}
+	request := Ride{RideType: RideTypeOnDemand, RiderID: 10, DriverID: &driverID, Status: RideStatusAccepted}
+
+	tests := []struct {
+		name   string
+		ride   Ride
+		actor  RideActor
+		status RideStatus
+		want   []RideStatus
+	}{
+		{"owner starts", shared, RideActor{UserID: 1, Role: RoleDriver}, RideStatusStarted, []RideStatus{RideStatusPending}},
+		{"owner completes", shared, RideActor{UserID: 1, Role: RoleDriver}, RideStatusCompleted, []RideStatus{RideStatusStarted}},
+		{"owner cancels", shared, RideActor{UserID: 1, Role: RoleDriver}, RideStatusCancelled, []RideStatus{RideStatusPending}},
+		{"passenger cannot cancel the ride", shared, RideActor{UserID: 2, Role: RoleRider}, RideStatusCancelled, nil},
+		{"driver starts", request, RideActor{UserID: 20, Role: RoleDriver}, RideStatusStarted, []RideStatus{RideStatusAccepted}},
+		{"driver completes", request, RideActor{UserID: 20, Role: RoleDriver}, RideStatusCompleted, []RideStatus{RideStatusStarted}},
+		{"driver cancels", request, RideActor{UserID: 20, Role: RoleDriver}, RideStatusCancelled, []RideStatus{RideStatusAccepted}},
+		{"rider cannot start", request, RideActor{UserID: 10, Role: RoleRider}, RideStatusStarted, nil},
+		{"rider cannot complete", request, RideActor{UserID: 10, Role: RoleRider}, RideStatusCompleted, nil},
+		{"rider cancels before the start", request, RideActor{UserID: 10, Role: RoleRider}, RideStatusCancelled,
+			[]RideStatus{RideStatusPending, RideStatusAccepted}},
+		{"nobody reopens a ride", request, RideActor{UserID: 20, Role: RoleDriver}, RideStatusPending, nil},
+		{"admin from any open status", request, RideActor{UserID: 31, Role: RoleAdmin}, RideStatusCompleted, openRideStatuses},
+		{"admin cannot reopen a ride", request, RideActor{UserID: 31, Role: RoleAdmin}, RideStatusPending, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ride := tt.ride
+			if got := ride.StatusTransitionsTo(tt.actor, tt.status); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrPoolUnavailable = errors.New("pool is no longer waiting for a driver")
+	ErrPoolTooLarge    = errors.New("pool has more riders than the vehicle has seats")
+)
+
+// RidePool is a trip shared by riders of pooled on-demand rides, driven by one driver. Riders join while the
+// pool waits for a driver; once a driver accepts it, its rides are accepted together and no one else joins.
+type RidePool struct {
+	ID         uint      `json:"id" gorm:"primaryKey"`
+	Tier       RideTier  `json:"tier" gorm:"not null"`
+	DriverID   *uint     `json:"driver_id"`
+	DistanceKm float64   `json:"distance_km"` // Straight-line length of the planned stops, in order
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+
+	// Relationships
+	Rides []Ride     `json:"rides,omitempty" gorm:"foreignKey:PoolID"`
+	Stops []RideStop `json:"stops,omitempty" gorm:"foreignKey:PoolID"`
+}
+
+// IsOpen reports whether the pool still takes riders
+func (p *RidePool) IsOpen() bool {
+	return p.DriverID == nil
+}
+
+// PoolStops returns the rider's pickup and dropoff stops on the ride's pool, not yet placed in the pool's order
+func (r *Ride) PoolStops() (pickup, dropoff RideStop) {
+	pickup = RideStop{
+		RideID:  r.ID,
+		PoolID:  r.PoolID,
+		UserID:  &r.RiderID,
+		Kind:    StopPickup,
+		Seats:   1,
+		Lat:     r.PickupLat,
+		Lng:     r.PickupLng,
+		Address: r.PickupAddress,
+	}
+	dropoff = pickup
+	dropoff.Kind = StopDropoff
+	dropoff.Lat, dropoff.Lng, dropoff.Address = r.DropoffLat, r.DropoffLng, r.DropoffAddress
+	return pickup, dropoff
+}
+
+// PoolsWith reports whether the riders of two pooled rides suit each other's preferences. Luggage is matched
+// with the driver rather than between riders. Riders must be loaded.
+func (r *Ride) PoolsWith(other *Ride) bool {
+	return len(r.Preferences.Conflicts(r.Rider.Gender, other.coRiderOffer())) == 0 &&
+		len(other.Preferences.Conflicts(other.Rider.Gender, r.coRiderOffer())) == 0
+}
+
+// coRiderOffer returns the rider of a pooled ride as an offer to the riders sharing the car with them
+func (r *Ride) coRiderOffer() PreferenceOffer {
+	preferences := r.Preferences
+	preferences.Luggage = ""
+	return PreferenceOffer{Preferences: preferences, AllWomen: r.Rider.Gender == GenderFemale}
+}
+
+// TripRides returns the rides a driver takes on by accepting the ride: the open rides of its pool, or the
+// ride alone. The pool's rides must be loaded.
+func (r *Ride) TripRides() []Ride {
+	if r.Pool != nil && len(r.Pool.Rides) > 0 {
+		return r.Pool.Rides
+	}
+	return []Ride{*r}
+}
@@ -0,0 +1,258 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidPreference      = errors.New("invalid ride preference")
+	ErrWomenOnlyRequiresWoman = errors.New("only women can ask for women-only rides")
+)
+
+// Gender is the gender a user states on their profile, used for women-only rides. Users can state it once;
+// only staff can change it afterwards.
+type Gender string
+
+const (
+	GenderFemale Gender = "female"
+	GenderMale   Gender = "male"
+	GenderOther  Gender = "other"
+)
+
+// SmokingPreference is whether smoking is fine in the car
+type SmokingPreference string
+
+const (
+	SmokingNotAllowed SmokingPreference = "no_smoking"
+	SmokingAllowed    SmokingPreference = "smoking"
+)
+
+// PetPreference is whether pets are fine in the car
+type PetPreference string
+
+const (
+	PetsNotAllowed PetPreference = "no_pets"
+	PetsAllowed    PetPreference = "pets"
+)
+
+// MusicPreference is whether music is played during the ride
+type MusicPreference string
+
+const (
+	MusicOff MusicPreference = "no_music"
+	MusicOn  MusicPreference = "music"
+)
+
+// ConversationLevel is how much talking a user likes during a ride
+type ConversationLevel string
+
+const (
+	ConversationQuiet  ConversationLevel = "quiet"
+	ConversationSome   ConversationLevel = "some"
+	ConversationChatty ConversationLevel = "chatty"
+)
+
+// conversationLevels lists the conversation levels from least to most talking. Levels next to each other
+// get along.
+var conversationLevels = []ConversationLevel{ConversationQuiet, ConversationSome, ConversationChatty}
+
+// LuggageSize is the largest luggage a rider brings, or a ride or driver takes
+type LuggageSize string
+
+const (
+	LuggageNone   LuggageSize = "none"
+	LuggageSmall  LuggageSize = "small"  // A backpack or handbag
+	LuggageMedium LuggageSize = "medium" // A cabin-size suitcase
+	LuggageLarge  LuggageSize = "large"  // A checked-in suitcase
+)
+
+// luggageSizes lists the luggage sizes from smallest to largest
+var luggageSizes = []LuggageSize{LuggageNone, LuggageSmall, LuggageMedium, LuggageLarge}
+
+// Preferences are what a user wants from the people they ride with, declared on their profile and on the
+// rides they offer or request. Empty values mean no preference.
+type Preferences struct {
+	Smoking      SmokingPreference `json:"smoking,omitempty" gorm:"not null;default:''"`
+	Pets         PetPreference     `json:"pets,omitempty" gorm:"not null;default:''"`
+	Music        MusicPreference   `json:"music,omitempty" gorm:"not null;default:''"`
+	Conversation ConversationLevel `json:"conversation,omitempty" gorm:"not null;default:''"`
+	Luggage      LuggageSize       `json:"luggage,omitempty" gorm:"not null;default:''"`       // Riders: the largest luggage they bring; rides and drivers: the largest they take
+	WomenOnly    bool              `json:"women_only,omitempty" gorm:"not null;default:false"` // Riders: only ride with women; rides and drivers: only take women riders
+}
+
+// indexOf returns the position of value in levels, or -1 when it is not one of them
+func indexOf[T comparable](levels []T, value T) int {
+	for i, level := range levels {
+		if level == value {
+			return i
+		}
+	}
+	return -1
+}
+
+// Validate checks that the preferences hold known values and that only women ask for women-only rides
+func (p Preferences) Validate(gender Gender) error {
+	if p.Smoking != "" && p.Smoking != SmokingNotAllowed && p.Smoking != SmokingAllowed {
+		return fmt.Errorf("%w: smoking %q", ErrInvalidPreference, p.Smoking)
+	}
+	if p.Pets != "" && p.Pets != PetsNotAllowed && p.Pets != PetsAllowed {
+		return fmt.Errorf("%w: pets %q", ErrInvalidPreference, p.Pets)
+	}
+	if p.Music != "" && p.Music != MusicOff && p.Music != MusicOn {
+		return fmt.Errorf("%w: music %q", ErrInvalidPreference, p.Music)
+	}
+	if p.Conversation != "" && indexOf(conversationLevels, p.Conversation) < 0 {
+		return fmt.Errorf("%w: conversation %q", ErrInvalidPreference, p.Conversation)
+	}
+	if p.Luggage != "" && indexOf(luggageSizes, p.Luggage) < 0 {
+		return fmt.Errorf("%w: luggage %q", ErrInvalidPreference, p.Luggage)
+	}
+	if p.WomenOnly && gender != GenderFemale {
+		return ErrWomenOnlyRequiresWoman
+	}
+	return nil
+}
+
+// PreferenceOffer is the other side of a match for a rider: a shared ride, or a driver for an on-demand ride
+type PreferenceOffer struct {
+	Preferences
+	AllWomen bool // Everyone the rider would ride with is a woman: a women-only shared ride, or a woman driver
+}
+
+// PreferenceFilter is a condition an offer must meet to suit a rider
+type PreferenceFilter struct {
+	Preference string        // The preference that excludes offers failing the condition
+	Attribute  string        // The attribute of the offer checked: a preference, or all_women
+	Accepted   []interface{} // Values of the attribute that suit the rider
+	Reason     string        // Explains why offers failing the condition are excluded
+}
+
+// PreferenceConflict explains how an offer fails a rider's preferences
+type PreferenceConflict struct {
+	Preference string `json:"preference"`
+	Reason     string `json:"reason"`
+}
+
+// Filters returns the conditions an offer must meet to suit a rider of the given gender with these
+// preferences. Offers without a preference suit everyone.
+func (p Preferences) Filters(gender Gender) []PreferenceFilter {
+	var filters []PreferenceFilter
+	if p.Smoking != "" {
+		filters = append(filters, PreferenceFilter{
+			Preference: "smoking", Attribute: "smoking", Accepted: []interface{}{"", string(p.Smoking)},
+			Reason: map[SmokingPreference]string{
+				SmokingNotAllowed: "Smoking is allowed on the ride",
+				SmokingAllowed:    "The ride is smoke-free",
+			}[p.Smoking],
+		})
+	}
+	if p.Pets != "" {
+		filters = append(filters, PreferenceFilter{
+			Preference: "pets", Attribute: "pets", Accepted: []interface{}{"", string(p.Pets)},
+			Reason: map[PetPreference]string{
+				PetsNotAllowed: "Pets are allowed on the ride",
+				PetsAllowed:    "The ride does not take pets",
+			}[p.Pets],
+		})
+	}
+	if p.Music != "" {
+		filters = append(filters, PreferenceFilter{
+			Preference: "music", Attribute: "music", Accepted: []interface{}{"", string(p.Music)},
+			Reason: map[MusicPreference]string{
+				MusicOff: "Music is played on the ride",
+				MusicOn:  "The ride has no music",
+			}[p.Music],
+		})
+	}
+	if i := indexOf(conversationLevels, p.Conversation); i >= 0 {
+		accepted := []interface{}{""}
+		for j, level := range conversationLevels {
+			if j >= i-1 && j <= i+1 {
+				accepted = append(accepted, string(level))
+			}
+		}
+		filters = append(filters, PreferenceFilter{
+			Preference: "conversation", Attribute: "conversation", Accepted: accepted,
+			Reason: map[ConversationLevel]string{
+				ConversationQuiet:  "The ride is chatty",
+				ConversationChatty: "The ride is quiet",
+			}[p.Conversation],
+		})
+	}
+	if i := indexOf(luggageSizes, p.Luggage); i >= 0 {
+		accepted := []interface{}{""}
+		for _, size := range luggageSizes[i:] {
+			accepted = append(accepted, string(size))
+		}
+		filters = append(filters, PreferenceFilter{
+			Preference: "luggage", Attribute: "luggage", Accepted: accepted,
+			Reason: fmt.Sprintf("The ride does not take %s luggage", p.Luggage),
+		})
+	}
+	if p.WomenOnly {
+		filters = append(filters, PreferenceFilter{
+			Preference: "women_only", Attribute: "all_women", Accepted: []interface{}{true},
+			Reason: "You only ride with women",
+		})
+	}
+	if gender != GenderFemale {
+		filters = append(filters, PreferenceFilter{
+			Preference: "women_only", Attribute: "women_only", Accepted: []interface{}{false},
+			Reason: "The ride only takes women riders",
+		})
+	}
+	return filters
+}
+
+// attribute returns the value of an attribute of the offer checked by filters
+func (o PreferenceOffer) attribute(name string) interface{} {
+	switch name {
+	case "smoking":
+		return string(o.Smoking)
+	case "pets":
+		return string(o.Pets)
+	case "music":
+		return string(o.Music)
+	case "conversation":
+		return string(o.Conversation)
+	case "luggage":
+		return string(o.Luggage)
+	case "women_only":
+		return o.WomenOnly
+	case "all_women":
+		return o.AllWomen
+	}
+	return nil
+}
+
+// Conflicts returns the ways an offer fails the preferences of a rider of the given gender, or nothing when
+// it suits them
+func (p Preferences) Conflicts(gender Gender, offer PreferenceOffer) []PreferenceConflict {
+	var conflicts []PreferenceConflict
+	for _, filter := range p.Filters(gender) {
+		value := offer.attribute(filter.Attribute)
+		accepted := false
+		for _, a := range filter.Accepted {
+			if a == value {
+				accepted = true
+				break
+			}
+		}
+		if !accepted {
+			conflicts = append(conflicts, PreferenceConflict{Preference: filter.Preference, Reason: filter.Reason})
+		}
+	}
+	return conflicts
+}
+
+// PreferenceOffer returns the shared ride as an offer to riders. Only women can offer women-only rides, so
+// everyone on them is a woman.
+func (r *Ride) PreferenceOffer() PreferenceOffer {
+	return PreferenceOffer{Preferences: r.Preferences, AllWomen: r.Preferences.WomenOnly}
+}
+
+// PreferenceOffer returns the driver as an offer to on-demand riders, who ride alone with them
+func (u *User) PreferenceOffer() PreferenceOffer {
+	return PreferenceOffer{Preferences: u.Preferences, AllWomen: u.Gender == GenderFemale}
+}
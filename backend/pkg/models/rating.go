@@ -1,21 +1,138 @@
 package models
 
 import (
+	"encoding/json"
+	"errors"
 	"time"
 )
 
+// ErrAlreadyRated is returned when a user rates the same participant of a ride twice
+var ErrAlreadyRated = errors.New("already rated this user for this ride")
+
 // Rating represents a rating for a ride
 type Rating struct {
 	ID         uint      `json:"id" gorm:"primaryKey;column:id"`
-	RideID     uint      `json:"ride_id" gorm:"column:ride_id"`
-	FromUserID uint      `json:"from_user_id" gorm:"column:from_user_id"`
-	ToUserID   uint      `json:"to_user_id" gorm:"column:to_user_id"`
+	RideID     uint      `json:"ride_id" gorm:"column:ride_id;uniqueIndex:idx_ratings_ride_from_to"`
+	FromUserID uint      `json:"from_user_id" gorm:"column:from_user_id;uniqueIndex:idx_ratings_ride_from_to"`
+	ToUserID   uint      `json:"to_user_id" gorm:"column:to_user_id;uniqueIndex:idx_ratings_ride_from_to"`
+	ToUserRole UserRole  `json:"to_user_role" gorm:"column:to_user_role"` // Capacity in which the rated user took part
 	Rating     int       `json:"rating" gorm:"column:rating"`
 	Comment    string    `json:"comment" gorm:"column:comment"`
 	UserID     *uint     `json:"user_id" gorm:"column:user_id"`
 	CreatedAt  time.Time `json:"created_at" gorm:"column:created_at"`
 	UpdatedAt  time.Time `json:"updated_at" gorm:"column:updated_at"`
-	FromUser   User      `json:"from_user" gorm:"foreignKey:FromUserID"`
-	ToUser     User      `json:"to_user" gorm:"foreignKey:ToUserID"`
-	Ride       Ride      `json:"ride" gorm:"foreignKey:RideID"`
+
+	// Comment moderation
+	ModerationStatus ModerationStatus `json:"moderation_status" gorm:"column:moderation_status;default:approved"`
+	ModerationReason string           `json:"moderation_reason,omitempty" gorm:"column:moderation_reason"` // Why the comment was flagged
+	ReviewedByID     *uint            `json:"reviewed_by_id,omitempty" gorm:"column:reviewed_by_id"`
+	ReviewedAt       *time.Time       `json:"reviewed_at,omitempty" gorm:"column:reviewed_at"`
+
+	FromUser User        `json:"from_user" gorm:"foreignKey:FromUserID"`
+	ToUser   User        `json:"to_user" gorm:"foreignKey:ToUserID"`
+	Ride     Ride        `json:"ride" gorm:"foreignKey:RideID"`
+	Tags     []RatingTag `json:"tags" gorm:"foreignKey:RatingID"`
+}
+
+// ModerationStatus is the review state of a rating's comment
+type ModerationStatus string
+
+const (
+	ModerationApproved      ModerationStatus = "approved"       // Shown publicly
+	ModerationPendingReview ModerationStatus = "pending_review" // Flagged automatically, hidden until reviewed
+	ModerationRejected      ModerationStatus = "rejected"       // Comment removed by a reviewer, rating shown without it
+)
+
+// FeedbackTag is a predefined piece of feedback that can be attached to a rating
+type FeedbackTag string
+
+const (
+	// Feedback about drivers
+	TagCleanCar      FeedbackTag = "clean_car"
+	TagSmoothDriving FeedbackTag = "smooth_driving"
+	TagGreatMusic    FeedbackTag = "great_music"
+	TagKnowsTheRoute FeedbackTag = "knows_the_route"
+	TagDirtyCar      FeedbackTag = "dirty_car"
+	TagUnsafeDriving FeedbackTag = "unsafe_driving"
+	TagLatePickup    FeedbackTag = "late_pickup"
+	TagWrongRoute    FeedbackTag = "wrong_route"
+
+	// Feedback about riders and passengers
+	TagOnTime     FeedbackTag = "on_time"
+	TagRespectful FeedbackTag = "respectful"
+	TagLate       FeedbackTag = "late"
+	TagLeftMess   FeedbackTag = "left_mess"
+
+	// Feedback for either
+	TagFriendly         FeedbackTag = "friendly"
+	TagGoodConversation FeedbackTag = "good_conversation"
+	TagRude             FeedbackTag = "rude"
+)
+
+// FeedbackTagsByRole lists the tags that can be given to a user who took part in a ride in each capacity
+var FeedbackTagsByRole = map[UserRole][]FeedbackTag{
+	RoleDriver: {
+		TagCleanCar, TagSmoothDriving, TagGreatMusic, TagKnowsTheRoute, TagFriendly, TagGoodConversation,
+		TagDirtyCar, TagUnsafeDriving, TagLatePickup, TagWrongRoute, TagRude,
+	},
+	RoleRider: {
+		TagOnTime, TagRespectful, TagFriendly, TagGoodConversation,
+		TagLate, TagLeftMess, TagRude,
+	},
+}
+
+// IsValidFeedbackTag reports whether the tag can be given to a user in the given capacity
+func IsValidFeedbackTag(role UserRole, tag FeedbackTag) bool {
+	for _, t := range FeedbackTagsByRole[role] {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// RatingTag is a feedback tag attached to a rating
+type RatingTag struct {
+	ID       uint        `json:"-" gorm:"primaryKey"`
+	RatingID uint        `json:"-" gorm:"not null;uniqueIndex:idx_rating_tags_rating_tag"`
+	Tag      FeedbackTag `json:"tag" gorm:"not null;uniqueIndex:idx_rating_tags_rating_tag"`
+}
+
+// MarshalJSON renders a rating tag as its name
+func (t RatingTag) MarshalJSON() ([]byte, error) {
+	return json.Marshal(t.Tag)
+}
+
+// RatingPeriod is how long after a ride is completed its participants can rate each other
+const RatingPeriod = 7 * 24 * time.Hour
+
+// Ratings are averaged with a Bayesian prior so that a handful of early reviews cannot swing
+// a new user's rating: every user starts as if they already had RatingPriorWeight ratings of RatingPriorMean.
+const (
+	RatingPriorMean   = 5.0
+	RatingPriorWeight = 5
+)
+
+// BayesianRating returns the prior-weighted average of count ratings adding up to sum
+func BayesianRating(sum, count int64) float64 {
+	return (RatingPriorMean*RatingPriorWeight + float64(sum)) / float64(RatingPriorWeight+count)
+}
+
+// RoleRatingSummary summarizes the ratings a user received in one capacity (or overall)
+type RoleRatingSummary struct {
+	Rating       float64       `json:"rating"`  // Bayesian average
+	Average      float64       `json:"average"` // Plain average, 0 without ratings
+	Count        int64         `json:"count"`
+	Distribution map[int]int64 `json:"distribution"` // Number of ratings per star
+}
+
+// RatingSummary summarizes the ratings a user received
+type RatingSummary struct {
+	UserID     uint              `json:"user_id"`
+	WindowDays int               `json:"window_days,omitempty"` // Only ratings from the last WindowDays days are counted
+	Overall    RoleRatingSummary `json:"overall"`
+	AsRider    RoleRatingSummary `json:"as_rider"`
+	AsDriver   RoleRatingSummary `json:"as_driver"`
+
+	Tags map[FeedbackTag]int64 `json:"tags"` // Number of times each feedback tag was received
 }
@@ -1,9 +1,12 @@
 package models
 
 import (
+	"errors"
 	"time"
 )
 
+var ErrJoinRequestNotPending = errors.New("join request is not pending")
+
 type RideStatus string
 
 const (
@@ -14,6 +17,25 @@ const (
 	RideStatusCancelled RideStatus = "cancelled"
 )
 
+// Statuses only passengers' join requests go through
+const (
+	RideStatusDeclined RideStatus = "declined" // Turned down by the ride owner
+	RideStatusExpired  RideStatus = "expired"  // Not answered by the ride owner in time
+)
+
+// HoldsSeats reports whether a passenger in this status has seats booked on the ride
+func (s RideStatus) HoldsSeats() bool {
+	return s == RideStatusPending || s == RideStatusAccepted || s == RideStatusStarted
+}
+
+// BookingMode is how passengers join a shared ride
+type BookingMode string
+
+const (
+	BookingModeInstant BookingMode = "instant" // Join requests are accepted straight away
+	BookingModeManual  BookingMode = "manual"  // The ride owner accepts or declines each join request
+)
+
 type RideType string
 
 const (
@@ -29,47 +51,159 @@ const (
 	PaymentMethodWallet PaymentMethod = "wallet"
 )
 
+// DefaultMaxDetourMinutes is the detour a shared ride accepts for a passenger unless its driver chose otherwise
+const DefaultMaxDetourMinutes = 10
+
 type Ride struct {
-	ID             uint          `json:"id" gorm:"primaryKey"`
-	RideType       RideType      `json:"ride_type" gorm:"not null"`
-	RiderID        uint          `json:"rider_id"`
-	DriverID       *uint         `json:"driver_id"`
-	PickupLat      float64       `json:"pickup_lat"`
-	PickupLng      float64       `json:"pickup_lng"`
-	DropoffLat     float64       `json:"dropoff_lat"`
-	DropoffLng     float64       `json:"dropoff_lng"`
-	PickupAddress  string        `json:"pickup_address"`
-	DropoffAddress string        `json:"dropoff_address"`
-	Status         RideStatus    `json:"status"`
-	Price          float64       `json:"price"`
-	Distance       float64       `json:"distance"`        // in kilometers
-	Duration       int           `json:"duration"`        // in minutes
-	SeatsAvailable int           `json:"seats_available"` // For shared rides
-	SeatsBooked    int           `json:"seats_booked"`    // For shared rides
-	DepartureTime  time.Time     `json:"departure_time"`  // For shared rides
-	PaymentMethod  PaymentMethod `json:"payment_method"`
-	StartedAt      *time.Time    `json:"started_at"`
-	CompletedAt    *time.Time    `json:"completed_at"`
-	CreatedAt      time.Time     `json:"created_at"`
-	UpdatedAt      time.Time     `json:"updated_at"`
+	ID               uint          `json:"id" gorm:"primaryKey"`
+	RideType         RideType      `json:"ride_type" gorm:"not null"`
+	Tier             RideTier      `json:"tier,omitempty"` // For on-demand rides
+	RiderID          uint          `json:"rider_id"`
+	DriverID         *uint         `json:"driver_id"`
+	PickupLat        float64       `json:"pickup_lat"`
+	PickupLng        float64       `json:"pickup_lng"`
+	DropoffLat       float64       `json:"dropoff_lat"`
+	DropoffLng       float64       `json:"dropoff_lng"`
+	PickupAddress    string        `json:"pickup_address"`
+	DropoffAddress   string        `json:"dropoff_address"`
+	Status           RideStatus    `json:"status"`
+	Price            float64       `json:"price"`
+	Commission       float64       `json:"commission"`               // Platform commission, set when the ride is completed
+	Tip              float64       `json:"tip"`                      // Added by the rider after the ride
+	Distance         float64       `json:"distance"`                 // in kilometers
+	Duration         int           `json:"duration"`                 // in minutes
+	SeatsAvailable   int           `json:"seats_available"`          // For shared rides
+	SeatsBooked      int           `json:"seats_booked"`             // For shared rides
+	DepartureTime    time.Time     `json:"departure_time"`           // For shared rides
+	RoutePolyline    string        `json:"route_polyline,omitempty"` // For shared rides, encoded polyline of the planned route
+	RouteMinLat      float64       `json:"-"`                        // Bounding box of the route, for matching
+	RouteMaxLat      float64       `json:"-"`
+	RouteMinLng      float64       `json:"-"`
+	RouteMaxLng      float64       `json:"-"`
+	MaxDetourMinutes int           `json:"max_detour_minutes,omitempty"`                                                       // For shared rides, the longest detour the driver accepts for a passenger
+	BookingMode      BookingMode   `json:"booking_mode,omitempty"`                                                             // For shared rides
+	OptimizeStops    bool          `json:"optimize_stops,omitempty"`                                                           // For shared rides, order passenger stops for the least detour instead of along the route
+	Preferences      Preferences   `json:"preferences" gorm:"embedded;embeddedPrefix:pref_"`                                   // Of the owner for shared rides, of the rider for on-demand rides
+	Pooled           bool          `json:"pooled,omitempty"`                                                                   // For on-demand rides, the rider agreed to share the car for a discount
+	PoolID           *uint         `json:"pool_id,omitempty" gorm:"index"`                                                     // Pool a pooled on-demand ride was matched into
+	SeriesID         *uint         `json:"series_id,omitempty" gorm:"uniqueIndex:idx_rides_series_occurrence"`                 // Recurring series the ride was created from
+	OccurrenceDate   *time.Time    `json:"occurrence_date,omitempty" gorm:"type:date;uniqueIndex:idx_rides_series_occurrence"` // Date of the series the ride runs on
+	OrganizationID   *uint         `json:"organization_id"`                                                                    // Restricts a shared ride to members of an organization
+	VehicleID        *uint         `json:"vehicle_id"`                                                                         // Vehicle the ride is driven with
+	PaymentMethod    PaymentMethod `json:"payment_method"`
+	AcceptedAt       *time.Time    `json:"accepted_at"`
+	StartedAt        *time.Time    `json:"started_at"`
+	CompletedAt      *time.Time    `json:"completed_at"`
+	CancelledByID    *uint         `json:"cancelled_by_id"`
+	CreatedAt        time.Time     `json:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at"`
+
+	// Set when listing a user's rides: the capacity in which the user took part
+	UserRole UserRole `json:"user_role,omitempty" gorm:"-"`
 
 	// Relationships
-	Rider      User            `json:"rider" gorm:"foreignKey:RiderID"`
-	Driver     *User           `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
-	Passengers []RidePassenger `json:"passengers,omitempty" gorm:"foreignKey:RideID"`
+	Rider        User            `json:"rider" gorm:"foreignKey:RiderID"`
+	Driver       *User           `json:"driver,omitempty" gorm:"foreignKey:DriverID"`
+	Organization *Organization   `json:"organization,omitempty" gorm:"foreignKey:OrganizationID"`
+	Vehicle      *Vehicle        `json:"vehicle,omitempty" gorm:"foreignKey:VehicleID"`
+	Passengers   []RidePassenger `json:"passengers,omitempty" gorm:"foreignKey:RideID"`
+	Pool         *RidePool       `json:"pool,omitempty" gorm:"foreignKey:PoolID"`
 }
 
 // RidePassenger represents a passenger in a shared ride
 type RidePassenger struct {
-	ID        uint       `json:"id" gorm:"primaryKey"`
-	RideID    uint       `json:"ride_id"`
-	UserID    uint       `json:"user_id"`
-	Seats     int        `json:"seats"`
-	Status    RideStatus `json:"status"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
+	ID             uint       `json:"id" gorm:"primaryKey"`
+	RideID         uint       `json:"ride_id"`
+	UserID         uint       `json:"user_id"`
+	Seats          int        `json:"seats"`
+	Status         RideStatus `json:"status"`
+	ExpiresAt      *time.Time `json:"expires_at,omitempty"`      // When a pending join request lapses if the ride owner does not answer
+	SubscriptionID *uint      `json:"subscription_id,omitempty"` // Set when booked through a subscription to the ride's series
+	PickupLat      float64    `json:"pickup_lat"`                // Where the passenger gets in
+	PickupLng      float64    `json:"pickup_lng"`
+	PickupAddress  string     `json:"pickup_address"`
+	DropoffLat     float64    `json:"dropoff_lat"` // Where the passenger gets out
+	DropoffLng     float64    `json:"dropoff_lng"`
+	DropoffAddress string     `json:"dropoff_address"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
 
 	// Relationships
 	User User `json:"user" gorm:"foreignKey:UserID"`
 	Ride Ride `json:"ride" gorm:"foreignKey:RideID"`
 }
+
+// NewPassenger returns a passenger joining the ride, getting in and out at the ride's pickup and dropoff
+// unless told otherwise. On manually approved rides the passenger is a join
+// request that holds its seats until the owner answers, for at most expiry and never past departure.
+func (r *Ride) NewPassenger(userID uint, seats int, expiry time.Duration) *RidePassenger {
+	passenger := &RidePassenger{
+		RideID:         r.ID,
+		UserID:         userID,
+		Seats:          seats,
+		Status:         RideStatusAccepted,
+		PickupLat:      r.PickupLat,
+		PickupLng:      r.PickupLng,
+		PickupAddress:  r.PickupAddress,
+		DropoffLat:     r.DropoffLat,
+		DropoffLng:     r.DropoffLng,
+		DropoffAddress: r.DropoffAddress,
+	}
+	if r.BookingMode == BookingModeManual {
+		expiresAt := time.Now().Add(expiry)
+		if r.DepartureTime.Before(expiresAt) {
+			expiresAt = r.DepartureTime
+		}
+		passenger.Status = RideStatusPending
+		passenger.ExpiresAt = &expiresAt
+	}
+	return passenger
+}
+
+// RatingTargets returns the users the given user may rate on this ride, with the capacity
+// each of them took part in. On-demand rides pair the rider with the driver; on shared rides
+// the ride owner (and assigned driver, if any) and the passengers rate each other.
+// Passengers must be loaded.
+func (r *Ride) RatingTargets(userID uint) map[uint]UserRole {
+	targets := make(map[uint]UserRole)
+
+	isDriver := r.DriverID != nil && *r.DriverID == userID
+
+	if r.RideType == RideTypeOnDemand {
+		if userID == r.RiderID && r.DriverID != nil {
+			targets[*r.DriverID] = RoleDriver
+		}
+		if isDriver {
+			targets[r.RiderID] = RoleRider
+		}
+		return targets
+	}
+
+	if userID == r.RiderID || isDriver {
+		for _, p := range r.Passengers {
+			if p.Status.HoldsSeats() && p.UserID != userID {
+				targets[p.UserID] = RoleRider
+			}
+		}
+		return targets
+	}
+
+	for _, p := range r.Passengers {
+		if p.UserID == userID && p.Status.HoldsSeats() {
+			targets[r.RiderID] = RoleDriver
+			if r.DriverID != nil {
+				targets[*r.DriverID] = RoleDriver
+			}
+			break
+		}
+	}
+	return targets
+}
+
+// RideDecline records a driver turning down an on-demand ride, so it is not offered to them again
+type RideDecline struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	RideID    uint      `json:"ride_id" gorm:"not null;uniqueIndex:idx_ride_declines_ride_driver"`
+	DriverID  uint      `json:"driver_id" gorm:"not null;uniqueIndex:idx_ride_declines_ride_driver"`
+	CreatedAt time.Time `json:"created_at"`
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"time"
+)
+
+// WalkingMinutesPerKm converts walking distance into minutes when ranking shared ride matches
+const WalkingMinutesPerKm = 12
+
+// GeoPoint is a point with a search radius around it
+type GeoPoint struct {
+	Lat      float64
+	Lng      float64
+	RadiusKm float64
+}
+
+// SharedRideMatchMode is how shared rides are matched to a search
+type SharedRideMatchMode string
+
+const (
+	MatchByRadius SharedRideMatchMode = "radius" // Ride pickup and dropoff near the search origin and destination
+	MatchByRoute  SharedRideMatchMode = "route"  // Search origin and destination can be added to the ride's route
+)
+
+// SharedRideSearch is a passenger's search for shared rides. Unset fields do not filter.
+type SharedRideSearch struct {
+	Mode          SharedRideMatchMode
+	Origin        *GeoPoint     // Matched against ride pickups
+	Destination   *GeoPoint     // Matched against ride dropoffs
+	DepartureTime *time.Time    // Preferred departure
+	TimeWindow    time.Duration // How far from the preferred departure a ride can leave
+	Seats         int
+	Preferences   Preferences // Of the passenger; rides that do not suit them are excluded
+	Gender        Gender      // Of the passenger, checked against women-only rides
+	Excluded      bool        // Find the rides excluded only by the passenger's preferences instead
+	Page          int         // From 1
+	PageSize      int
+}
+
+// SharedRideMatch is a shared ride found by a search, with how well it fits
+type SharedRideMatch struct {
+	Ride                 Ride     `json:"ride"`
+	OriginWalkKm         float64  `json:"origin_walk_km"`           // From the search origin to the ride's pickup
+	DestinationWalkKm    float64  `json:"destination_walk_km"`      // From the ride's dropoff to the search destination
+	DepartureDiffMinutes float64  `json:"departure_diff_minutes"`   // Between the preferred and the ride's departure
+	DetourMinutes        *float64 `json:"detour_minutes,omitempty"` // Extra driving to pick up and drop off the passenger, when matched by route
+	Score                float64  `json:"score"`                    // Walking, detour and waiting in minutes; lower is better
+
+	// Set for rides excluded by the passenger's preferences: the preferences the ride does not suit
+	Conflicts []PreferenceConflict `json:"conflicts,omitempty"`
+}
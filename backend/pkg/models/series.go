@@ -0,0 +1,189 @@
+package models
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// DateLayout is how calendar dates are written in requests and responses
+const DateLayout = "2006-01-02"
+
+// ClockLayout is how times of day are written in recurrence rules
+const ClockLayout = "15:04"
+
+// weekdayNames maps the day names used in recurrence rules to weekdays
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// RideSeries is a template for a shared ride offered again and again, such as a daily commute. Rides are
+// created from it ahead of time for each date its recurrence rule matches.
+type RideSeries struct {
+	ID               uint          `json:"id" gorm:"primaryKey"`
+	OwnerID          uint          `json:"owner_id" gorm:"not null;index"`
+	PickupLat        float64       `json:"pickup_lat"`
+	PickupLng        float64       `json:"pickup_lng"`
+	DropoffLat       float64       `json:"dropoff_lat"`
+	DropoffLng       float64       `json:"dropoff_lng"`
+	PickupAddress    string        `json:"pickup_address"`
+	DropoffAddress   string        `json:"dropoff_address"`
+	Price            float64       `json:"price"`
+	Distance         float64       `json:"distance"` // in kilometers
+	Duration         int           `json:"duration"` // in minutes
+	SeatsAvailable   int           `json:"seats_available"`
+	PaymentMethod    PaymentMethod `json:"payment_method"`
+	OrganizationID   *uint         `json:"organization_id"`
+	RoutePolyline    string        `json:"route_polyline,omitempty"`
+	RouteMinLat      float64       `json:"-"`
+	RouteMaxLat      float64       `json:"-"`
+	RouteMinLng      float64       `json:"-"`
+	RouteMaxLng      float64       `json:"-"`
+	MaxDetourMinutes int           `json:"max_detour_minutes"`
+	BookingMode      BookingMode   `json:"booking_mode"`
+
+	// Recurrence rule
+	DaysOfWeek    string     `json:"days_of_week" gorm:"not null"`   // Comma separated, e.g. "mon,tue,wed"
+	DepartureTime string     `json:"departure_time" gorm:"not null"` // Time of day in TimeZone, e.g. "08:15"
+	TimeZone      string     `json:"time_zone" gorm:"not null"`      // IANA name, e.g. "Europe/Berlin"
+	StartDate     time.Time  `json:"start_date" gorm:"type:date;not null"`
+	EndDate       *time.Time `json:"end_date" gorm:"type:date"` // Open-ended when nil
+
+	IsActive  bool      `json:"is_active" gorm:"default:true"` // Ended series create no more rides
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	Owner     User             `json:"owner" gorm:"foreignKey:OwnerID"`
+	SkipDates []RideSeriesSkip `json:"skip_dates" gorm:"foreignKey:SeriesID"`
+}
+
+// RideSeriesSkip is a date on which a series does not run
+type RideSeriesSkip struct {
+	ID       uint      `json:"-" gorm:"primaryKey"`
+	SeriesID uint      `json:"-" gorm:"not null;uniqueIndex:idx_ride_series_skips_series_date"`
+	Date     time.Time `json:"date" gorm:"type:date;not null;uniqueIndex:idx_ride_series_skips_series_date"`
+}
+
+// MarshalJSON renders a skip date as the date
+func (s RideSeriesSkip) MarshalJSON() ([]byte, error) {
+	return json.Marshal(s.Date.Format(DateLayout))
+}
+
+// RideSeriesSubscription books a passenger on every ride of a series
+type RideSeriesSubscription struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	SeriesID  uint      `json:"series_id" gorm:"not null;uniqueIndex:idx_ride_series_subscriptions_series_user"`
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_ride_series_subscriptions_series_user"`
+	Seats     int       `json:"seats"`
+	IsActive  bool      `json:"is_active" gorm:"default:true"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	// Relationships
+	Series RideSeries `json:"series" gorm:"foreignKey:SeriesID"`
+}
+
+// ParseWeekdays parses comma separated day names such as "mon,wed,fri"
+func ParseWeekdays(days string) (map[time.Weekday]bool, bool) {
+	weekdays := make(map[time.Weekday]bool)
+	for _, name := range strings.Split(days, ",") {
+		day, ok := weekdayNames[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return nil, false
+		}
+		weekdays[day] = true
+	}
+	return weekdays, true
+}
+
+// Occurrence is one date a series runs on
+type Occurrence struct {
+	Date      time.Time // Calendar date in the series' time zone, at midnight UTC
+	Departure time.Time
+}
+
+// Occurrences returns the occurrences of the series departing between from and to, in order
+func (s *RideSeries) Occurrences(from, to time.Time) []Occurrence {
+	loc, err := time.LoadLocation(s.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	clock, err := time.Parse(ClockLayout, s.DepartureTime)
+	if err != nil {
+		return nil
+	}
+	weekdays, ok := ParseWeekdays(s.DaysOfWeek)
+	if !ok {
+		return nil
+	}
+	skipped := make(map[string]bool, len(s.SkipDates))
+	for _, skip := range s.SkipDates {
+		skipped[skip.Date.Format(DateLayout)] = true
+	}
+
+	start := time.Date(s.StartDate.Year(), s.StartDate.Month(), s.StartDate.Day(), 0, 0, 0, 0, loc)
+	from = from.In(loc)
+	day := time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, loc)
+	if day.Before(start) {
+		day = start
+	}
+
+	var occurrences []Occurrence
+	for ; ; day = day.AddDate(0, 0, 1) {
+		date := day.Format(DateLayout)
+		if s.EndDate != nil && date > s.EndDate.Format(DateLayout) {
+			break
+		}
+		departure := time.Date(day.Year(), day.Month(), day.Day(), clock.Hour(), clock.Minute(), 0, 0, loc)
+		if departure.After(to) {
+			break
+		}
+		if departure.Before(from) || !weekdays[day.Weekday()] || skipped[date] {
+			continue
+		}
+		occurrences = append(occurrences, Occurrence{
+			Date:      time.Date(day.Year(), day.Month(), day.Day(), 0, 0, 0, 0, time.UTC),
+			Departure: departure,
+		})
+	}
+	return occurrences
+}
+
+// NewRide returns the shared ride of the series for an occurrence
+func (s *RideSeries) NewRide(occurrence Occurrence) *Ride {
+	date := occurrence.Date
+	return &Ride{
+		RideType:         RideTypeShared,
+		RiderID:          s.OwnerID,
+		PickupLat:        s.PickupLat,
+		PickupLng:        s.PickupLng,
+		DropoffLat:       s.DropoffLat,
+		DropoffLng:       s.DropoffLng,
+		PickupAddress:    s.PickupAddress,
+		DropoffAddress:   s.DropoffAddress,
+		Status:           RideStatusPending,
+		Price:            s.Price,
+		Distance:         s.Distance,
+		Duration:         s.Duration,
+		SeatsAvailable:   s.SeatsAvailable,
+		DepartureTime:    occurrence.Departure,
+		RoutePolyline:    s.RoutePolyline,
+		RouteMinLat:      s.RouteMinLat,
+		RouteMaxLat:      s.RouteMaxLat,
+		RouteMinLng:      s.RouteMinLng,
+		RouteMaxLng:      s.RouteMaxLng,
+		MaxDetourMinutes: s.MaxDetourMinutes,
+		BookingMode:      s.BookingMode,
+		OrganizationID:   s.OrganizationID,
+		PaymentMethod:    s.PaymentMethod,
+		SeriesID:         &s.ID,
+		OccurrenceDate:   &date,
+	}
+}
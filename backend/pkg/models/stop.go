@@ -0,0 +1,84 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrDetourTooLong      = errors.New("stops are too far off the ride's route")
+	ErrStopCheckedIn      = errors.New("stop already checked in")
+	ErrPickupNotCheckedIn = errors.New("passenger has not been picked up")
+)
+
+// StopKind is what happens at a stop of a shared ride
+type StopKind string
+
+const (
+	StopOrigin      StopKind = "origin"      // Start of the ride, not stored
+	StopPickup      StopKind = "pickup"      // A passenger gets in
+	StopDropoff     StopKind = "dropoff"     // A passenger gets out
+	StopDestination StopKind = "destination" // End of the ride, not stored
+)
+
+// RideStop is a passenger's pickup or dropoff point on a shared ride, or a rider's on a pooled on-demand
+// ride. The stops of a ride, or of a pool, are visited in order of Sequence.
+type RideStop struct {
+	ID          uint       `json:"id,omitempty" gorm:"primaryKey"`
+	RideID      uint       `json:"ride_id" gorm:"not null;index"`
+	PassengerID *uint      `json:"passenger_id,omitempty" gorm:"index"`
+	PoolID      *uint      `json:"pool_id,omitempty" gorm:"index"` // Set for the stops of pooled on-demand rides, ordered across the pool
+	UserID      *uint      `json:"user_id,omitempty"`
+	Kind        StopKind   `json:"kind" gorm:"not null"`
+	Sequence    int        `json:"sequence"`
+	Seats       int        `json:"seats,omitempty"`
+	Lat         float64    `json:"lat"`
+	Lng         float64    `json:"lng"`
+	Address     string     `json:"address"`
+	CheckedInAt *time.Time `json:"checked_in_at,omitempty"` // When the driver confirmed the passenger got in or out
+	CreatedAt   time.Time  `json:"-"`
+	UpdatedAt   time.Time  `json:"-"`
+
+	// Relationships
+	User *User `json:"user,omitempty" gorm:"foreignKey:UserID"`
+}
+
+// Stops returns the passenger's pickup and dropoff stops, not yet placed in the ride's order
+func (p *RidePassenger) Stops() (pickup, dropoff RideStop) {
+	pickup = RideStop{
+		RideID:      p.RideID,
+		PassengerID: &p.ID,
+		UserID:      &p.UserID,
+		Kind:        StopPickup,
+		Seats:       p.Seats,
+		Lat:         p.PickupLat,
+		Lng:         p.PickupLng,
+		Address:     p.PickupAddress,
+	}
+	dropoff = pickup
+	dropoff.Kind = StopDropoff
+	dropoff.Lat, dropoff.Lng, dropoff.Address = p.DropoffLat, p.DropoffLng, p.DropoffAddress
+	return pickup, dropoff
+}
+
+// Itinerary returns the ride's stops in order, from its origin to its destination
+func (r *Ride) Itinerary(stops []RideStop) []RideStop {
+	itinerary := make([]RideStop, 0, len(stops)+2)
+	itinerary = append(itinerary, RideStop{
+		RideID:  r.ID,
+		Kind:    StopOrigin,
+		Lat:     r.PickupLat,
+		Lng:     r.PickupLng,
+		Address: r.PickupAddress,
+	})
+	itinerary = append(itinerary, stops...)
+	itinerary = append(itinerary, RideStop{
+		RideID:   r.ID,
+		Kind:     StopDestination,
+		Sequence: len(stops) + 1,
+		Lat:      r.DropoffLat,
+		Lng:      r.DropoffLng,
+		Address:  r.DropoffAddress,
+	})
+	return itinerary
+}
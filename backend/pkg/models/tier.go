@@ -0,0 +1,140 @@
+package models
+
+import (
+	"math"
+	"time"
+)
+
+// RideTier is the product an on-demand ride is booked with
+type RideTier string
+
+const (
+	RideTierEconomy    RideTier = "economy"
+	RideTierXL         RideTier = "xl"         // Six or more passenger seats
+	RideTierPremium    RideTier = "premium"    // Recent luxury vehicles
+	RideTierAccessible RideTier = "accessible" // Wheelchair accessible vehicles
+)
+
+// RateCard is the fare structure of a tier
+type RateCard struct {
+	BaseFare    float64 `json:"base_fare"`
+	PerKm       float64 `json:"per_km"`
+	PerMinute   float64 `json:"per_minute"`
+	MinimumFare float64 `json:"minimum_fare"`
+	BookingFee  float64 `json:"booking_fee"` // Added on top of the fare, including the minimum fare
+}
+
+// Fare returns the price of a trip of the given distance (in kilometers) and duration (in minutes), rounded to cents
+func (rc RateCard) Fare(distance float64, duration int) float64 {
+	fare := rc.BaseFare + rc.PerKm*distance + rc.PerMinute*float64(duration)
+	if fare < rc.MinimumFare {
+		fare = rc.MinimumFare
+	}
+	return math.Round((fare+rc.BookingFee)*100) / 100
+}
+
+// VehicleRequirements are what a driver's active vehicle needs to offer to serve a tier
+type VehicleRequirements struct {
+	MinSeats         int                    `json:"min_seats"`
+	Classes          []VehicleClass         `json:"classes,omitempty"`       // Any class when empty
+	MaxAgeYears      int                    `json:"max_age_years,omitempty"` // No age limit when zero
+	RequiredFeatures []AccessibilityFeature `json:"required_features,omitempty"`
+}
+
+// TierDefinition describes a tier's pricing and vehicle requirements
+type TierDefinition struct {
+	Tier         RideTier            `json:"tier"`
+	Name         string              `json:"name"`
+	RateCard     RateCard            `json:"rate_card"`
+	Requirements VehicleRequirements `json:"requirements"`
+}
+
+// RideTiers lists the tiers riders can choose from, cheapest first
+var RideTiers = []TierDefinition{
+	{
+		Tier:         RideTierEconomy,
+		Name:         "Economy",
+		RateCard:     RateCard{BaseFare: 2.50, PerKm: 1.00, PerMinute: 0.20, MinimumFare: 6.00, BookingFee: 1.50},
+		Requirements: VehicleRequirements{MinSeats: 3},
+	},
+	{
+		Tier:     RideTierAccessible,
+		Name:     "Accessible",
+		RateCard: RateCard{BaseFare: 2.50, PerKm: 1.00, PerMinute: 0.20, MinimumFare: 6.00, BookingFee: 1.50},
+		Requirements: VehicleRequirements{
+			MinSeats:         1,
+			RequiredFeatures: []AccessibilityFeature{FeatureWheelchairAccessible},
+		},
+	},
+	{
+		Tier:     RideTierXL,
+		Name:     "XL",
+		RateCard: RateCard{BaseFare: 4.00, PerKm: 1.60, PerMinute: 0.30, MinimumFare: 9.00, BookingFee: 1.50},
+		Requirements: VehicleRequirements{
+			MinSeats: 6,
+			Classes:  []VehicleClass{VehicleClassSUV, VehicleClassMinivan},
+		},
+	},
+	{
+		Tier:     RideTierPremium,
+		Name:     "Premium",
+		RateCard: RateCard{BaseFare: 6.00, PerKm: 2.50, PerMinute: 0.45, MinimumFare: 15.00, BookingFee: 2.00},
+		Requirements: VehicleRequirements{
+			MinSeats:    3,
+			Classes:     []VehicleClass{VehicleClassLuxury},
+			MaxAgeYears: 6,
+		},
+	},
+}
+
+// GetTierDefinition returns the definition of a tier, or false if the tier does not exist
+func GetTierDefinition(tier RideTier) (TierDefinition, bool) {
+	for _, def := range RideTiers {
+		if def.Tier == tier {
+			return def, true
+		}
+	}
+	return TierDefinition{}, false
+}
+
+// Qualifies reports whether the vehicle meets the requirements. The vehicle's features must be loaded.
+func (req VehicleRequirements) Qualifies(vehicle *Vehicle, now time.Time) bool {
+	if vehicle.SeatCapacity < req.MinSeats {
+		return false
+	}
+
+	if len(req.Classes) > 0 {
+		allowed := false
+		for _, class := range req.Classes {
+			if vehicle.Class == class {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return false
+		}
+	}
+
+	if req.MaxAgeYears > 0 && now.Year()-vehicle.Year > req.MaxAgeYears {
+		return false
+	}
+
+	for _, feature := range req.RequiredFeatures {
+		if !vehicle.HasFeature(feature) {
+			return false
+		}
+	}
+	return true
+}
+
+// QualifyingTiers returns the tiers the vehicle can serve
+func QualifyingTiers(vehicle *Vehicle, now time.Time) []RideTier {
+	var tiers []RideTier
+	for _, def := range RideTiers {
+		if def.Requirements.Qualifies(vehicle, now) {
+			tiers = append(tiers, def.Tier)
+		}
+	}
+	return tiers
+}
@@ -9,29 +9,45 @@ import (
 type UserRole string
 
 const (
-	RoleRider  UserRole = "rider"
-	RoleDriver UserRole = "driver"
+	RoleRider   UserRole = "rider"
+	RoleDriver  UserRole = "driver"
+	RoleSupport UserRole = "support" // Customer support staff
+	RoleAdmin   UserRole = "admin"   // Platform administrators
 )
 
+// AccountDeletionCoolingOff is how long a deletion request can still be cancelled before it takes effect
+const AccountDeletionCoolingOff = 14 * 24 * time.Hour
+
 type User struct {
-	ID             uint      `json:"id" gorm:"primaryKey"`
-	Email          string    `json:"email" gorm:"unique;not null"`
-	Password       string    `json:"-" gorm:"not null"`
-	FirstName      string    `json:"first_name"`
-	LastName       string    `json:"last_name"`
-	Phone          string    `json:"phone"`
-	Role           UserRole  `json:"role"`
-	ProfilePicture string    `json:"profile_picture"`                  // URL to profile picture
-	Rating         float64   `json:"rating" gorm:"default:5.0"`        // User rating
-	IsVerified     bool      `json:"is_verified" gorm:"default:false"` // Whether the user is verified
-	CreatedAt      time.Time `json:"created_at"`
-	UpdatedAt      time.Time `json:"updated_at"`
-
-	// For drivers
+	ID                uint       `json:"id" gorm:"primaryKey"`
+	Email             string     `json:"email" gorm:"unique;not null"`
+	Password          string     `json:"-" gorm:"not null"`
+	FirstName         string     `json:"first_name"`
+	LastName          string     `json:"last_name"`
+	Phone             string     `json:"phone"`
+	Role              UserRole   `json:"role"`                                 // Active mode for riders and drivers
+	ProfilePicture    string     `json:"profile_picture"`                      // URL to profile picture
+	Rating            float64    `json:"rating" gorm:"default:5.0"`            // Bayesian average of all ratings received
+	RatingCount       int64      `json:"rating_count" gorm:"default:0"`        // Number of ratings received
+	RiderRating       float64    `json:"rider_rating" gorm:"default:5.0"`      // Bayesian average of ratings received as rider
+	RiderRatingCount  int64      `json:"rider_rating_count" gorm:"default:0"`  // Number of ratings received as rider
+	DriverRating      float64    `json:"driver_rating" gorm:"default:5.0"`     // Bayesian average of ratings received as driver
+	DriverRatingCount int64      `json:"driver_rating_count" gorm:"default:0"` // Number of ratings received as driver
+	IsVerified        bool       `json:"is_verified" gorm:"default:false"`     // Whether the user is verified
+	CanDrive          bool       `json:"can_drive" gorm:"default:false"`       // Whether the user may switch to driver mode
+	RandomPassword    bool       `json:"-" gorm:"not null;default:false"`      // Set for social login accounts until a password is chosen
+	Gender            Gender     `json:"gender,omitempty"`                     // Optional, needed for women-only rides
+	SuspendedAt       *time.Time `json:"suspended_at,omitempty"`               // Set while the account is suspended
+	DeletionDueAt     *time.Time `json:"deletion_due_at,omitempty"`            // When a requested account deletion takes effect
+	AnonymizedAt      *time.Time `json:"anonymized_at,omitempty"`              // Set once the account's personal data has been removed
+	CreatedAt         time.Time  `json:"created_at"`
+	UpdatedAt         time.Time  `json:"updated_at"`
+
+	// For drivers; vehicles are registered separately
 	LicenseNumber string `json:"license_number,omitempty"`
-	VehicleModel  string `json:"vehicle_model,omitempty"`
-	VehicleColor  string `json:"vehicle_color,omitempty"`
-	VehiclePlate  string `json:"vehicle_plate,omitempty"`
+
+	// Defaults for the rides the user requests or offers, and what they accept as a driver
+	Preferences Preferences `json:"preferences" gorm:"embedded;embeddedPrefix:pref_"`
 }
 
 func (u *User) HashPassword() error {
@@ -47,3 +63,13 @@ func (u *User) CheckPassword(password string) bool {
 	err := bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password))
 	return err == nil
 }
+
+// IsSuspended reports whether the account has been suspended
+func (u *User) IsSuspended() bool {
+	return u.SuspendedAt != nil
+}
+
+// IsDeletionPending reports whether the user has requested account deletion that has not yet taken effect
+func (u *User) IsDeletionPending() bool {
+	return u.DeletionDueAt != nil && u.AnonymizedAt == nil
+}
@@ -0,0 +1,87 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var (
+	ErrNoActiveVehicle   = errors.New("no active vehicle")
+	ErrVehicleInUse      = errors.New("vehicle is assigned to open rides")
+	ErrVehicleOverbooked = errors.New("open rides offer more seats than the vehicle has")
+)
+
+// VehicleClass is the body type of a vehicle
+type VehicleClass string
+
+const (
+	VehicleClassCompact VehicleClass = "compact"
+	VehicleClassSedan   VehicleClass = "sedan"
+	VehicleClassSUV     VehicleClass = "suv"
+	VehicleClassMinivan VehicleClass = "minivan"
+	VehicleClassLuxury  VehicleClass = "luxury"
+)
+
+// AccessibilityFeature is an accessibility feature a vehicle offers
+type AccessibilityFeature string
+
+const (
+	FeatureWheelchairAccessible AccessibilityFeature = "wheelchair_accessible" // Room for a passenger to stay seated in a wheelchair
+	FeatureRamp                 AccessibilityFeature = "ramp"                  // Ramp or lift for boarding
+	FeatureSwivelSeat           AccessibilityFeature = "swivel_seat"
+	FeatureFoldingWheelchair    AccessibilityFeature = "folding_wheelchair" // Trunk space for a folding wheelchair
+)
+
+// IsValidAccessibilityFeature reports whether feature is a known accessibility feature
+func IsValidAccessibilityFeature(feature AccessibilityFeature) bool {
+	switch feature {
+	case FeatureWheelchairAccessible, FeatureRamp, FeatureSwivelSeat, FeatureFoldingWheelchair:
+		return true
+	}
+	return false
+}
+
+// Vehicle is a vehicle registered by a driver. A driver can register several vehicles but drives one active vehicle at a time.
+type Vehicle struct {
+	ID           uint           `json:"id" gorm:"primaryKey"`
+	DriverID     uint           `json:"driver_id" gorm:"not null;index;uniqueIndex:idx_vehicles_active_driver,where:is_active"`
+	Make         string         `json:"make" gorm:"not null"`
+	Model        string         `json:"model" gorm:"not null"`
+	Year         int            `json:"year"`
+	Color        string         `json:"color"`
+	Plate        string         `json:"plate" gorm:"not null"`
+	SeatCapacity int            `json:"seat_capacity" gorm:"not null"` // Passenger seats, not counting the driver
+	Class        VehicleClass   `json:"class" gorm:"not null"`
+	IsActive     bool           `json:"is_active" gorm:"default:false"` // The vehicle the driver is currently driving
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	DeletedAt    gorm.DeletedAt `json:"-" gorm:"index"` // Removed vehicles are kept for the rides that used them
+
+	// Relationships
+	Features []VehicleFeature `json:"accessibility_features" gorm:"foreignKey:VehicleID"`
+}
+
+// HasFeature reports whether the vehicle offers the given accessibility feature. Features must be loaded.
+func (v *Vehicle) HasFeature(feature AccessibilityFeature) bool {
+	for _, f := range v.Features {
+		if f.Feature == feature {
+			return true
+		}
+	}
+	return false
+}
+
+// VehicleFeature is an accessibility feature of a vehicle
+type VehicleFeature struct {
+	ID        uint                 `json:"-" gorm:"primaryKey"`
+	VehicleID uint                 `json:"-" gorm:"not null;uniqueIndex:idx_vehicle_features_vehicle_feature"`
+	Feature   AccessibilityFeature `json:"feature" gorm:"not null;uniqueIndex:idx_vehicle_features_vehicle_feature"`
+}
+
+// MarshalJSON renders a vehicle feature as its name
+func (f VehicleFeature) MarshalJSON() ([]byte, error) {
+	return json.Marshal(f.Feature)
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrNotEnoughSeats    = errors.New("not enough seats available")
+	ErrRideNotOpen       = errors.New("ride is no longer open for booking")
+	ErrAlreadyWaitlisted = errors.New("already on the waitlist")
+	ErrNoWaitlistOffer   = errors.New("no seat is on offer")
+)
+
+// WaitlistStatus is the state of a waitlist entry
+type WaitlistStatus string
+
+const (
+	WaitlistWaiting   WaitlistStatus = "waiting"   // In line for seats
+	WaitlistOffered   WaitlistStatus = "offered"   // Seats are held until the passenger confirms or the offer expires
+	WaitlistConfirmed WaitlistStatus = "confirmed" // The passenger took the seats
+	WaitlistExpired   WaitlistStatus = "expired"   // The offer was not confirmed in time, or the ride was cancelled
+	WaitlistCancelled WaitlistStatus = "cancelled" // The passenger left the waitlist
+)
+
+// RideWaitlistEntry is a passenger waiting for seats on a full shared ride. Entries are served first come,
+// first served, skipping entries that need more seats than have been freed.
+type RideWaitlistEntry struct {
+	ID             uint           `json:"id" gorm:"primaryKey"`
+	RideID         uint           `json:"ride_id" gorm:"not null;index"`
+	UserID         uint           `json:"user_id" gorm:"not null;index"`
+	Seats          int            `json:"seats"`
+	Status         WaitlistStatus `json:"status" gorm:"not null"`
+	OfferExpiresAt *time.Time     `json:"offer_expires_at,omitempty"` // Set while seats are on offer
+	PassengerID    *uint          `json:"passenger_id,omitempty"`     // Set once the offer is confirmed
+	PickupLat      float64        `json:"pickup_lat"`                 // Stops the passenger asked for, kept for the booking
+	PickupLng      float64        `json:"pickup_lng"`
+	PickupAddress  string         `json:"pickup_address"`
+	DropoffLat     float64        `json:"dropoff_lat"`
+	DropoffLng     float64        `json:"dropoff_lng"`
+	DropoffAddress string         `json:"dropoff_address"`
+	Position       int64          `json:"position,omitempty" gorm:"-"` // Place in line while waiting, from 1
+	CreatedAt      time.Time      `json:"created_at"`
+	UpdatedAt      time.Time      `json:"updated_at"`
+
+	// Relationships
+	User User `json:"user" gorm:"foreignKey:UserID"`
+	Ride Ride `json:"-" gorm:"foreignKey:RideID"`
+}
+
+// IsOpen reports whether the entry is still waiting for or holding seats
+func (e *RideWaitlistEntry) IsOpen() bool {
+	return e.Status == WaitlistWaiting || e.Status == WaitlistOffered
+}
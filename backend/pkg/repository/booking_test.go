@@ -0,0 +1,269 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database/databasetest"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+)
+
+// createSharedRide creates an owner and an open shared ride with the given seats, and n other users
+func createSharedRide(t *testing.T, seats, n int) (*models.Ride, []uint) {
+	t.Helper()
+	userRepo := NewUserRepository()
+	owner := &models.User{Email: "owner@example.com", Password: "password", Role: models.RoleDriver}
+	if err := userRepo.CreateUser(owner); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	ride := &models.Ride{
+		RideType:         models.RideTypeShared,
+		RiderID:          owner.ID,
+		PickupLat:        52.52,
+		PickupLng:        13.40,
+		DropoffLat:       52.40,
+		DropoffLng:       13.06,
+		Status:           models.RideStatusPending,
+		SeatsAvailable:   seats,
+		DepartureTime:    time.Now().Add(2 * time.Hour),
+		MaxDetourMinutes: models.DefaultMaxDetourMinutes,
+		BookingMode:      models.BookingModeInstant,
+	}
+	if err := NewRideRepository().CreateRide(ride); err != nil {
+		t.Fatalf("CreateRide: %v", err)
+	}
+
+	userIDs := make([]uint, n)
+	for i := range userIDs {
+		user := &models.User{Email: fmt.Sprintf("passenger%d@example.com", i), Password: "password", Role: models.RoleRider}
+		if err := userRepo.CreateUser(user); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		userIDs[i] = user.ID
+	}
+	return ride, userIDs
+}
+
+// seatsBooked reads the seats booked on a ride
+func seatsBooked(t *testing.T, db *gorm.DB, rideID uint) int {
+	t.Helper()
+	var ride models.Ride
+	if err := db.First(&ride, rideID).Error; err != nil {
+		t.Fatalf("failed to load ride: %v", err)
+	}
+	return ride.SeatsBooked
+}
+
+func TestConcurrentBookingsDoNotOversell(t *testing.T) {
+	db := databasetest.Setup(t)
+	const seats, attempts = 3, 20
+	ride, userIDs := createSharedRide(t, seats, attempts)
+
+	rideRepo := NewRideRepository()
+	holdRepo := NewHoldRepository()
+	errs := make([]error, attempts)
+	var wg sync.WaitGroup
+	for i, userID := range userIDs {
+		wg.Add(1)
+		go func(i int, userID uint) {
+			defer wg.Done()
+			passenger := ride.NewPassenger(userID, 1, time.Hour)
+			if i%2 == 0 {
+				errs[i] = rideRepo.AddPassenger(passenger)
+			} else {
+				errs[i] = holdRepo.CreateHold(ride.NewHold(passenger, time.Hour), passenger)
+			}
+		}(i, userID)
+	}
+	wg.Wait()
+
+	booked := 0
+	for i, err := range errs {
+		switch {
+		case err == nil:
+			booked++
+		case !errors.Is(err, models.ErrNotEnoughSeats):
+			t.Errorf("attempt %d: unexpected error %v", i, err)
+		}
+	}
+	if booked != seats {
+		t.Errorf("%d bookings and holds succeeded, want %d", booked, seats)
+	}
+	if got := seatsBooked(t, db, ride.ID); got != seats {
+		t.Errorf("seats_booked is %d, want %d", got, seats)
+	}
+
+	var passengers, holds int64
+	db.Model(&models.RidePassenger{}).Where("ride_id = ?", ride.ID).Count(&passengers)
+	db.Model(&models.SeatHold{}).Where("ride_id = ? AND status = ?", ride.ID, models.SeatHoldActive).Count(&holds)
+	if int(passengers+holds) != seats {
+		t.Errorf("%d passengers and %d holds, want %d in total", passengers, holds, seats)
+	}
+}
+
+func TestHoldsReleaseSeatsExactlyOnce(t *testing.T) {
+	db := databasetest.Setup(t)
+	const booked, held = 2, 4
+	ride, userIDs := createSharedRide(t, booked+held, booked+held)
+
+	rideRepo := NewRideRepository()
+	holdRepo := NewHoldRepository()
+	for _, userID := range userIDs[:booked] {
+		if err := rideRepo.AddPassenger(ride.NewPassenger(userID, 1, time.Hour)); err != nil {
+			t.Fatalf("AddPassenger: %v", err)
+		}
+	}
+	var holds []*models.SeatHold
+	for _, userID := range userIDs[booked:] {
+		passenger := ride.NewPassenger(userID, 1, time.Hour)
+		hold := ride.NewHold(passenger, time.Hour)
+		if err := holdRepo.CreateHold(hold, passenger); err != nil {
+			t.Fatalf("CreateHold: %v", err)
+		}
+		holds = append(holds, hold)
+	}
+
+	// Passengers release their holds while the expiry job runs, several times over
+	var mu sync.Mutex
+	released, expired := 0, 0
+	var wg sync.WaitGroup
+	for round := 0; round < 3; round++ {
+		for _, hold := range holds {
+			wg.Add(1)
+			go func(hold models.SeatHold) {
+				defer wg.Done()
+				err := holdRepo.ReleaseHold(&hold)
+				if err != nil && !errors.Is(err, models.ErrHoldNotActive) {
+					t.Errorf("ReleaseHold: %v", err)
+				}
+				if err == nil {
+					mu.Lock()
+					released++
+					mu.Unlock()
+				}
+			}(*hold)
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			due, err := holdRepo.ExpireHolds(time.Now().Add(2 * time.Hour))
+			if err != nil {
+				t.Errorf("ExpireHolds: %v", err)
+			}
+			mu.Lock()
+			expired += len(due)
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	if released+expired != held {
+		t.Errorf("%d holds released and %d expired, want %d in total", released, expired, held)
+	}
+	if got := seatsBooked(t, db, ride.ID); got != booked {
+		t.Errorf("seats_booked is %d after the holds ended, want %d", got, booked)
+	}
+}
+
+func TestBookingRefusedOnceRideIsNotOpen(t *testing.T) {
+	db := databasetest.Setup(t)
+	ride, userIDs := createSharedRide(t, 3, 2)
+	rideRepo := NewRideRepository()
+	holdRepo := NewHoldRepository()
+
+	if err := db.Model(ride).Update("status", models.RideStatusCancelled).Error; err != nil {
+		t.Fatalf("failed to cancel ride: %v", err)
+	}
+	if err := rideRepo.AddPassenger(ride.NewPassenger(userIDs[0], 1, time.Hour)); !errors.Is(err, models.ErrRideNotOpen) {
+		t.Errorf("AddPassenger on a cancelled ride: got %v, want ErrRideNotOpen", err)
+	}
+
+	if err := db.Model(ride).Updates(map[string]interface{}{
+		"status":         models.RideStatusPending,
+		"departure_time": time.Now().Add(-time.Minute),
+	}).Error; err != nil {
+		t.Fatalf("failed to update ride: %v", err)
+	}
+	passenger := ride.NewPassenger(userIDs[1], 1, time.Hour)
+	if err := holdRepo.CreateHold(ride.NewHold(passenger, time.Hour), passenger); !errors.Is(err, models.ErrRideNotOpen) {
+		t.Errorf("CreateHold on a departed ride: got %v, want ErrRideNotOpen", err)
+	}
+	if got := seatsBooked(t, db, ride.ID); got != 0 {
+		t.Errorf("seats_booked is %d, want 0", got)
+	}
+}
+
+func TestCancelRideEndsBookingsInProgress(t *testing.T) {
+	db := databasetest.Setup(t)
+	ride, userIDs := createSharedRide(t, 4, 3)
+	rideRepo := NewRideRepository()
+	holdRepo := NewHoldRepository()
+
+	// One accepted passenger, one pending join request and one seat hold
+	if err := rideRepo.AddPassenger(ride.NewPassenger(userIDs[0], 1, time.Hour)); err != nil {
+		t.Fatalf("AddPassenger: %v", err)
+	}
+	request := ride.NewPassenger(userIDs[1], 1, time.Hour)
+	request.Status = models.RideStatusPending
+	if err := rideRepo.AddPassenger(request); err != nil {
+		t.Fatalf("AddPassenger: %v", err)
+	}
+	held := ride.NewPassenger(userIDs[2], 2, time.Hour)
+	hold := ride.NewHold(held, time.Hour)
+	if err := holdRepo.CreateHold(hold, held); err != nil {
+		t.Fatalf("CreateHold: %v", err)
+	}
+
+	if err := rideRepo.CancelRide(ride.ID, []models.RideStatus{models.RideStatusStarted}, ride.RiderID); !errors.Is(err, models.ErrRideStatusTransition) {
+		t.Fatalf("CancelRide from the wrong status: got %v, want ErrRideStatusTransition", err)
+	}
+	if err := rideRepo.CancelRide(ride.ID, []models.RideStatus{models.RideStatusPending}, ride.RiderID); err != nil {
+		t.Fatalf("CancelRide: %v", err)
+	}
+
+	var current models.SeatHold
+	db.First(&current, hold.ID)
+	if current.Status != models.SeatHoldExpired {
+		t.Errorf("hold is %s, want expired", current.Status)
+	}
+	var pending models.RidePassenger
+	db.First(&pending, request.ID)
+	if pending.Status != models.RideStatusCancelled {
+		t.Errorf("join request is %s, want cancelled", pending.Status)
+	}
+	if got := seatsBooked(t, db, ride.ID); got != 1 {
+		t.Errorf("seats_booked is %d, want the accepted passenger's 1", got)
+	}
+}
+
+func TestConfirmHoldOnClosedRideReleasesSeats(t *testing.T) {
+	db := databasetest.Setup(t)
+	ride, userIDs := createSharedRide(t, 3, 1)
+	holdRepo := NewHoldRepository()
+
+	passenger := ride.NewPassenger(userIDs[0], 2, time.Hour)
+	hold := ride.NewHold(passenger, time.Hour)
+	if err := holdRepo.CreateHold(hold, passenger); err != nil {
+		t.Fatalf("CreateHold: %v", err)
+	}
+	if err := db.Model(ride).Update("status", models.RideStatusCancelled).Error; err != nil {
+		t.Fatalf("failed to cancel ride: %v", err)
+	}
+
+	if err := holdRepo.ConfirmHold(hold, ride.NewPassenger(userIDs[0], 2, time.Hour), ""); !errors.Is(err, models.ErrHoldNotActive) {
+		t.Fatalf("ConfirmHold on a cancelled ride: got %v, want ErrHoldNotActive", err)
+	}
+	var current models.SeatHold
+	db.First(&current, hold.ID)
+	if current.Status != models.SeatHoldExpired {
+		t.Errorf("hold is %s, want expired", current.Status)
+	}
+	if got := seatsBooked(t, db, ride.ID); got != 0 {
+		t.Errorf("seats_booked is %d, want 0", got)
+	}
+}
@@ -0,0 +1,415 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+)
+
+type DriverRepository struct {
+	db *gorm.DB
+}
+
+func NewDriverRepository() *DriverRepository {
+	return &DriverRepository{
+		db: database.GetDB(),
+	}
+}
+
+// CreateApplication creates a new driver application
+func (r *DriverRepository) CreateApplication(app *models.DriverApplication) error {
+	return r.db.Create(app).Error
+}
+
+// GetApplicationByUserID retrieves a user's driver application with its documents
+func (r *DriverRepository) GetApplicationByUserID(userID uint) (*models.DriverApplication, error) {
+	var app models.DriverApplication
+	if err := r.db.Where("user_id = ?", userID).Preload("Documents").First(&app).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("driver application not found")
+		}
+		return nil, err
+	}
+	return &app, nil
+}
+
+// GetApplicationByID retrieves a driver application with its documents and applicant
+func (r *DriverRepository) GetApplicationByID(id uint) (*models.DriverApplication, error) {
+	var app models.DriverApplication
+	if err := r.db.Preload("User").Preload("Documents").First(&app, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("driver application not found")
+		}
+		return nil, err
+	}
+	return &app, nil
+}
+
+// GetApplications retrieves driver applications, optionally filtered by status, oldest submissions first
+func (r *DriverRepository) GetApplications(status models.DriverApplicationStatus) ([]models.DriverApplication, error) {
+	var apps []models.DriverApplication
+	query := r.db.Order("submitted_at").Preload("User").Preload("Documents")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	return apps, nil
+}
+
+// AddDocument adds an uploaded document to an application
+func (r *DriverRepository) AddDocument(doc *models.DriverDocument) error {
+	return r.db.Create(doc).Error
+}
+
+// GetDocumentByID retrieves a driver document by ID
+func (r *DriverRepository) GetDocumentByID(id uint) (*models.DriverDocument, error) {
+	var doc models.DriverDocument
+	if err := r.db.First(&doc, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("document not found")
+		}
+		return nil, err
+	}
+	return &doc, nil
+}
+
+// SubmitApplication moves an application to submitted
+func (r *DriverRepository) SubmitApplication(app *models.DriverApplication) error {
+	now := time.Now()
+	app.Status = models.DriverApplicationSubmitted
+	app.SubmittedAt = &now
+	app.RejectionReason = ""
+	return r.db.Model(app).Updates(map[string]interface{}{
+		"status":           app.Status,
+		"submitted_at":     now,
+		"rejection_reason": "",
+	}).Error
+}
+
+// StartReview moves a submitted application to in review
+func (r *DriverRepository) StartReview(app *models.DriverApplication, reviewerID uint) error {
+	app.Status = models.DriverApplicationInReview
+	app.ReviewedByID = &reviewerID
+	return r.db.Model(app).Updates(map[string]interface{}{
+		"status":         app.Status,
+		"reviewed_by_id": reviewerID,
+	}).Error
+}
+
+// ApproveApplication approves an application and its pending documents and lets the user drive
+func (r *DriverRepository) ApproveApplication(app *models.DriverApplication, reviewerID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(app).Updates(map[string]interface{}{
+			"status":         models.DriverApplicationApproved,
+			"reviewed_at":    now,
+			"reviewed_by_id": reviewerID,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.DriverDocument{}).
+			Where("application_id = ? AND status = ?", app.ID, models.DocumentPending).
+			Update("status", models.DocumentApproved).Error; err != nil {
+			return err
+		}
+
+		userUpdates := map[string]interface{}{"can_drive": true}
+		if license := app.CurrentDocument(models.DocumentLicense); license != nil {
+			userUpdates["license_number"] = license.Number
+		}
+		if err := tx.Model(&models.User{}).Where("id = ?", app.UserID).Updates(userUpdates).Error; err != nil {
+			return err
+		}
+
+		app.Status = models.DriverApplicationApproved
+		app.ReviewedAt = &now
+		app.ReviewedByID = &reviewerID
+		return nil
+	})
+}
+
+// RejectApplication rejects an application and its pending documents so they can be uploaded again
+func (r *DriverRepository) RejectApplication(app *models.DriverApplication, reviewerID uint, reason string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(app).Updates(map[string]interface{}{
+			"status":           models.DriverApplicationRejected,
+			"rejection_reason": reason,
+			"reviewed_at":      now,
+			"reviewed_by_id":   reviewerID,
+		}).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Model(&models.DriverDocument{}).
+			Where("application_id = ? AND status = ?", app.ID, models.DocumentPending).
+			Update("status", models.DocumentRejected).Error; err != nil {
+			return err
+		}
+
+		app.Status = models.DriverApplicationRejected
+		app.RejectionReason = reason
+		app.ReviewedAt = &now
+		app.ReviewedByID = &reviewerID
+		return nil
+	})
+}
+
+// ReviewDocument approves or rejects a single document, e.g. a renewal uploaded after approval
+func (r *DriverRepository) ReviewDocument(doc *models.DriverDocument, approve bool) error {
+	doc.Status = models.DocumentRejected
+	if approve {
+		doc.Status = models.DocumentApproved
+	}
+	return r.db.Model(doc).Update("status", doc.Status).Error
+}
+
+// CheckDriverEligibility returns an error unless the user is an approved driver with valid documents
+func (r *DriverRepository) CheckDriverEligibility(userID uint) error {
+	app, err := r.GetApplicationByUserID(userID)
+	if err != nil {
+		return models.ErrDriverNotApproved
+	}
+	return app.CheckEligibility(time.Now())
+}
+
+// GetOpenSession retrieves the session of a driver who is currently online
+func (r *DriverRepository) GetOpenSession(driverID uint) (*models.DriverSession, error) {
+	var session models.DriverSession
+	if err := r.db.Where("driver_id = ? AND ended_at IS NULL", driverID).First(&session).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("driver is offline")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// StartSession puts a driver online
+func (r *DriverRepository) StartSession(driverID uint) (*models.DriverSession, error) {
+	session := &models.DriverSession{DriverID: driverID, StartedAt: time.Now()}
+	if err := r.db.Create(session).Error; err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// EndSession takes a driver offline, with a reason when it was not the driver's choice
+func (r *DriverRepository) EndSession(session *models.DriverSession, reason string) error {
+	now := time.Now()
+	session.EndedAt = &now
+	session.EndReason = reason
+	return r.db.Model(session).Updates(map[string]interface{}{
+		"ended_at":   now,
+		"end_reason": reason,
+	}).Error
+}
+
+// GetOpenSessions retrieves the sessions of all drivers who are currently online
+func (r *DriverRepository) GetOpenSessions() ([]models.DriverSession, error) {
+	var sessions []models.DriverSession
+	if err := r.db.Where("ended_at IS NULL").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetHoursOfService works out a driver's online time and remaining allowance under the hours-of-service limits
+func (r *DriverRepository) GetHoursOfService(driverID uint) (*models.HoursOfServiceStatus, error) {
+	limits := hoursOfServiceLimits()
+	now := time.Now()
+
+	// Look back far enough to find the start of the current continuous stretch
+	since := now.Add(-24 * time.Hour)
+	if lookback := now.Add(-limits.MaxContinuous - limits.MinBreak); lookback.Before(since) {
+		since = lookback
+	}
+
+	var sessions []models.DriverSession
+	if err := r.db.Where("driver_id = ? AND (ended_at IS NULL OR ended_at > ?)", driverID, since).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+
+	var trips []models.Ride
+	if err := r.db.Select("id", "accepted_at", "started_at", "completed_at").
+		Where("driver_id = ? AND (accepted_at IS NOT NULL OR started_at IS NOT NULL) AND (completed_at IS NULL OR completed_at > ?)", driverID, since).
+		Where("status <> ?", models.RideStatusCancelled).
+		Find(&trips).Error; err != nil {
+		return nil, err
+	}
+
+	status := models.ComputeHoursOfService(limits, sessions, trips, now)
+	return &status, nil
+}
+
+// hoursOfServiceLimits returns the configured hours-of-service limits, in minutes
+func hoursOfServiceLimits() models.HoursOfServiceLimits {
+	minutes := func(name string, fallback int) time.Duration {
+		m, err := strconv.Atoi(os.Getenv(name))
+		if err != nil || m <= 0 {
+			m = fallback
+		}
+		return time.Duration(m) * time.Minute
+	}
+	return models.HoursOfServiceLimits{
+		MaxContinuous: minutes("HOS_MAX_CONTINUOUS_MINUTES", 240),
+		MinBreak:      minutes("HOS_MIN_BREAK_MINUTES", 30),
+		MaxDaily:      minutes("HOS_MAX_DAILY_MINUTES", 720),
+	}
+}
+
+// GetEarnings aggregates a driver's completed trips, time online and ride decisions between from and to,
+// bucketed by periods starting at midnight in the given time zone
+func (r *DriverRepository) GetEarnings(driverID uint, period models.EarningsPeriod, from, to time.Time, loc *time.Location) (*models.EarningsReport, error) {
+	buckets := make(map[int64]*models.EarningsBucket)
+	bucket := func(start time.Time) *models.EarningsBucket {
+		b, ok := buckets[start.Unix()]
+		if !ok {
+			b = &models.EarningsBucket{PeriodStart: start.In(loc)}
+			buckets[start.Unix()] = b
+		}
+		return b
+	}
+
+	// Fares of completed trips
+	var trips []models.EarningsBucket
+	if err := r.db.Model(&models.Ride{}).
+		Select(`date_trunc(?, completed_at, ?) AS period_start, COUNT(*) AS trips,
+			COALESCE(SUM(price), 0) AS gross_fares, COALESCE(SUM(commission), 0) AS commission,
+			COALESCE(SUM(tip), 0) AS tips,
+			COALESCE(SUM(CASE WHEN payment_method = ? THEN price ELSE 0 END), 0) AS cash_fares`,
+			period, loc.String(), models.PaymentMethodCash).
+		Where("driver_id = ? AND status = ? AND completed_at >= ? AND completed_at < ?",
+			driverID, models.RideStatusCompleted, from, to).
+		Group("period_start").
+		Scan(&trips).Error; err != nil {
+		return nil, err
+	}
+	for _, t := range trips {
+		b := bucket(t.PeriodStart)
+		b.Trips, b.GrossFares, b.Commission, b.Tips, b.CashFares = t.Trips, t.GrossFares, t.Commission, t.Tips, t.CashFares
+	}
+
+	// Time online, clipped to the range. Sessions spanning several periods count towards each of them.
+	var sessions []models.DriverSession
+	if err := r.db.Where("driver_id = ? AND started_at < ? AND COALESCE(ended_at, NOW()) > ?", driverID, to, from).
+		Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for _, s := range sessions {
+		start, end := s.StartedAt, now
+		if s.EndedAt != nil {
+			end = *s.EndedAt
+		}
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		for periodStart, hours := range period.SplitHours(start, end, loc) {
+			bucket(periodStart).OnlineHours += hours
+		}
+	}
+
+	// Rides accepted, and of those the ones the driver cancelled
+	var accepted []models.EarningsBucket
+	if err := r.db.Model(&models.Ride{}).
+		Select(`date_trunc(?, accepted_at, ?) AS period_start, COUNT(*) AS accepted,
+			COUNT(*) FILTER (WHERE status = ? AND cancelled_by_id = driver_id) AS cancelled`,
+			period, loc.String(), models.RideStatusCancelled).
+		Where("driver_id = ? AND accepted_at >= ? AND accepted_at < ?", driverID, from, to).
+		Group("period_start").
+		Scan(&accepted).Error; err != nil {
+		return nil, err
+	}
+	for _, a := range accepted {
+		b := bucket(a.PeriodStart)
+		b.Accepted, b.Cancelled = a.Accepted, a.Cancelled
+	}
+
+	var declined []models.EarningsBucket
+	if err := r.db.Model(&models.RideDecline{}).
+		Select("date_trunc(?, created_at, ?) AS period_start, COUNT(*) AS declined", period, loc.String()).
+		Where("driver_id = ? AND created_at >= ? AND created_at < ?", driverID, from, to).
+		Group("period_start").
+		Scan(&declined).Error; err != nil {
+		return nil, err
+	}
+	for _, d := range declined {
+		bucket(d.PeriodStart).Declined = d.Declined
+	}
+
+	report := &models.EarningsReport{
+		DriverID: driverID,
+		Period:   period,
+		TimeZone: loc.String(),
+		From:     from,
+		To:       to,
+		Buckets:  make([]models.EarningsBucket, 0, len(buckets)),
+	}
+	for _, b := range buckets {
+		b.Finish()
+		report.Buckets = append(report.Buckets, *b)
+		report.Totals.Add(*b)
+	}
+	sort.Slice(report.Buckets, func(i, j int) bool {
+		return report.Buckets[i].PeriodStart.Before(report.Buckets[j].PeriodStart)
+	})
+	report.Totals.PeriodStart = from
+	report.Totals.Finish()
+	return report, nil
+}
+
+// GetActiveDestination retrieves the destination of a driver in destination mode
+func (r *DriverRepository) GetActiveDestination(driverID uint) (*models.DriverDestination, error) {
+	var dest models.DriverDestination
+	if err := r.db.Where("driver_id = ? AND deactivated_at IS NULL", driverID).First(&dest).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("destination mode is off")
+		}
+		return nil, err
+	}
+	return &dest, nil
+}
+
+// CountDestinationUses counts how many times a driver turned on destination mode since the given time
+func (r *DriverRepository) CountDestinationUses(driverID uint, since time.Time) (int64, error) {
+	var count int64
+	err := r.db.Model(&models.DriverDestination{}).
+		Where("driver_id = ? AND activated_at >= ?", driverID, since).
+		Count(&count).Error
+	return count, err
+}
+
+// SetDestination turns on destination mode, replacing any destination already set
+func (r *DriverRepository) SetDestination(dest *models.DriverDestination) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		if err := tx.Model(&models.DriverDestination{}).
+			Where("driver_id = ? AND deactivated_at IS NULL", dest.DriverID).
+			Update("deactivated_at", now).Error; err != nil {
+			return err
+		}
+		dest.ActivatedAt = now
+		return tx.Create(dest).Error
+	})
+}
+
+// ClearDestination turns off destination mode
+func (r *DriverRepository) ClearDestination(driverID uint) error {
+	return r.db.Model(&models.DriverDestination{}).
+		Where("driver_id = ? AND deactivated_at IS NULL", driverID).
+		Update("deactivated_at", time.Now()).Error
+}
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+)
+
+type HeatmapRepository struct {
+	db *gorm.DB
+}
+
+func NewHeatmapRepository() *HeatmapRepository {
+	return &HeatmapRepository{
+		db: database.GetDB(),
+	}
+}
+
+// Pickup is where and when a ride was requested
+type Pickup struct {
+	PickupLat float64
+	PickupLng float64
+	CreatedAt time.Time
+}
+
+// GetPickupsSince retrieves the pickup points of on-demand rides requested since the given time
+func (r *HeatmapRepository) GetPickupsSince(since time.Time) ([]Pickup, error) {
+	var pickups []Pickup
+	if err := r.db.Model(&models.Ride{}).
+		Select("pickup_lat, pickup_lng, created_at").
+		Where("ride_type = ? AND created_at >= ?", models.RideTypeOnDemand, since).
+		Scan(&pickups).Error; err != nil {
+		return nil, err
+	}
+	return pickups, nil
+}
+
+// ReplaceDemandCells replaces the whole heatmap with the given cells
+func (r *HeatmapRepository) ReplaceDemandCells(cells []models.DemandCell) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("1 = 1").Delete(&models.DemandCell{}).Error; err != nil {
+			return err
+		}
+		if len(cells) == 0 {
+			return nil
+		}
+		return tx.CreateInBatches(cells, 500).Error
+	})
+}
+
+// GetDemandCells retrieves up to limit heatmap cells whose centers fall within the bounding box, busiest first
+func (r *HeatmapRepository) GetDemandCells(minLat, minLng, maxLat, maxLng float64, limit int) ([]models.DemandCell, error) {
+	var cells []models.DemandCell
+	if err := r.db.Where("lat BETWEEN ? AND ? AND lng BETWEEN ? AND ?", minLat, maxLat, minLng, maxLng).
+		Order("intensity DESC").
+		Limit(limit).
+		Find(&cells).Error; err != nil {
+		return nil, err
+	}
+	return cells, nil
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HoldRepository struct {
+	db *gorm.DB
+}
+
+func NewHoldRepository() *HoldRepository {
+	return &HoldRepository{
+		db: database.GetDB(),
+	}
+}
+
+// CreateHold reserves seats on a shared ride for a passenger. A passenger can hold seats on a ride once at
+// a time.
+func (r *HoldRepository) CreateHold(hold *models.SeatHold, passenger *models.RidePassenger) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Booking the seats locks the ride, so the same passenger cannot hold seats twice concurrently
+		if _, err := bookSeats(tx, passenger); err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.SeatHold{}).
+			Where("ride_id = ? AND user_id = ? AND status = ? AND expires_at > ?",
+				hold.RideID, hold.UserID, models.SeatHoldActive, time.Now()).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return models.ErrSeatsAlreadyHeld
+		}
+
+		return tx.Create(hold).Error
+	})
+}
+
+// GetHoldByID retrieves a seat hold
+func (r *HoldRepository) GetHoldByID(id uint) (*models.SeatHold, error) {
+	var hold models.SeatHold
+	if err := r.db.First(&hold, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("seat hold not found")
+		}
+		return nil, err
+	}
+	return &hold, nil
+}
+
+// GetActiveHoldsByUserID retrieves the seat holds of a user that have not ended yet
+func (r *HoldRepository) GetActiveHoldsByUserID(userID uint) ([]models.SeatHold, error) {
+	var holds []models.SeatHold
+	if err := r.db.Where("user_id = ? AND status = ?", userID, models.SeatHoldActive).Find(&holds).Error; err != nil {
+		return nil, err
+	}
+	return holds, nil
+}
+
+// ConfirmHold turns the seats held into a booking on the ride. Holds that expired, even if not yet swept,
+// cannot be confirmed. Holds on rides no longer open expire straight away, releasing their seats.
+func (r *HoldRepository) ConfirmHold(hold *models.SeatHold, passenger *models.RidePassenger, paymentReference string) error {
+	rideClosed := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current models.SeatHold
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, hold.ID).Error; err != nil {
+			return err
+		}
+		if !current.IsActive(time.Now()) {
+			return models.ErrHoldNotActive
+		}
+
+		// The seats are already booked on the ride
+		var ride models.Ride
+		if err := tx.First(&ride, current.RideID).Error; err != nil {
+			return err
+		}
+		if ride.Status != models.RideStatusPending {
+			if err := tx.Model(&current).Update("status", models.SeatHoldExpired).Error; err != nil {
+				return err
+			}
+			hold.Status = models.SeatHoldExpired
+			rideClosed = true
+			return releaseSeats(tx, current.RideID, current.Seats)
+		}
+		if err := tx.Create(passenger).Error; err != nil {
+			return err
+		}
+		if err := addStops(tx, &ride, passenger); err != nil {
+			return err
+		}
+
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"status":            models.SeatHoldConfirmed,
+			"passenger_id":      passenger.ID,
+			"payment_reference": paymentReference,
+		}).Error; err != nil {
+			return err
+		}
+		hold.Status = models.SeatHoldConfirmed
+		hold.PassengerID = &passenger.ID
+		hold.PaymentReference = paymentReference
+		return nil
+	})
+	if err == nil && rideClosed {
+		return models.ErrHoldNotActive
+	}
+	return err
+}
+
+// ReleaseHold gives up the seats held, returning them to the ride
+func (r *HoldRepository) ReleaseHold(hold *models.SeatHold) error {
+	return r.endHold(hold, models.SeatHoldReleased)
+}
+
+// endHold ends an active hold with the given status and releases its seats
+func (r *HoldRepository) endHold(hold *models.SeatHold, status models.SeatHoldStatus) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.SeatHold{}).
+			Where("id = ? AND status = ?", hold.ID, models.SeatHoldActive).
+			Update("status", status)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrHoldNotActive
+		}
+		if err := releaseSeats(tx, hold.RideID, hold.Seats); err != nil {
+			return err
+		}
+		hold.Status = status
+		return nil
+	})
+}
+
+// ExpireHolds expires the holds not confirmed in time, releasing their seats. Returns the expired holds.
+func (r *HoldRepository) ExpireHolds(now time.Time) ([]models.SeatHold, error) {
+	var due []models.SeatHold
+	if err := r.db.Where("status = ? AND expires_at <= ?", models.SeatHoldActive, now).Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	var expired []models.SeatHold
+	for _, hold := range due {
+		// The passenger may have confirmed or released the hold since it was loaded
+		if err := r.endHold(&hold, models.SeatHoldExpired); err != nil {
+			if errors.Is(err, models.ErrHoldNotActive) {
+				continue
+			}
+			return expired, err
+		}
+		expired = append(expired, hold)
+	}
+	return expired, nil
+}
@@ -0,0 +1,90 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdentityRepository struct {
+	db *gorm.DB
+}
+
+func NewIdentityRepository() *IdentityRepository {
+	return &IdentityRepository{
+		db: database.GetDB(),
+	}
+}
+
+// GetIdentity retrieves a linked identity by provider and subject
+func (r *IdentityRepository) GetIdentity(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.Where("provider = ? AND subject = ?", provider, subject).First(&identity).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("identity not found")
+		}
+		return nil, err
+	}
+	return &identity, nil
+}
+
+// GetIdentitiesByUserID retrieves all identities linked to a user
+func (r *IdentityRepository) GetIdentitiesByUserID(userID uint) ([]models.UserIdentity, error) {
+	var identities []models.UserIdentity
+	if err := r.db.Where("user_id = ?", userID).Find(&identities).Error; err != nil {
+		return nil, err
+	}
+	return identities, nil
+}
+
+// LinkIdentity links an external identity to an existing user
+func (r *IdentityRepository) LinkIdentity(identity *models.UserIdentity) error {
+	return r.db.Create(identity).Error
+}
+
+// CreateUserWithIdentity creates a new user and links the identity in a single transaction
+func (r *IdentityRepository) CreateUserWithIdentity(user *models.User, identity *models.UserIdentity) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		identity.UserID = user.ID
+		return tx.Create(identity).Error
+	})
+}
+
+// UnlinkIdentity removes a linked identity from a user. The last identity of an account that never set a
+// password is kept, since the user could not sign in without it.
+func (r *IdentityRepository) UnlinkIdentity(userID uint, provider string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&user, userID).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return errors.New("identity not found")
+			}
+			return err
+		}
+
+		result := tx.Where("user_id = ? AND provider = ?", userID, provider).Delete(&models.UserIdentity{})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return errors.New("identity not found")
+		}
+
+		if user.RandomPassword {
+			var remaining int64
+			if err := tx.Model(&models.UserIdentity{}).Where("user_id = ?", userID).Count(&remaining).Error; err != nil {
+				return err
+			}
+			if remaining == 0 {
+				return models.ErrLastSignInMethod
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+)
+
+type NotificationRepository struct {
+	db *gorm.DB
+}
+
+func NewNotificationRepository() *NotificationRepository {
+	return &NotificationRepository{
+		db: database.GetDB(),
+	}
+}
+
+// CreateNotification adds a notification to a user's inbox
+func (r *NotificationRepository) CreateNotification(notification *models.Notification) error {
+	return r.db.Create(notification).Error
+}
+
+// GetNotificationsByUserID retrieves a user's most recent notifications, newest first
+func (r *NotificationRepository) GetNotificationsByUserID(userID uint, unreadOnly bool, limit int) ([]models.Notification, error) {
+	var notifications []models.Notification
+	query := r.db.Where("user_id = ?", userID)
+	if unreadOnly {
+		query = query.Where("read_at IS NULL")
+	}
+	if err := query.Order("created_at DESC, id DESC").Limit(limit).Find(&notifications).Error; err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+// MarkNotificationRead marks one of the user's notifications as read
+func (r *NotificationRepository) MarkNotificationRead(id, userID uint) error {
+	result := r.db.Model(&models.Notification{}).
+		Where("id = ? AND user_id = ? AND read_at IS NULL", id, userID).
+		Update("read_at", time.Now())
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := r.db.Model(&models.Notification{}).Where("id = ? AND user_id = ?", id, userID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count == 0 {
+			return errors.New("notification not found")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,171 @@
+package repository
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type OrganizationRepository struct {
+	db *gorm.DB
+}
+
+func NewOrganizationRepository() *OrganizationRepository {
+	return &OrganizationRepository{
+		db: database.GetDB(),
+	}
+}
+
+// CreateOrganization creates a new organization in the database
+func (r *OrganizationRepository) CreateOrganization(org *models.Organization) error {
+	org.EmailDomain = strings.ToLower(org.EmailDomain)
+	return r.db.Create(org).Error
+}
+
+// GetOrganizationByID retrieves an organization by ID
+func (r *OrganizationRepository) GetOrganizationByID(id uint) (*models.Organization, error) {
+	var org models.Organization
+	if err := r.db.First(&org, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("organization not found")
+		}
+		return nil, err
+	}
+	return &org, nil
+}
+
+// GetOrganizations retrieves all organizations
+func (r *OrganizationRepository) GetOrganizations() ([]models.Organization, error) {
+	var orgs []models.Organization
+	if err := r.db.Order("name").Find(&orgs).Error; err != nil {
+		return nil, err
+	}
+	return orgs, nil
+}
+
+// GetMember retrieves a user's membership in an organization
+func (r *OrganizationRepository) GetMember(orgID, userID uint) (*models.OrganizationMember, error) {
+	var member models.OrganizationMember
+	if err := r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).First(&member).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("membership not found")
+		}
+		return nil, err
+	}
+	return &member, nil
+}
+
+// SaveMember creates or updates a membership
+func (r *OrganizationRepository) SaveMember(member *models.OrganizationMember) error {
+	return r.db.Save(member).Error
+}
+
+// SetVerificationCode stores the hash of a new verification code for a membership, replacing any earlier code
+// and its failed attempts
+func (r *OrganizationRepository) SetVerificationCode(member *models.OrganizationMember, code string) error {
+	hashedCode, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	expiresAt := now.Add(models.VerificationCodeLifetime)
+	member.VerificationCode = string(hashedCode)
+	member.CodeSentAt = &now
+	member.CodeExpiresAt = &expiresAt
+	member.CodeAttempts = 0
+	return r.db.Save(member).Error
+}
+
+// VerifyMember marks a membership as verified
+func (r *OrganizationRepository) VerifyMember(member *models.OrganizationMember) error {
+	now := time.Now()
+	member.VerifiedAt = &now
+	member.VerificationCode = ""
+	return r.db.Model(member).Updates(map[string]interface{}{
+		"verified_at":       now,
+		"verification_code": "",
+		"code_expires_at":   nil,
+	}).Error
+}
+
+// VerifyMemberCode verifies a membership with the code sent for it. Wrong codes count as attempts; once
+// MaxVerificationAttempts have failed, or the code has expired, a new code must be requested.
+func (r *OrganizationRepository) VerifyMemberCode(member *models.OrganizationMember, code string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the membership so concurrent guesses are all counted
+		var current models.OrganizationMember
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, member.ID).Error; err != nil {
+			return err
+		}
+		if current.VerificationCode == "" {
+			return models.ErrInvalidVerificationCode
+		}
+		if current.CodeAttempts >= models.MaxVerificationAttempts {
+			return models.ErrTooManyVerificationAttempts
+		}
+		if current.CodeExpiresAt == nil || time.Now().After(*current.CodeExpiresAt) {
+			return models.ErrVerificationCodeExpired
+		}
+
+		if bcrypt.CompareHashAndPassword([]byte(current.VerificationCode), []byte(code)) != nil {
+			if err := tx.Model(&current).Update("code_attempts", gorm.Expr("code_attempts + 1")).Error; err != nil {
+				return err
+			}
+			return models.ErrInvalidVerificationCode
+		}
+
+		now := time.Now()
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"verified_at":       now,
+			"verification_code": "",
+			"code_expires_at":   nil,
+		}).Error; err != nil {
+			return err
+		}
+		member.VerifiedAt = &now
+		member.VerificationCode = ""
+		return nil
+	})
+}
+
+// RemoveMember removes a user from an organization
+func (r *OrganizationRepository) RemoveMember(orgID, userID uint) error {
+	return r.db.Where("organization_id = ? AND user_id = ?", orgID, userID).Delete(&models.OrganizationMember{}).Error
+}
+
+// GetMembershipsByUserID retrieves all memberships of a user
+func (r *OrganizationRepository) GetMembershipsByUserID(userID uint) ([]models.OrganizationMember, error) {
+	var members []models.OrganizationMember
+	if err := r.db.Where("user_id = ?", userID).Preload("Organization").Find(&members).Error; err != nil {
+		return nil, err
+	}
+	return members, nil
+}
+
+// GetVerifiedOrganizationIDs retrieves the IDs of organizations the user is a verified member of
+func (r *OrganizationRepository) GetVerifiedOrganizationIDs(userID uint) ([]uint, error) {
+	var ids []uint
+	if err := r.db.Model(&models.OrganizationMember{}).
+		Where("user_id = ? AND verified_at IS NOT NULL", userID).
+		Pluck("organization_id", &ids).Error; err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+// IsVerifiedMember checks if the user is a verified member of the organization
+func (r *OrganizationRepository) IsVerifiedMember(orgID, userID uint) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.OrganizationMember{}).
+		Where("organization_id = ? AND user_id = ? AND verified_at IS NOT NULL", orgID, userID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
@@ -0,0 +1,282 @@
+package repository
+
+import (
+	"errors"
+	"math"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PoolRepository struct {
+	db *gorm.DB
+}
+
+func NewPoolRepository() *PoolRepository {
+	return &PoolRepository{
+		db: database.GetDB(),
+	}
+}
+
+// maxPoolCandidates limits the open pools a new pooled ride is matched against
+const maxPoolCandidates = 100
+
+// poolLegs returns the trips of the riders of pooled rides, each allowed the detour in minutes turned into
+// distance at the ride's speed
+func poolLegs(rides []models.Ride, maxDetourMinutes float64) []utils.Leg {
+	legs := make([]utils.Leg, len(rides))
+	for i := range rides {
+		legs[i] = utils.Leg{
+			Pickup:     utils.LatLng{Lat: rides[i].PickupLat, Lng: rides[i].PickupLng},
+			Dropoff:    utils.LatLng{Lat: rides[i].DropoffLat, Lng: rides[i].DropoffLng},
+			MaxExtraKm: maxDetourMinutes / 60 * rideSpeedKmh(&rides[i]) / roadFactor,
+		}
+	}
+	return legs
+}
+
+// openPoolRides retrieves the rides of pools still waiting for a driver, with their riders
+func openPoolRides(db *gorm.DB, poolIDs []uint) ([]models.Ride, error) {
+	var rides []models.Ride
+	if err := db.Where("pool_id IN ? AND status = ? AND driver_id IS NULL", poolIDs, models.RideStatusPending).
+		Order("created_at").
+		Preload("Rider").
+		Find(&rides).Error; err != nil {
+		return nil, err
+	}
+	return rides, nil
+}
+
+// CreatePooledRide saves a pooled on-demand ride and matches it into a pool in one transaction, so a ride
+// is never left with a pooled fare but no pool. The ride's rider must be loaded.
+func (r *PoolRepository) CreatePooledRide(ride *models.Ride, maxRiders int, maxDetourMinutes float64) (*models.RidePool, error) {
+	var pool *models.RidePool
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ride).Error; err != nil {
+			return err
+		}
+		var err error
+		pool, err = joinPool(tx, ride, maxRiders, maxDetourMinutes)
+		return err
+	})
+	if err != nil {
+		ride.ID, ride.PoolID = 0, nil
+		return nil, err
+	}
+	return pool, nil
+}
+
+// joinPool matches a pooled on-demand ride into the open pool of its tier where the planned trip grows the
+// least, and plans the pool's stops again. A pool fits when it has fewer than maxRiders, its riders and the
+// new one suit each other, and the stops can be ordered so that no rider's trip takes more than
+// maxDetourMinutes longer than going straight. Starts a new pool when none fits. The ride's rider must be
+// loaded.
+func joinPool(tx *gorm.DB, ride *models.Ride, maxRiders int, maxDetourMinutes float64) (*models.RidePool, error) {
+	// Requests of a tier are matched one at a time, including against pools created concurrently
+	if err := tx.Exec("SELECT pg_advisory_xact_lock(hashtext(?))", "ride_pool:"+string(ride.Tier)).Error; err != nil {
+		return nil, err
+	}
+
+	// The ride may have been cancelled or accepted on its own while waiting for the lock
+	var current models.Ride
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, ride.ID).Error; err != nil {
+		return nil, err
+	}
+	if current.Status != models.RideStatusPending || current.DriverID != nil || current.PoolID != nil {
+		return nil, models.ErrPoolUnavailable
+	}
+
+	// Lock the open pools of the tier so drivers cannot be assigned while they change
+	var pools []models.RidePool
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("tier = ? AND driver_id IS NULL", ride.Tier).
+		Where("id IN (?)", tx.Model(&models.Ride{}).Select("pool_id").
+			Where("status = ? AND driver_id IS NULL AND pool_id IS NOT NULL", models.RideStatusPending)).
+		Order("created_at DESC").
+		Limit(maxPoolCandidates).
+		Find(&pools).Error; err != nil {
+		return nil, err
+	}
+
+	trip := []models.Ride{*ride}
+	order, distanceKm, _ := utils.SequenceLegs(poolLegs(trip, maxDetourMinutes))
+	pool := models.RidePool{Tier: ride.Tier}
+	if len(pools) > 0 {
+		ids := make([]uint, len(pools))
+		for i, p := range pools {
+			ids[i] = p.ID
+		}
+		rides, err := openPoolRides(tx, ids)
+		if err != nil {
+			return nil, err
+		}
+		members := make(map[uint][]models.Ride)
+		for _, member := range rides {
+			members[*member.PoolID] = append(members[*member.PoolID], member)
+		}
+
+		bestIncrease := math.Inf(1)
+		for _, candidate := range pools {
+			riders := members[candidate.ID]
+			if len(riders) == 0 || len(riders) >= maxRiders {
+				continue
+			}
+			compatible := true
+			for i := range riders {
+				if !ride.PoolsWith(&riders[i]) {
+					compatible = false
+					break
+				}
+			}
+			if !compatible {
+				continue
+			}
+
+			candidateTrip := append(append([]models.Ride{}, riders...), *ride)
+			candidateOrder, candidateKm, ok := utils.SequenceLegs(poolLegs(candidateTrip, maxDetourMinutes))
+			if !ok || candidateKm-candidate.DistanceKm >= bestIncrease {
+				continue
+			}
+			bestIncrease = candidateKm - candidate.DistanceKm
+			pool, trip, order, distanceKm = candidate, candidateTrip, candidateOrder, candidateKm
+		}
+	}
+
+	pool.DistanceKm = distanceKm
+	if err := tx.Save(&pool).Error; err != nil {
+		return nil, err
+	}
+	if err := tx.Model(&models.Ride{}).Where("id = ?", ride.ID).Update("pool_id", pool.ID).Error; err != nil {
+		return nil, err
+	}
+	ride.PoolID = &pool.ID
+
+	// The pool's stops are planned again in the new order
+	if err := tx.Where("pool_id = ?", pool.ID).Delete(&models.RideStop{}).Error; err != nil {
+		return nil, err
+	}
+	stops := make([]models.RideStop, len(order))
+	for sequence, stop := range order {
+		member := trip[stop/2]
+		member.PoolID = &pool.ID
+		pickup, dropoff := member.PoolStops()
+		stops[sequence] = pickup
+		if stop%2 == 1 {
+			stops[sequence] = dropoff
+		}
+		stops[sequence].Sequence = sequence + 1
+	}
+	if err := tx.Create(&stops).Error; err != nil {
+		return nil, err
+	}
+	pool.Stops = stops
+	return &pool, nil
+}
+
+// GetPoolByID retrieves a pool with its rides still waiting for a driver and their riders
+func (r *PoolRepository) GetPoolByID(id uint) (*models.RidePool, error) {
+	var pool models.RidePool
+	if err := r.db.First(&pool, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("pool not found")
+		}
+		return nil, err
+	}
+	rides, err := openPoolRides(r.db, []uint{pool.ID})
+	if err != nil {
+		return nil, err
+	}
+	pool.Rides = rides
+	return &pool, nil
+}
+
+// AssignDriver assigns a driver and their vehicle to every ride of an open pool and marks them accepted,
+// closing the pool to new riders. The vehicle must have a seat for every rider, and the driver must suit
+// every rider's preferences. Returns the accepted rides.
+func (r *PoolRepository) AssignDriver(poolID uint, driver *models.User, vehicle *models.Vehicle) ([]models.Ride, error) {
+	var rides []models.Ride
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var pool models.RidePool
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pool, poolID).Error; err != nil {
+			return err
+		}
+		if !pool.IsOpen() {
+			return models.ErrPoolUnavailable
+		}
+
+		var err error
+		rides, err = openPoolRides(tx, []uint{pool.ID})
+		if err != nil {
+			return err
+		}
+		if len(rides) == 0 {
+			return models.ErrPoolUnavailable
+		}
+		if len(rides) > vehicle.SeatCapacity {
+			return models.ErrPoolTooLarge
+		}
+		for _, ride := range rides {
+			if len(ride.Preferences.Conflicts(ride.Rider.Gender, driver.PreferenceOffer())) > 0 {
+				return models.ErrPoolUnavailable
+			}
+		}
+
+		if err := tx.Model(&pool).Update("driver_id", driver.ID).Error; err != nil {
+			return err
+		}
+		ids := make([]uint, len(rides))
+		for i, ride := range rides {
+			ids[i] = ride.ID
+		}
+		return tx.Model(&models.Ride{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"driver_id":   driver.ID,
+			"vehicle_id":  vehicle.ID,
+			"status":      models.RideStatusAccepted,
+			"accepted_at": time.Now(),
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rides, nil
+}
+
+// LeavePool takes the stops not yet made of a cancelled ride out of its pool, keeping the order of the
+// remaining stops
+func (r *PoolRepository) LeavePool(ride *models.Ride) error {
+	if ride.PoolID == nil {
+		return nil
+	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var pool models.RidePool
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&pool, *ride.PoolID).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("pool_id = ? AND ride_id = ? AND checked_in_at IS NULL", pool.ID, ride.ID).
+			Delete(&models.RideStop{}).Error; err != nil {
+			return err
+		}
+
+		var stops []models.RideStop
+		if err := tx.Where("pool_id = ?", pool.ID).Order("sequence, id").Find(&stops).Error; err != nil {
+			return err
+		}
+		distanceKm := 0.0
+		for i, stop := range stops {
+			if i > 0 {
+				distanceKm += utils.DistanceKm(stops[i-1].Lat, stops[i-1].Lng, stop.Lat, stop.Lng)
+			}
+			if stop.Sequence == i+1 {
+				continue
+			}
+			if err := tx.Model(&models.RideStop{}).Where("id = ?", stop.ID).Update("sequence", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&pool).Update("distance_km", distanceKm).Error
+	})
+}
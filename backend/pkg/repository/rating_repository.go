@@ -0,0 +1,231 @@
+package repository
+
+import (
+	"errors"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RatingRepository struct {
+	db *gorm.DB
+}
+
+func NewRatingRepository() *RatingRepository {
+	return &RatingRepository{
+		db: database.GetDB(),
+	}
+}
+
+// ratingBucket is the number of ratings with a given star value received in a given capacity
+type ratingBucket struct {
+	ToUserRole models.UserRole
+	Rating     int
+	Count      int64
+}
+
+// GetRatingSummary summarizes the ratings a user received, optionally only those from the last windowDays days
+func (r *RatingRepository) GetRatingSummary(userID uint, windowDays int) (*models.RatingSummary, error) {
+	buckets, err := getRatingBuckets(r.db, userID, windowDays)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := summarizeRatings(buckets)
+	summary.UserID = userID
+	summary.WindowDays = windowDays
+
+	tags, err := getTagCounts(r.db, userID, windowDays)
+	if err != nil {
+		return nil, err
+	}
+	summary.Tags = tags
+	return summary, nil
+}
+
+// GetRatingByID retrieves a rating by ID
+func (r *RatingRepository) GetRatingByID(id uint) (*models.Rating, error) {
+	var rating models.Rating
+	if err := r.db.Preload("Tags").First(&rating, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("rating not found")
+		}
+		return nil, err
+	}
+	return &rating, nil
+}
+
+// GetRatingsPendingReview retrieves ratings whose comments were flagged, oldest first
+func (r *RatingRepository) GetRatingsPendingReview() ([]models.Rating, error) {
+	var ratings []models.Rating
+	if err := r.db.Where("moderation_status = ?", models.ModerationPendingReview).
+		Order("created_at").
+		Preload("Tags").
+		Preload("FromUser").
+		Preload("ToUser").
+		Find(&ratings).Error; err != nil {
+		return nil, err
+	}
+	return ratings, nil
+}
+
+// ReviewRating records a reviewer's decision on a flagged rating. Rejecting removes the comment
+// but keeps the star rating and tags.
+func (r *RatingRepository) ReviewRating(rating *models.Rating, reviewerID uint, approve bool) error {
+	now := time.Now()
+	rating.ReviewedByID = &reviewerID
+	rating.ReviewedAt = &now
+	if approve {
+		rating.ModerationStatus = models.ModerationApproved
+	} else {
+		rating.ModerationStatus = models.ModerationRejected
+		rating.Comment = ""
+	}
+
+	return r.db.Model(rating).Updates(map[string]interface{}{
+		"moderation_status": rating.ModerationStatus,
+		"comment":           rating.Comment,
+		"reviewed_by_id":    reviewerID,
+		"reviewed_at":       now,
+	}).Error
+}
+
+// RefreshUserRatings recalculates the rating aggregates of every user who has been rated, so that ratings
+// leaving the rolling window stop counting even when no new rating arrives
+func (r *RatingRepository) RefreshUserRatings() error {
+	var userIDs []uint
+	if err := r.db.Model(&models.Rating{}).Distinct("to_user_id").Pluck("to_user_id", &userIDs).Error; err != nil {
+		return err
+	}
+	for _, userID := range userIDs {
+		if err := r.db.Transaction(func(tx *gorm.DB) error {
+			return updateUserRatings(tx, userID)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updateUserRatings recalculates the rating aggregates stored on a user. It must run in a transaction, such
+// as the one that inserted a rating; the user row is locked so concurrent updates serialize.
+func updateUserRatings(tx *gorm.DB, userID uint) error {
+	var user models.User
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&user, userID).Error; err != nil {
+		return err
+	}
+
+	buckets, err := getRatingBuckets(tx, userID, ratingWindowDays())
+	if err != nil {
+		return err
+	}
+	summary := summarizeRatings(buckets)
+
+	return tx.Model(&user).Updates(map[string]interface{}{
+		"rating":              summary.Overall.Rating,
+		"rating_count":        summary.Overall.Count,
+		"rider_rating":        summary.AsRider.Rating,
+		"rider_rating_count":  summary.AsRider.Count,
+		"driver_rating":       summary.AsDriver.Rating,
+		"driver_rating_count": summary.AsDriver.Count,
+	}).Error
+}
+
+// ratingWindowDays returns the rolling window used for stored ratings; 0 counts all ratings
+func ratingWindowDays() int {
+	days, err := strconv.Atoi(os.Getenv("RATING_WINDOW_DAYS"))
+	if err != nil || days < 0 {
+		return 0
+	}
+	return days
+}
+
+func getRatingBuckets(db *gorm.DB, userID uint, windowDays int) ([]ratingBucket, error) {
+	query := db.Model(&models.Rating{}).
+		Select("to_user_role, rating, COUNT(*) AS count").
+		Where("to_user_id = ?", userID).
+		Group("to_user_role, rating")
+	if windowDays > 0 {
+		query = query.Where("created_at >= ?", time.Now().AddDate(0, 0, -windowDays))
+	}
+
+	var buckets []ratingBucket
+	if err := query.Scan(&buckets).Error; err != nil {
+		return nil, err
+	}
+	return buckets, nil
+}
+
+func getTagCounts(db *gorm.DB, userID uint, windowDays int) (map[models.FeedbackTag]int64, error) {
+	query := db.Model(&models.RatingTag{}).
+		Select("rating_tags.tag, COUNT(*) AS count").
+		Joins("JOIN ratings ON ratings.id = rating_tags.rating_id").
+		Where("ratings.to_user_id = ?", userID).
+		Group("rating_tags.tag")
+	if windowDays > 0 {
+		query = query.Where("ratings.created_at >= ?", time.Now().AddDate(0, 0, -windowDays))
+	}
+
+	var rows []struct {
+		Tag   models.FeedbackTag
+		Count int64
+	}
+	if err := query.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	tags := make(map[models.FeedbackTag]int64)
+	for _, row := range rows {
+		tags[row.Tag] = row.Count
+	}
+	return tags, nil
+}
+
+func summarizeRatings(buckets []ratingBucket) *models.RatingSummary {
+	summary := &models.RatingSummary{
+		Overall:  newRoleRatingSummary(),
+		AsRider:  newRoleRatingSummary(),
+		AsDriver: newRoleRatingSummary(),
+	}
+
+	var sum, riderSum, driverSum int64
+	for _, b := range buckets {
+		summary.Overall.Distribution[b.Rating] += b.Count
+		summary.Overall.Count += b.Count
+		sum += int64(b.Rating) * b.Count
+
+		switch b.ToUserRole {
+		case models.RoleRider:
+			summary.AsRider.Distribution[b.Rating] += b.Count
+			summary.AsRider.Count += b.Count
+			riderSum += int64(b.Rating) * b.Count
+		case models.RoleDriver:
+			summary.AsDriver.Distribution[b.Rating] += b.Count
+			summary.AsDriver.Count += b.Count
+			driverSum += int64(b.Rating) * b.Count
+		}
+	}
+
+	finishRoleRatingSummary(&summary.Overall, sum)
+	finishRoleRatingSummary(&summary.AsRider, riderSum)
+	finishRoleRatingSummary(&summary.AsDriver, driverSum)
+	return summary
+}
+
+func newRoleRatingSummary() models.RoleRatingSummary {
+	return models.RoleRatingSummary{
+		Distribution: map[int]int64{1: 0, 2: 0, 3: 0, 4: 0, 5: 0},
+	}
+}
+
+func finishRoleRatingSummary(s *models.RoleRatingSummary, sum int64) {
+	s.Rating = models.BayesianRating(sum, s.Count)
+	if s.Count > 0 {
+		s.Average = float64(sum) / float64(s.Count)
+	}
+}
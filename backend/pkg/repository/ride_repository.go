@@ -2,11 +2,20 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/rakeshkumar/ridesapp/pkg/database"
 	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type RideRepository struct {
@@ -19,15 +28,34 @@ func NewRideRepository() *RideRepository {
 	}
 }
 
-// CreateRide creates a new ride in the database
+// CreateRide creates a new ride in the database. Shared rides cannot offer more seats than their vehicle has.
 func (r *RideRepository) CreateRide(ride *models.Ride) error {
-	return r.db.Create(ride).Error
+	if ride.RideType != models.RideTypeShared || ride.VehicleID == nil {
+		return r.db.Create(ride).Error
+	}
+
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Share-lock the vehicle so its capacity cannot change until the ride is saved
+		var vehicle models.Vehicle
+		if err := tx.Clauses(clause.Locking{Strength: "SHARE"}).First(&vehicle, *ride.VehicleID).Error; err != nil {
+			return err
+		}
+		if ride.SeatsAvailable > vehicle.SeatCapacity {
+			return models.ErrVehicleOverbooked
+		}
+		return tx.Create(ride).Error
+	})
+}
+
+// withRemoved includes soft-deleted records, so rides keep showing vehicles that were removed since
+func withRemoved(db *gorm.DB) *gorm.DB {
+	return db.Unscoped()
 }
 
 // GetRideByID retrieves a ride by ID
 func (r *RideRepository) GetRideByID(id uint) (*models.Ride, error) {
 	var ride models.Ride
-	if err := r.db.Preload("Rider").Preload("Driver").Preload("Passengers.User").First(&ride, id).Error; err != nil {
+	if err := r.db.Preload("Rider").Preload("Driver").Preload("Vehicle", withRemoved).Preload("Passengers.User").First(&ride, id).Error; err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return nil, errors.New("ride not found")
 		}
@@ -54,108 +82,661 @@ func (r *RideRepository) GetRidesByDriverID(driverID uint) ([]models.Ride, error
 	return rides, nil
 }
 
-// GetAvailableSharedRides retrieves all available shared rides
-func (r *RideRepository) GetAvailableSharedRides() ([]models.Ride, error) {
+// visibleToUser limits shared rides to public rides and rides of organizations the user is a verified member of
+func visibleToUser(userID uint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where("(organization_id IS NULL OR organization_id IN (?))",
+			db.Session(&gorm.Session{NewDB: true}).Model(&models.OrganizationMember{}).
+				Select("organization_id").
+				Where("user_id = ? AND verified_at IS NOT NULL", userID))
+	}
+}
+
+// GetRidesByUserID retrieves all rides the user took part in as rider, driver, shared ride owner or passenger
+// with seats booked, marking the capacity on each. Declined, expired and cancelled join requests are left out,
+// as they give no access to the ride's passengers.
+func (r *RideRepository) GetRidesByUserID(userID uint) ([]models.Ride, error) {
+	var rides []models.Ride
+	if err := r.db.Where("rider_id = ? OR driver_id = ? OR id IN (?)", userID, userID,
+		r.db.Model(&models.RidePassenger{}).Select("ride_id").
+			Where("user_id = ? AND status IN ?", userID, seatHoldingStatuses)).
+		Order("created_at DESC").
+		Preload("Rider").Preload("Driver").Preload("Vehicle", withRemoved).Preload("Passengers.User").
+		Find(&rides).Error; err != nil {
+		return nil, err
+	}
+
+	actor := models.RideActor{UserID: userID}
+	for i := range rides {
+		relations := rides[i].RelationsTo(actor)
+		if relations[models.RideRelationDriver] || relations[models.RideRelationOwner] {
+			rides[i].UserRole = models.RoleDriver
+		} else {
+			rides[i].UserRole = models.RoleRider
+		}
+	}
+	return rides, nil
+}
+
+// GetOpenRidesByUserID retrieves the rides the user requested, offers or was assigned to drive that have not
+// started yet, with their passengers
+func (r *RideRepository) GetOpenRidesByUserID(userID uint) ([]models.Ride, error) {
+	var rides []models.Ride
+	if err := r.db.Where("(rider_id = ? OR driver_id = ?) AND status IN ?", userID, userID,
+		[]models.RideStatus{models.RideStatusPending, models.RideStatusAccepted}).
+		Preload("Passengers").
+		Find(&rides).Error; err != nil {
+		return nil, err
+	}
+	return rides, nil
+}
+
+// GetUpcomingBookings retrieves the user's passenger entries holding seats on shared rides that have not
+// started yet
+func (r *RideRepository) GetUpcomingBookings(userID uint) ([]models.RidePassenger, error) {
+	var passengers []models.RidePassenger
+	if err := r.db.Joins("JOIN rides ON rides.id = ride_passengers.ride_id").
+		Where("ride_passengers.user_id = ? AND ride_passengers.status IN ? AND rides.status = ?", userID,
+			[]models.RideStatus{models.RideStatusPending, models.RideStatusAccepted}, models.RideStatusPending).
+		Find(&passengers).Error; err != nil {
+		return nil, err
+	}
+	return passengers, nil
+}
+
+// distanceSQL returns an SQL expression for the great-circle distance in kilometers between the point in
+// the given latitude and longitude columns and a point passed as the arguments lat, lat, lng
+func distanceSQL(latColumn, lngColumn string) string {
+	return fmt.Sprintf("(6371 * 2 * ASIN(SQRT(LEAST(1, POWER(SIN(RADIANS(%[1]s - ?) / 2), 2) + "+
+		"COS(RADIANS(?)) * COS(RADIANS(%[1]s)) * POWER(SIN(RADIANS(%[2]s - ?) / 2), 2)))))", latColumn, lngColumn)
+}
+
+// withinBox limits the point in the given columns to the bounding box around a search point, so the
+// exact distance is only calculated for nearby rides and the coordinate indexes can be used
+func withinBox(latColumn, lngColumn string, p *models.GeoPoint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		dLat := p.RadiusKm / 111.0
+		dLng := p.RadiusKm / (111.0 * math.Max(math.Cos(p.Lat*math.Pi/180), 0.01))
+		return db.Where(latColumn+" BETWEEN ? AND ?", p.Lat-dLat, p.Lat+dLat).
+			Where(lngColumn+" BETWEEN ? AND ?", p.Lng-dLng, p.Lng+dLng)
+	}
+}
+
+// ridePreferenceColumns maps the attributes of offers checked by preference filters to ride columns
+var ridePreferenceColumns = map[string]string{
+	"smoking":      "pref_smoking",
+	"pets":         "pref_pets",
+	"music":        "pref_music",
+	"conversation": "pref_conversation",
+	"luggage":      "pref_luggage",
+	"women_only":   "pref_women_only",
+	"all_women":    "pref_women_only", // Only women can offer women-only rides
+}
+
+// suitingPreferences limits rides to those that suit the passenger's preferences in the search, or, when the
+// search is for excluded rides, to those that do not
+func suitingPreferences(search models.SharedRideSearch) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		filters := search.Preferences.Filters(search.Gender)
+		if len(filters) == 0 {
+			if search.Excluded {
+				return db.Where("FALSE")
+			}
+			return db
+		}
+		conditions := make([]string, len(filters))
+		args := make([]interface{}, len(filters))
+		for i, filter := range filters {
+			conditions[i] = ridePreferenceColumns[filter.Attribute] + " IN ?"
+			args[i] = filter.Accepted
+		}
+		condition := strings.Join(conditions, " AND ")
+		if search.Excluded {
+			condition = "NOT (" + condition + ")"
+		}
+		return db.Where(condition, args...)
+	}
+}
+
+// SearchSharedRides finds open shared rides visible to the user that match the search and suit the
+// passenger's preferences, best matches first. Searches for excluded rides find the rides that match but do
+// not suit the passenger, with the preferences they fail. Returns one page of matches and the total number
+// of matches.
+func (r *RideRepository) SearchSharedRides(userID uint, search models.SharedRideSearch) ([]models.SharedRideMatch, int64, error) {
+	if search.Mode == models.MatchByRoute {
+		return r.searchSharedRidesByRoute(userID, search)
+	}
+
+	query := r.db.Model(&models.Ride{}).
+		Where("ride_type = ? AND status = ? AND seats_available - seats_booked >= ?",
+			models.RideTypeShared, models.RideStatusPending, search.Seats).
+		Scopes(visibleToUser(userID), suitingPreferences(search))
+
+	originSQL, destinationSQL, departureSQL := "0", "0", "0"
+	var args []interface{}
+
+	if search.Origin != nil {
+		query = query.Scopes(withinBox("pickup_lat", "pickup_lng", search.Origin))
+		originSQL = distanceSQL("pickup_lat", "pickup_lng")
+		args = append(args, search.Origin.Lat, search.Origin.Lat, search.Origin.Lng)
+	}
+	if search.Destination != nil {
+		query = query.Scopes(withinBox("dropoff_lat", "dropoff_lng", search.Destination))
+		destinationSQL = distanceSQL("dropoff_lat", "dropoff_lng")
+		args = append(args, search.Destination.Lat, search.Destination.Lat, search.Destination.Lng)
+	}
+	if search.DepartureTime != nil {
+		query = query.Where("departure_time BETWEEN ? AND ?",
+			search.DepartureTime.Add(-search.TimeWindow), search.DepartureTime.Add(search.TimeWindow))
+		departureSQL = "ABS(EXTRACT(EPOCH FROM departure_time - ?)) / 60"
+		args = append(args, *search.DepartureTime)
+	}
+	query = query.Where("departure_time > ?", time.Now()).
+		Select(fmt.Sprintf("id, %s AS origin_walk_km, %s AS destination_walk_km, %s AS departure_diff_minutes",
+			originSQL, destinationSQL, departureSQL), args...)
+
+	// Filter on the exact distances calculated for the rides within the bounding boxes
+	matches := r.db.Table("(?) AS matches", query)
+	if search.Origin != nil {
+		matches = matches.Where("origin_walk_km <= ?", search.Origin.RadiusKm)
+	}
+	if search.Destination != nil {
+		matches = matches.Where("destination_walk_km <= ?", search.Destination.RadiusKm)
+	}
+
+	matches = matches.Session(&gorm.Session{})
+
+	var total int64
+	if err := matches.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var rows []struct {
+		ID                   uint
+		OriginWalkKm         float64
+		DestinationWalkKm    float64
+		DepartureDiffMinutes float64
+		Score                float64
+	}
+	if err := matches.
+		Select("*, (origin_walk_km + destination_walk_km) * ? + departure_diff_minutes AS score", models.WalkingMinutesPerKm).
+		Order("score, id").
+		Limit(search.PageSize).
+		Offset((search.Page - 1) * search.PageSize).
+		Scan(&rows).Error; err != nil {
+		return nil, 0, err
+	}
+	if len(rows) == 0 {
+		return []models.SharedRideMatch{}, total, nil
+	}
+
+	ids := make([]uint, len(rows))
+	for i, row := range rows {
+		ids[i] = row.ID
+	}
 	var rides []models.Ride
-	if err := r.db.Where("ride_type = ? AND status = ? AND seats_available > seats_booked",
-		models.RideTypeShared, models.RideStatusPending).
+	if err := r.db.Where("id IN ?", ids).Preload("Rider").Preload("Vehicle").Find(&rides).Error; err != nil {
+		return nil, 0, err
+	}
+	ridesByID := make(map[uint]models.Ride, len(rides))
+	for _, ride := range rides {
+		ridesByID[ride.ID] = ride
+	}
+
+	results := make([]models.SharedRideMatch, 0, len(rows))
+	for _, row := range rows {
+		ride, ok := ridesByID[row.ID]
+		if !ok {
+			continue
+		}
+		results = append(results, models.SharedRideMatch{
+			Ride:                 ride,
+			OriginWalkKm:         row.OriginWalkKm,
+			DestinationWalkKm:    row.DestinationWalkKm,
+			DepartureDiffMinutes: row.DepartureDiffMinutes,
+			Score:                row.Score,
+			Conflicts:            search.Preferences.Conflicts(search.Gender, ride.PreferenceOffer()),
+		})
+	}
+	return results, total, nil
+}
+
+// Route matching converts straight-line detours into driving minutes. Roads are longer than straight lines
+// by roughly roadFactor, and detours are driven at the ride's average speed within these bounds.
+const (
+	roadFactor         = 1.3
+	minRouteSpeedKmh   = 15.0
+	maxRouteSpeedKmh   = 80.0
+	maxRouteCandidates = 1000
+)
+
+// rideRoute returns the planned route of a shared ride, or a straight line from pickup to dropoff for rides
+// without one
+func rideRoute(ride *models.Ride) []utils.LatLng {
+	if ride.RoutePolyline != "" {
+		if route, err := utils.DecodePolyline(ride.RoutePolyline); err == nil && len(route) >= 2 {
+			return route
+		}
+	}
+	return []utils.LatLng{{Lat: ride.PickupLat, Lng: ride.PickupLng}, {Lat: ride.DropoffLat, Lng: ride.DropoffLng}}
+}
+
+// rideSpeedKmh returns the average speed of a ride, used to turn detours into minutes
+func rideSpeedKmh(ride *models.Ride) float64 {
+	if ride.Duration <= 0 || ride.Distance <= 0 {
+		return 30
+	}
+	return math.Min(math.Max(ride.Distance/float64(ride.Duration)*60, minRouteSpeedKmh), maxRouteSpeedKmh)
+}
+
+// detourMinutes returns the minutes a ride's driver spends off the route to pick up at origin and drop off
+// at destination
+func detourMinutes(ride *models.Ride, origin, destination utils.LatLng) float64 {
+	detourKm := utils.InsertionDetourKm(rideRoute(ride), origin, destination)
+	return math.Round(detourKm*roadFactor/rideSpeedKmh(ride)*60*10) / 10
+}
+
+// nearRoute limits rides to those whose route bounding box, widened by the longest detour the ride accepts,
+// contains the point. A point further from the route than that cannot be reached within the detour.
+func nearRoute(p *models.GeoPoint) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		// Degrees covered per minute of detour at the highest speed considered
+		kmPerMinute := maxRouteSpeedKmh / 60 / roadFactor
+		dLat := kmPerMinute / 111.0
+		dLng := kmPerMinute / (111.0 * math.Max(math.Cos(p.Lat*math.Pi/180), 0.01))
+		return db.Where("route_min_lat - max_detour_minutes * ? <= ? AND route_max_lat + max_detour_minutes * ? >= ?",
+			dLat, p.Lat, dLat, p.Lat).
+			Where("route_min_lng - max_detour_minutes * ? <= ? AND route_max_lng + max_detour_minutes * ? >= ?",
+				dLng, p.Lng, dLng, p.Lng)
+	}
+}
+
+// searchSharedRidesByRoute finds open shared rides visible to the user whose route can take in the search
+// origin and destination, in that order, within the detour the ride accepts. Preferences are checked as
+// for SearchSharedRides. Best matches first.
+func (r *RideRepository) searchSharedRidesByRoute(userID uint, search models.SharedRideSearch) ([]models.SharedRideMatch, int64, error) {
+	query := r.db.Where("ride_type = ? AND status = ? AND seats_available - seats_booked >= ? AND departure_time > ?",
+		models.RideTypeShared, models.RideStatusPending, search.Seats, time.Now()).
+		Scopes(visibleToUser(userID), nearRoute(search.Origin), nearRoute(search.Destination))
+	if search.DepartureTime != nil {
+		query = query.Where("departure_time BETWEEN ? AND ?",
+			search.DepartureTime.Add(-search.TimeWindow), search.DepartureTime.Add(search.TimeWindow))
+	}
+
+	var candidates []models.Ride
+	if err := query.Order("departure_time").Limit(maxRouteCandidates).Find(&candidates).Error; err != nil {
+		return nil, 0, err
+	}
+
+	origin := utils.LatLng{Lat: search.Origin.Lat, Lng: search.Origin.Lng}
+	destination := utils.LatLng{Lat: search.Destination.Lat, Lng: search.Destination.Lng}
+	var matches []models.SharedRideMatch
+	for _, ride := range candidates {
+		detourMinutes := detourMinutes(&ride, origin, destination)
+		if detourMinutes > float64(ride.MaxDetourMinutes) {
+			continue
+		}
+		conflicts := search.Preferences.Conflicts(search.Gender, ride.PreferenceOffer())
+		if (len(conflicts) > 0) != search.Excluded {
+			continue
+		}
+
+		match := models.SharedRideMatch{Ride: ride, DetourMinutes: &detourMinutes, Conflicts: conflicts}
+		if search.DepartureTime != nil {
+			match.DepartureDiffMinutes = math.Abs(ride.DepartureTime.Sub(*search.DepartureTime).Minutes())
+		}
+		match.Score = detourMinutes + match.DepartureDiffMinutes
+		matches = append(matches, match)
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].Score < matches[j].Score
+	})
+
+	total := int64(len(matches))
+	start := (search.Page - 1) * search.PageSize
+	if start >= len(matches) {
+		return []models.SharedRideMatch{}, total, nil
+	}
+	end := start + search.PageSize
+	if end > len(matches) {
+		end = len(matches)
+	}
+	page := matches[start:end]
+
+	// Load the riders and vehicles of the page only
+	ids := make([]uint, len(page))
+	for i, match := range page {
+		ids[i] = match.Ride.ID
+	}
+	var rides []models.Ride
+	if err := r.db.Where("id IN ?", ids).Preload("Rider").Preload("Vehicle").Find(&rides).Error; err != nil {
+		return nil, 0, err
+	}
+	ridesByID := make(map[uint]models.Ride, len(rides))
+	for _, ride := range rides {
+		ridesByID[ride.ID] = ride
+	}
+	for i := range page {
+		if ride, ok := ridesByID[page[i].Ride.ID]; ok {
+			page[i].Ride = ride
+		}
+	}
+	return page, total, nil
+}
+
+// GetAvailableOnDemandRides retrieves pending on-demand rides without a driver in the given tiers that the
+// driver has not declined, oldest first. Pooled rides come with their pool's open rides and stops.
+func (r *RideRepository) GetAvailableOnDemandRides(driverID uint, tiers []models.RideTier) ([]models.Ride, error) {
+	var rides []models.Ride
+	if len(tiers) == 0 {
+		return rides, nil
+	}
+	if err := r.db.Where("ride_type = ? AND status = ? AND driver_id IS NULL AND tier IN ?",
+		models.RideTypeOnDemand, models.RideStatusPending, tiers).
+		Where("id NOT IN (?)", r.db.Model(&models.RideDecline{}).Select("ride_id").Where("driver_id = ?", driverID)).
+		Order("created_at").
 		Preload("Rider").
+		Preload("Pool.Rides", "status = ? AND driver_id IS NULL", models.RideStatusPending).
+		Preload("Pool.Rides.Rider").
+		Preload("Pool.Stops", func(db *gorm.DB) *gorm.DB { return db.Order("sequence") }).
 		Find(&rides).Error; err != nil {
 		return nil, err
 	}
 	return rides, nil
 }
 
-// GetUpcomingSharedRides retrieves upcoming shared rides
-func (r *RideRepository) GetUpcomingSharedRides() ([]models.Ride, error) {
+// GetUpcomingSharedRides retrieves upcoming shared rides visible to the user
+func (r *RideRepository) GetUpcomingSharedRides(userID uint) ([]models.Ride, error) {
 	var rides []models.Ride
 	now := time.Now()
 	if err := r.db.Where("ride_type = ? AND status = ? AND departure_time > ?",
 		models.RideTypeShared, models.RideStatusPending, now).
+		Scopes(visibleToUser(userID)).
 		Preload("Rider").
+		Preload("Vehicle").
 		Find(&rides).Error; err != nil {
 		return nil, err
 	}
 	return rides, nil
 }
 
+// GetRides retrieves all rides, optionally filtered by status
+func (r *RideRepository) GetRides(status models.RideStatus) ([]models.Ride, error) {
+	var rides []models.Ride
+	query := r.db.Order("created_at DESC")
+	if status != "" {
+		query = query.Where("status = ?", status)
+	}
+	if err := query.Preload("Rider").Preload("Driver").Find(&rides).Error; err != nil {
+		return nil, err
+	}
+	return rides, nil
+}
+
 // UpdateRide updates a ride in the database
 func (r *RideRepository) UpdateRide(ride *models.Ride) error {
 	return r.db.Save(ride).Error
 }
 
-// UpdateRideStatus updates the status of a ride
-func (r *RideRepository) UpdateRideStatus(rideID uint, status models.RideStatus) error {
-	return r.db.Model(&models.Ride{}).Where("id = ?", rideID).Update("status", status).Error
+// UpdateRideStatus moves a ride to a status from one of the given statuses, recording when it started or
+// completed and who cancelled it. The platform commission is taken when the ride is completed. Returns
+// ErrRideStatusTransition if the ride is in none of the given statuses.
+func (r *RideRepository) UpdateRideStatus(rideID uint, from []models.RideStatus, status models.RideStatus, userID uint) error {
+	return updateRideStatus(r.db, rideID, from, status, userID)
+}
+
+// CancelRide cancels a ride from one of the given statuses, as UpdateRideStatus does. Bookings still in
+// progress end with it: active seat holds and open waitlist entries expire and pending join requests are
+// cancelled, and the ride keeps only the seats of its accepted passengers.
+func (r *RideRepository) CancelRide(rideID uint, from []models.RideStatus, userID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := updateRideStatus(tx, rideID, from, models.RideStatusCancelled, userID); err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SeatHold{}).
+			Where("ride_id = ? AND status = ?", rideID, models.SeatHoldActive).
+			Update("status", models.SeatHoldExpired).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RideWaitlistEntry{}).
+			Where("ride_id = ? AND status IN ?", rideID, []models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered}).
+			Updates(map[string]interface{}{"status": models.WaitlistExpired, "offer_expires_at": nil}).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RidePassenger{}).
+			Where("ride_id = ? AND status = ?", rideID, models.RideStatusPending).
+			Updates(map[string]interface{}{"status": models.RideStatusCancelled, "expires_at": nil}).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Ride{}).Where("id = ?", rideID).
+			Update("seats_booked", tx.Model(&models.RidePassenger{}).Select("COALESCE(SUM(seats), 0)").
+				Where("ride_id = ? AND status IN ?", rideID, seatHoldingStatuses)).Error
+	})
+}
+
+// updateRideStatus moves a ride to a status, see UpdateRideStatus
+func updateRideStatus(tx *gorm.DB, rideID uint, from []models.RideStatus, status models.RideStatus, userID uint) error {
+	updates := map[string]interface{}{"status": status}
+	switch status {
+	case models.RideStatusStarted:
+		updates["started_at"] = time.Now()
+	case models.RideStatusCompleted:
+		updates["completed_at"] = time.Now()
+		updates["commission"] = gorm.Expr("ROUND(price * ?, 2)", platformCommissionRate())
+	case models.RideStatusCancelled:
+		updates["cancelled_by_id"] = userID
+	}
+	result := tx.Model(&models.Ride{}).Where("id = ? AND status IN ?", rideID, from).Updates(updates)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return models.ErrRideStatusTransition
+	}
+	return nil
+}
+
+// DeclineRide records that a driver turned down a ride
+func (r *RideRepository) DeclineRide(rideID, driverID uint) error {
+	decline := &models.RideDecline{RideID: rideID, DriverID: driverID}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(decline).Error
 }
 
-// AddPassenger adds a passenger to a shared ride
+// AddTip adds the rider's tip to a completed ride; a ride can be tipped once
+func (r *RideRepository) AddTip(rideID, riderID uint, amount float64) error {
+	result := r.db.Model(&models.Ride{}).
+		Where("id = ? AND rider_id = ? AND status = ? AND driver_id IS NOT NULL AND tip = 0",
+			rideID, riderID, models.RideStatusCompleted).
+		Update("tip", amount)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("ride cannot be tipped")
+	}
+	return nil
+}
+
+// platformCommissionRate returns the share of each fare the platform keeps
+func platformCommissionRate() float64 {
+	rate, err := strconv.ParseFloat(os.Getenv("PLATFORM_COMMISSION_RATE"), 64)
+	if err != nil || rate < 0 || rate > 1 {
+		return 0.20
+	}
+	return rate
+}
+
+// AssignDriver assigns a driver and their vehicle to a pending ride that has no driver yet and marks it accepted
+func (r *RideRepository) AssignDriver(rideID, driverID, vehicleID uint) error {
+	result := r.db.Model(&models.Ride{}).
+		Where("id = ? AND status = ? AND driver_id IS NULL", rideID, models.RideStatusPending).
+		Updates(map[string]interface{}{
+			"driver_id":   driverID,
+			"vehicle_id":  vehicleID,
+			"status":      models.RideStatusAccepted,
+			"accepted_at": time.Now(),
+		})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return errors.New("ride is no longer available")
+	}
+	return nil
+}
+
+// AddPassenger adds a passenger to a shared ride, booking their seats. Pending join requests hold their
+// seats until the ride owner answers.
 func (r *RideRepository) AddPassenger(passenger *models.RidePassenger) error {
-	// Start a transaction
-	tx := r.db.Begin()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		ride, err := bookSeats(tx, passenger)
+		if err != nil {
+			return err
+		}
+
+		// Add the passenger and their stops
+		if err := tx.Create(passenger).Error; err != nil {
+			return err
+		}
+		return addStops(tx, ride, passenger)
+	})
+}
 
-	// Get the ride
+// bookSeats books the seats a passenger asks for on a shared ride, after checking that their stops are within
+// the detour the ride accepts. The ride stays locked until the transaction ends, so concurrent bookings
+// cannot oversell it, and bookings racing a cancellation or departure are refused.
+func bookSeats(tx *gorm.DB, passenger *models.RidePassenger) (*models.Ride, error) {
 	var ride models.Ride
-	if err := tx.First(&ride, passenger.RideID).Error; err != nil {
-		tx.Rollback()
-		return err
+	if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ride, passenger.RideID).Error; err != nil {
+		return nil, err
 	}
-
-	// Check if there are enough seats available
-	if ride.SeatsBooked+passenger.Seats > ride.SeatsAvailable {
-		tx.Rollback()
-		return errors.New("not enough seats available")
+	if ride.Status != models.RideStatusPending || !ride.DepartureTime.After(time.Now()) {
+		return nil, models.ErrRideNotOpen
 	}
 
-	// Update the ride's booked seats
-	if err := tx.Model(&ride).Update("seats_booked", ride.SeatsBooked+passenger.Seats).Error; err != nil {
-		tx.Rollback()
-		return err
+	// Stops of the passenger's own must be within the detour the ride accepts
+	pickup := utils.LatLng{Lat: passenger.PickupLat, Lng: passenger.PickupLng}
+	dropoff := utils.LatLng{Lat: passenger.DropoffLat, Lng: passenger.DropoffLng}
+	if detourMinutes(&ride, pickup, dropoff) > float64(ride.MaxDetourMinutes) {
+		return nil, models.ErrDetourTooLong
 	}
 
-	// Add the passenger
-	if err := tx.Create(passenger).Error; err != nil {
-		tx.Rollback()
-		return err
+	// Seats are only booked while enough are free
+	result := tx.Model(&models.Ride{}).
+		Where("id = ? AND seats_booked + ? <= seats_available", ride.ID, passenger.Seats).
+		Update("seats_booked", gorm.Expr("seats_booked + ?", passenger.Seats))
+	if result.Error != nil {
+		return nil, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil, models.ErrNotEnoughSeats
 	}
+	ride.SeatsBooked += passenger.Seats
+	return &ride, nil
+}
 
-	// Commit the transaction
-	return tx.Commit().Error
+// releaseSeats returns seats booked by a passenger to the ride
+func releaseSeats(tx *gorm.DB, rideID uint, seats int) error {
+	return tx.Model(&models.Ride{}).Where("id = ?", rideID).
+		Update("seats_booked", gorm.Expr("GREATEST(seats_booked - ?, 0)", seats)).Error
 }
 
-// RemovePassenger removes a passenger from a shared ride
+// RemovePassenger removes a passenger from a shared ride, releasing any seats they hold
 func (r *RideRepository) RemovePassenger(passengerID uint) error {
-	// Start a transaction
-	tx := r.db.Begin()
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Get the passenger
+		var passenger models.RidePassenger
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&passenger, passengerID).Error; err != nil {
+			return err
+		}
+
+		// Declined and expired requests no longer hold seats
+		if passenger.Status.HoldsSeats() {
+			if err := releaseSeats(tx, passenger.RideID, passenger.Seats); err != nil {
+				return err
+			}
+		}
+
+		// Delete the passenger and their stops
+		if err := tx.Where("passenger_id = ?", passenger.ID).Delete(&models.RideStop{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&passenger).Error
+	})
+}
 
-	// Get the passenger
+// GetPassengerByID retrieves a passenger of a shared ride with their ride
+func (r *RideRepository) GetPassengerByID(id uint) (*models.RidePassenger, error) {
 	var passenger models.RidePassenger
-	if err := tx.First(&passenger, passengerID).Error; err != nil {
-		tx.Rollback()
-		return err
+	if err := r.db.Preload("User").Preload("Ride").First(&passenger, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("passenger not found")
+		}
+		return nil, err
 	}
+	return &passenger, nil
+}
 
-	// Get the ride
-	var ride models.Ride
-	if err := tx.First(&ride, passenger.RideID).Error; err != nil {
-		tx.Rollback()
-		return err
+// RespondToJoinRequest accepts or declines a pending join request. Declined requests release their seats.
+func (r *RideRepository) RespondToJoinRequest(passenger *models.RidePassenger, accept bool) error {
+	status := models.RideStatusDeclined
+	if accept {
+		status = models.RideStatusAccepted
 	}
 
-	// Update the ride's booked seats
-	if err := tx.Model(&ride).Update("seats_booked", ride.SeatsBooked-passenger.Seats).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&models.RidePassenger{}).
+			Where("id = ? AND status = ?", passenger.ID, models.RideStatusPending).
+			Updates(map[string]interface{}{"status": status, "expires_at": nil})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return models.ErrJoinRequestNotPending
+		}
+		if !accept {
+			if err := releaseSeats(tx, passenger.RideID, passenger.Seats); err != nil {
+				return err
+			}
+		}
+		passenger.Status = status
+		passenger.ExpiresAt = nil
+		return nil
+	})
+}
 
-	// Delete the passenger
-	if err := tx.Delete(&passenger).Error; err != nil {
-		tx.Rollback()
-		return err
+// ExpireJoinRequests marks pending join requests the ride owner did not answer in time as expired, releasing
+// their seats. Returns the expired requests with their rides.
+func (r *RideRepository) ExpireJoinRequests(now time.Time) ([]models.RidePassenger, error) {
+	var due []models.RidePassenger
+	if err := r.db.Where("status = ? AND expires_at <= ?", models.RideStatusPending, now).
+		Preload("Ride").
+		Find(&due).Error; err != nil {
+		return nil, err
 	}
 
-	// Commit the transaction
-	return tx.Commit().Error
+	var expired []models.RidePassenger
+	for _, passenger := range due {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			// The owner may have answered since the requests were loaded
+			result := tx.Model(&models.RidePassenger{}).
+				Where("id = ? AND status = ?", passenger.ID, models.RideStatusPending).
+				Update("status", models.RideStatusExpired)
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			if err := releaseSeats(tx, passenger.RideID, passenger.Seats); err != nil {
+				return err
+			}
+			passenger.Status = models.RideStatusExpired
+			expired = append(expired, passenger)
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
 }
 
 // GetPassengersByRideID retrieves all passengers for a specific ride
@@ -167,16 +748,43 @@ func (r *RideRepository) GetPassengersByRideID(rideID uint) ([]models.RidePassen
 	return passengers, nil
 }
 
-// AddRating adds a rating for a ride
+// AddRating adds a rating for a ride and updates the rated user's rating aggregates.
+// A concurrent duplicate that slips past HasRated is reported as ErrAlreadyRated.
 func (r *RideRepository) AddRating(rating *models.Rating) error {
-	return r.db.Create(rating).Error
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(rating).Error; err != nil {
+			if isUniqueViolation(err, "idx_ratings_ride_from_to") {
+				return models.ErrAlreadyRated
+			}
+			return err
+		}
+		return updateUserRatings(tx, rating.ToUserID)
+	})
+}
+
+// isUniqueViolation reports whether err is Postgres rejecting a row that duplicates the given unique index
+func isUniqueViolation(err error, constraint string) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == constraint
+}
+
+// HasRated checks if a user already rated another user for a ride
+func (r *RideRepository) HasRated(rideID, fromUserID, toUserID uint) (bool, error) {
+	var count int64
+	if err := r.db.Model(&models.Rating{}).
+		Where("ride_id = ? AND from_user_id = ? AND to_user_id = ?", rideID, fromUserID, toUserID).
+		Count(&count).Error; err != nil {
+		return false, err
+	}
+	return count > 0, nil
 }
 
-// GetRatingsByRideID retrieves all ratings for a specific ride
+// GetRatingsByRideID retrieves all publicly visible ratings for a specific ride
 func (r *RideRepository) GetRatingsByRideID(rideID uint) ([]models.Rating, error) {
 	var ratings []models.Rating
 	result := r.db.Debug().
-		Where("ride_id = ?", rideID).
+		Where("ride_id = ? AND moderation_status <> ?", rideID, models.ModerationPendingReview).
+		Preload("Tags").
 		Preload("FromUser").
 		Preload("ToUser").
 		Preload("Ride").
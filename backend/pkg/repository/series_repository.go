@@ -0,0 +1,221 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type SeriesRepository struct {
+	db *gorm.DB
+}
+
+func NewSeriesRepository() *SeriesRepository {
+	return &SeriesRepository{
+		db: database.GetDB(),
+	}
+}
+
+// CreateSeries creates a ride series with its skip dates
+func (r *SeriesRepository) CreateSeries(series *models.RideSeries) error {
+	return r.db.Create(series).Error
+}
+
+// GetSeriesByID retrieves a ride series with its owner and skip dates
+func (r *SeriesRepository) GetSeriesByID(id uint) (*models.RideSeries, error) {
+	var series models.RideSeries
+	if err := r.db.Preload("Owner").Preload("SkipDates").First(&series, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ride series not found")
+		}
+		return nil, err
+	}
+	return &series, nil
+}
+
+// GetSeriesByOwnerID retrieves the ride series a user offers, newest first
+func (r *SeriesRepository) GetSeriesByOwnerID(ownerID uint) ([]models.RideSeries, error) {
+	var series []models.RideSeries
+	if err := r.db.Where("owner_id = ?", ownerID).
+		Order("created_at DESC").
+		Preload("SkipDates").
+		Find(&series).Error; err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// GetActiveSeries retrieves all series that still create rides
+func (r *SeriesRepository) GetActiveSeries() ([]models.RideSeries, error) {
+	var series []models.RideSeries
+	if err := r.db.Where("is_active = ?", true).Preload("SkipDates").Find(&series).Error; err != nil {
+		return nil, err
+	}
+	return series, nil
+}
+
+// EndSeries stops a series from creating more rides and cancels its upcoming rides. Returns the cancelled rides
+// with their passengers.
+func (r *SeriesRepository) EndSeries(series *models.RideSeries) ([]models.Ride, error) {
+	var cancelled []models.Ride
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(series).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("series_id = ? AND status = ? AND departure_time > ?",
+			series.ID, models.RideStatusPending, time.Now()).
+			Preload("Passengers").
+			Find(&cancelled).Error; err != nil {
+			return err
+		}
+		if len(cancelled) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(cancelled))
+		for i, ride := range cancelled {
+			ids[i] = ride.ID
+		}
+		return tx.Model(&models.Ride{}).Where("id IN ?", ids).Updates(map[string]interface{}{
+			"status":          models.RideStatusCancelled,
+			"cancelled_by_id": series.OwnerID,
+		}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return cancelled, nil
+}
+
+// SkipDate records that a series does not run on a date
+func (r *SeriesRepository) SkipDate(seriesID uint, date time.Time) error {
+	skip := &models.RideSeriesSkip{SeriesID: seriesID, Date: date}
+	return r.db.Clauses(clause.OnConflict{DoNothing: true}).Create(skip).Error
+}
+
+// GetOccurrence retrieves the ride a series created for a date, with its passengers
+func (r *SeriesRepository) GetOccurrence(seriesID uint, date time.Time) (*models.Ride, error) {
+	var ride models.Ride
+	if err := r.db.Where("series_id = ? AND occurrence_date = ?", seriesID, date).
+		Preload("Passengers").
+		First(&ride).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("ride not found")
+		}
+		return nil, err
+	}
+	return &ride, nil
+}
+
+// GetOccurrenceDates returns the dates a series has already created rides for, from the given date on
+func (r *SeriesRepository) GetOccurrenceDates(seriesID uint, from time.Time) (map[string]bool, error) {
+	var dates []time.Time
+	if err := r.db.Model(&models.Ride{}).
+		Where("series_id = ? AND occurrence_date >= ?", seriesID, from.Format(models.DateLayout)).
+		Pluck("occurrence_date", &dates).Error; err != nil {
+		return nil, err
+	}
+	created := make(map[string]bool, len(dates))
+	for _, date := range dates {
+		created[date.Format(models.DateLayout)] = true
+	}
+	return created, nil
+}
+
+// GetUpcomingRides retrieves the upcoming rides of a series that are still open, soonest first
+func (r *SeriesRepository) GetUpcomingRides(seriesID uint) ([]models.Ride, error) {
+	var rides []models.Ride
+	if err := r.db.Where("series_id = ? AND status = ? AND departure_time > ?",
+		seriesID, models.RideStatusPending, time.Now()).
+		Order("departure_time").
+		Find(&rides).Error; err != nil {
+		return nil, err
+	}
+	return rides, nil
+}
+
+// Subscribe subscribes a user to a series, reactivating an earlier subscription
+func (r *SeriesRepository) Subscribe(subscription *models.RideSeriesSubscription) error {
+	subscription.IsActive = true
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "series_id"}, {Name: "user_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"seats", "is_active", "updated_at"}),
+	}).Create(subscription).Error
+}
+
+// GetSubscription retrieves a user's active subscription to a series
+func (r *SeriesRepository) GetSubscription(seriesID, userID uint) (*models.RideSeriesSubscription, error) {
+	var subscription models.RideSeriesSubscription
+	if err := r.db.Where("series_id = ? AND user_id = ? AND is_active = ?", seriesID, userID, true).
+		First(&subscription).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("subscription not found")
+		}
+		return nil, err
+	}
+	return &subscription, nil
+}
+
+// GetActiveSubscriptions retrieves the active subscriptions to a series
+func (r *SeriesRepository) GetActiveSubscriptions(seriesID uint) ([]models.RideSeriesSubscription, error) {
+	var subscriptions []models.RideSeriesSubscription
+	if err := r.db.Where("series_id = ? AND is_active = ?", seriesID, true).
+		Order("created_at").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// GetSubscriptionsByUserID retrieves a user's active subscriptions with their series
+func (r *SeriesRepository) GetSubscriptionsByUserID(userID uint) ([]models.RideSeriesSubscription, error) {
+	var subscriptions []models.RideSeriesSubscription
+	if err := r.db.Where("user_id = ? AND is_active = ?", userID, true).
+		Preload("Series.SkipDates").
+		Find(&subscriptions).Error; err != nil {
+		return nil, err
+	}
+	return subscriptions, nil
+}
+
+// Unsubscribe ends a subscription and removes the passenger from the upcoming rides it booked, releasing their
+// seats. Rides joined individually are kept. Returns the rides the passenger left.
+func (r *SeriesRepository) Unsubscribe(subscription *models.RideSeriesSubscription) ([]uint, error) {
+	var rideIDs []uint
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(subscription).Update("is_active", false).Error; err != nil {
+			return err
+		}
+
+		var passengers []models.RidePassenger
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Joins("JOIN rides ON rides.id = ride_passengers.ride_id").
+			Where("ride_passengers.subscription_id = ? AND rides.status = ? AND rides.departure_time > ?",
+				subscription.ID, models.RideStatusPending, time.Now()).
+			Find(&passengers).Error; err != nil {
+			return err
+		}
+		for _, passenger := range passengers {
+			if passenger.Status.HoldsSeats() {
+				if err := releaseSeats(tx, passenger.RideID, passenger.Seats); err != nil {
+					return err
+				}
+			}
+			if err := tx.Where("passenger_id = ?", passenger.ID).Delete(&models.RideStop{}).Error; err != nil {
+				return err
+			}
+			if err := tx.Delete(&passenger).Error; err != nil {
+				return err
+			}
+			rideIDs = append(rideIDs, passenger.RideID)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return rideIDs, nil
+}
@@ -0,0 +1,193 @@
+package repository
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type StopRepository struct {
+	db *gorm.DB
+}
+
+func NewStopRepository() *StopRepository {
+	return &StopRepository{
+		db: database.GetDB(),
+	}
+}
+
+// seatHoldingStatuses are the passenger statuses with seats booked on the ride, see RideStatus.HoldsSeats
+var seatHoldingStatuses = []models.RideStatus{models.RideStatusPending, models.RideStatusAccepted, models.RideStatusStarted}
+
+// activeStops returns the stops of the passengers holding seats on a ride, in order
+func activeStops(db *gorm.DB, rideID uint) *gorm.DB {
+	return db.Joins("JOIN ride_passengers ON ride_passengers.id = ride_stops.passenger_id").
+		Where("ride_stops.ride_id = ? AND ride_passengers.status IN ?", rideID, seatHoldingStatuses).
+		Order("ride_stops.sequence, ride_stops.id")
+}
+
+// addStops adds a passenger's pickup and dropoff to a ride and puts the ride's stops in order again
+func addStops(tx *gorm.DB, ride *models.Ride, passenger *models.RidePassenger) error {
+	pickup, dropoff := passenger.Stops()
+	if err := tx.Create(&[]models.RideStop{pickup, dropoff}).Error; err != nil {
+		return err
+	}
+	return planStops(tx, ride)
+}
+
+// planStops orders the stops of a ride. By default they are visited in the order they come along the ride's
+// route; rides that optimize their stops take in each passenger, in the order they joined, where their stops
+// add the least distance. A passenger is always picked up before being dropped off.
+func planStops(tx *gorm.DB, ride *models.Ride) error {
+	var stops []models.RideStop
+	if err := activeStops(tx, ride.ID).Find(&stops).Error; err != nil {
+		return err
+	}
+
+	route := rideRoute(ride)
+	var planned []models.RideStop
+	if ride.OptimizeStops {
+		pickups := make(map[uint]models.RideStop)
+		var passengerIDs []uint
+		for _, stop := range stops {
+			if stop.Kind == models.StopPickup {
+				pickups[*stop.PassengerID] = stop
+				passengerIDs = append(passengerIDs, *stop.PassengerID)
+			}
+		}
+		sort.Slice(passengerIDs, func(i, j int) bool { return passengerIDs[i] < passengerIDs[j] })
+
+		for _, passengerID := range passengerIDs {
+			pickup := pickups[passengerID]
+			var dropoff models.RideStop
+			for _, stop := range stops {
+				if stop.Kind == models.StopDropoff && *stop.PassengerID == passengerID {
+					dropoff = stop
+				}
+			}
+
+			path := make([]utils.LatLng, 0, len(planned)+2)
+			path = append(path, route[0])
+			for _, stop := range planned {
+				path = append(path, utils.LatLng{Lat: stop.Lat, Lng: stop.Lng})
+			}
+			path = append(path, route[len(route)-1])
+
+			// Segment i of the path runs from planned[i-1] to planned[i]
+			i, j, _ := utils.InsertionPoints(path,
+				utils.LatLng{Lat: pickup.Lat, Lng: pickup.Lng}, utils.LatLng{Lat: dropoff.Lat, Lng: dropoff.Lng})
+			planned = append(planned[:i], append([]models.RideStop{pickup}, planned[i:]...)...)
+			planned = append(planned[:j+1], append([]models.RideStop{dropoff}, planned[j+1:]...)...)
+		}
+	} else {
+		// A dropoff that comes before its pickup along the route is made right after the pickup
+		progress := make(map[uint]float64, len(stops))
+		pickedUpAt := make(map[uint]float64)
+		for _, stop := range stops {
+			progress[stop.ID] = utils.ProgressKm(route, utils.LatLng{Lat: stop.Lat, Lng: stop.Lng})
+			if stop.Kind == models.StopPickup {
+				pickedUpAt[*stop.PassengerID] = progress[stop.ID]
+			}
+		}
+		for _, stop := range stops {
+			if stop.Kind == models.StopDropoff && progress[stop.ID] < pickedUpAt[*stop.PassengerID] {
+				progress[stop.ID] = pickedUpAt[*stop.PassengerID]
+			}
+		}
+		planned = stops
+		sort.SliceStable(planned, func(a, b int) bool {
+			if progress[planned[a].ID] != progress[planned[b].ID] {
+				return progress[planned[a].ID] < progress[planned[b].ID]
+			}
+			return planned[a].Kind == models.StopPickup && planned[b].Kind == models.StopDropoff
+		})
+	}
+
+	for i, stop := range planned {
+		if stop.Sequence == i+1 {
+			continue
+		}
+		if err := tx.Model(&models.RideStop{}).Where("id = ?", stop.ID).Update("sequence", i+1).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// GetStops retrieves the stops of the passengers holding seats on a ride in order, with their users
+func (r *StopRepository) GetStops(rideID uint) ([]models.RideStop, error) {
+	var stops []models.RideStop
+	if err := activeStops(r.db, rideID).Preload("User").Find(&stops).Error; err != nil {
+		return nil, err
+	}
+	return stops, nil
+}
+
+// GetPoolStops retrieves the stops of a pool's rides that were not cancelled in order, with their users
+func (r *StopRepository) GetPoolStops(poolID uint) ([]models.RideStop, error) {
+	var stops []models.RideStop
+	if err := r.db.Joins("JOIN rides ON rides.id = ride_stops.ride_id").
+		Where("ride_stops.pool_id = ? AND rides.status <> ?", poolID, models.RideStatusCancelled).
+		Order("ride_stops.sequence, ride_stops.id").
+		Preload("User").
+		Find(&stops).Error; err != nil {
+		return nil, err
+	}
+	return stops, nil
+}
+
+// GetStopByID retrieves a stop of a ride
+func (r *StopRepository) GetStopByID(id uint) (*models.RideStop, error) {
+	var stop models.RideStop
+	if err := r.db.Preload("User").First(&stop, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("stop not found")
+		}
+		return nil, err
+	}
+	return &stop, nil
+}
+
+// CheckIn records that the passenger or pooled rider of a stop got in or out. They must be picked up before
+// they can be dropped off.
+func (r *StopRepository) CheckIn(stop *models.RideStop) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current models.RideStop
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, stop.ID).Error; err != nil {
+			return err
+		}
+		if current.CheckedInAt != nil {
+			return models.ErrStopCheckedIn
+		}
+
+		if current.Kind == models.StopDropoff {
+			// A pooled ride has a single rider, so its pickup is found by ride
+			pickups := tx.Model(&models.RideStop{}).Where("kind = ? AND checked_in_at IS NOT NULL", models.StopPickup)
+			if current.PoolID != nil {
+				pickups = pickups.Where("ride_id = ? AND pool_id = ?", current.RideID, *current.PoolID)
+			} else {
+				pickups = pickups.Where("passenger_id = ?", current.PassengerID)
+			}
+			var pickedUp int64
+			if err := pickups.Count(&pickedUp).Error; err != nil {
+				return err
+			}
+			if pickedUp == 0 {
+				return models.ErrPickupNotCheckedIn
+			}
+		}
+
+		now := time.Now()
+		if err := tx.Model(&current).Update("checked_in_at", now).Error; err != nil {
+			return err
+		}
+		stop.CheckedInAt = &now
+		return nil
+	})
+}
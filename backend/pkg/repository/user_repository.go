@@ -2,6 +2,8 @@ package repository
 
 import (
 	"errors"
+	"fmt"
+	"time"
 
 	"github.com/rakeshkumar/ridesapp/pkg/database"
 	"github.com/rakeshkumar/ridesapp/pkg/models"
@@ -52,9 +54,196 @@ func (r *UserRepository) UpdateUser(user *models.User) error {
 	return r.db.Save(user).Error
 }
 
-// DeleteUser deletes a user from the database
+// DeleteUser deletes a user by anonymizing their personal data. The row is kept because
+// rides, passengers and ratings still reference it.
 func (r *UserRepository) DeleteUser(id uint) error {
-	return r.db.Delete(&models.User{}, id).Error
+	return r.AnonymizeUser(id)
+}
+
+// AnonymizeUser removes a user's personal data while keeping ride and financial records intact
+func (r *UserRepository) AnonymizeUser(id uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var user models.User
+		if err := tx.First(&user, id).Error; err != nil {
+			return err
+		}
+		if user.AnonymizedAt != nil {
+			return nil
+		}
+
+		// Replace the profile with placeholders; the password hash is made unusable
+		now := time.Now()
+		if err := tx.Model(&user).Updates(map[string]interface{}{
+			"email":           fmt.Sprintf("deleted-user-%d@deleted.invalid", user.ID),
+			"password":        "!",
+			"first_name":      "Deleted",
+			"last_name":       "User",
+			"phone":           "",
+			"profile_picture": "",
+			"license_number":  "",
+			"gender":          "",
+			"is_verified":     false,
+			"can_drive":       false,
+			"anonymized_at":   now,
+		}).Error; err != nil {
+			return err
+		}
+
+		// Free-text comments the user wrote may identify them
+		if err := tx.Model(&models.Rating{}).Where("from_user_id = ?", user.ID).Update("comment", "").Error; err != nil {
+			return err
+		}
+
+		// Rides and bookings are kept, but not where the user was picked up and dropped off
+		if err := tx.Model(&models.Ride{}).Where("rider_id = ?", user.ID).Updates(map[string]interface{}{
+			"pickup_address":  "",
+			"dropoff_address": "",
+		}).Error; err != nil {
+			return err
+		}
+		blankStops := map[string]interface{}{
+			"pickup_lat": 0, "pickup_lng": 0, "pickup_address": "",
+			"dropoff_lat": 0, "dropoff_lng": 0, "dropoff_address": "",
+		}
+		if err := tx.Model(&models.RidePassenger{}).Where("user_id = ?", user.ID).Updates(blankStops).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.SeatHold{}).Where("user_id = ?", user.ID).Updates(blankStops).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RideStop{}).Where("user_id = ?", user.ID).Updates(map[string]interface{}{
+			"lat": 0, "lng": 0, "address": "",
+		}).Error; err != nil {
+			return err
+		}
+
+		// Vehicles stay linked to past rides but lose their plate
+		if err := tx.Model(&models.Vehicle{}).Where("driver_id = ?", user.ID).Updates(map[string]interface{}{
+			"plate":     "",
+			"is_active": false,
+		}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("driver_id = ?", user.ID).Delete(&models.Vehicle{}).Error; err != nil {
+			return err
+		}
+
+		// Recurring rides stop creating rides and booking the user
+		if err := tx.Model(&models.RideSeries{}).Where("owner_id = ?", user.ID).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		if err := tx.Model(&models.RideSeriesSubscription{}).Where("user_id = ?", user.ID).Update("is_active", false).Error; err != nil {
+			return err
+		}
+
+		// The user leaves every waitlist. Offers were released beforehand; any made since expire on their own.
+		if err := tx.Model(&models.RideWaitlistEntry{}).
+			Where("user_id = ? AND status = ?", user.ID, models.WaitlistWaiting).
+			Update("status", models.WaitlistCancelled).Error; err != nil {
+			return err
+		}
+
+		// Driver documents identify the user; their uploaded files are removed by the caller
+		if err := tx.Where("application_id IN (?)", tx.Model(&models.DriverApplication{}).Select("id").Where("user_id = ?", user.ID)).
+			Delete(&models.DriverDocument{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.DriverApplication{}).Error; err != nil {
+			return err
+		}
+
+		// Location history, destinations, notifications, linked identities and memberships are personal data
+		// with no record-keeping value
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Location{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("driver_id = ?", user.ID).Delete(&models.DriverDestination{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.Notification{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("user_id = ?", user.ID).Delete(&models.UserIdentity{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", user.ID).Delete(&models.OrganizationMember{}).Error
+	})
+}
+
+// ScheduleDeletion marks a user for deletion at the given time, or cancels a pending deletion when dueAt is nil
+func (r *UserRepository) ScheduleDeletion(id uint, dueAt *time.Time) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("deletion_due_at", dueAt).Error
+}
+
+// GetUsersDueForDeletion retrieves users whose cooling-off period has ended
+func (r *UserRepository) GetUsersDueForDeletion() ([]models.User, error) {
+	var users []models.User
+	if err := r.db.Where("deletion_due_at <= ? AND anonymized_at IS NULL", time.Now()).Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// ExportUserData collects all personal data held about a user
+func (r *UserRepository) ExportUserData(id uint) (*models.UserDataExport, error) {
+	user, err := r.GetUserByID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	export := &models.UserDataExport{
+		ExportedAt: time.Now(),
+		Profile:    *user,
+	}
+
+	if err := r.db.Where("rider_id = ? OR driver_id = ?", id, id).Order("created_at").Find(&export.Rides).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Order("created_at").Find(&export.SharedBookings).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("from_user_id = ?", id).Order("created_at").Find(&export.RatingsGiven).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("to_user_id = ?", id).Order("created_at").Find(&export.RatingsReceived).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Order("created_at").Find(&export.Locations).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Find(&export.Identities).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Preload("Organization").Find(&export.Organizations).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("driver_id = ?", id).Preload("Features").Find(&export.Vehicles).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Order("created_at").Find(&export.Notifications).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("owner_id = ?", id).Order("created_at").Preload("SkipDates").Find(&export.RideSeries).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Order("created_at").Find(&export.Subscriptions).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("user_id = ?", id).Order("created_at").Find(&export.Waitlists).Error; err != nil {
+		return nil, err
+	}
+	if err := r.db.Where("driver_id = ?", id).Order("activated_at").Find(&export.Destinations).Error; err != nil {
+		return nil, err
+	}
+	var apps []models.DriverApplication
+	if err := r.db.Where("user_id = ?", id).Preload("Documents").Limit(1).Find(&apps).Error; err != nil {
+		return nil, err
+	}
+	if len(apps) > 0 {
+		export.DriverApplication = &apps[0]
+	}
+
+	return export, nil
 }
 
 // GetDrivers retrieves all active drivers
@@ -65,3 +254,52 @@ func (r *UserRepository) GetDrivers() ([]models.User, error) {
 	}
 	return drivers, nil
 }
+
+// GetUsers retrieves all users, optionally filtered by role
+func (r *UserRepository) GetUsers(role models.UserRole) ([]models.User, error) {
+	var users []models.User
+	query := r.db.Order("id")
+	if role != "" {
+		query = query.Where("role = ?", role)
+	}
+	if err := query.Find(&users).Error; err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// UpdateUserRole changes the role of a user
+func (r *UserRepository) UpdateUserRole(id uint, role models.UserRole) error {
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("role", role).Error
+}
+
+// SetSuspended suspends or reinstates a user
+func (r *UserRepository) SetSuspended(id uint, suspended bool) error {
+	var suspendedAt *time.Time
+	if suspended {
+		now := time.Now()
+		suspendedAt = &now
+	}
+	return r.db.Model(&models.User{}).Where("id = ?", id).Update("suspended_at", suspendedAt).Error
+}
+
+// SetGender changes a user's gender, turning off the women-only preference when it no longer applies
+func (r *UserRepository) SetGender(id uint, gender models.Gender) error {
+	updates := map[string]interface{}{"gender": gender}
+	if gender != models.GenderFemale {
+		updates["pref_women_only"] = false
+	}
+	return r.db.Model(&models.User{}).Where("id = ?", id).Updates(updates).Error
+}
+
+// GetLatestLocation retrieves the last location reported by a user
+func (r *UserRepository) GetLatestLocation(userID uint) (*models.Location, error) {
+	var location models.Location
+	if err := r.db.Where("user_id = ?", userID).Order("created_at DESC").First(&location).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("location not found")
+		}
+		return nil, err
+	}
+	return &location, nil
+}
@@ -0,0 +1,143 @@
+package repository
+
+import (
+	"errors"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type VehicleRepository struct {
+	db *gorm.DB
+}
+
+func NewVehicleRepository() *VehicleRepository {
+	return &VehicleRepository{
+		db: database.GetDB(),
+	}
+}
+
+// openRideStatuses are the statuses of rides that have not finished yet
+var openRideStatuses = []models.RideStatus{models.RideStatusPending, models.RideStatusAccepted, models.RideStatusStarted}
+
+// CreateVehicle registers a vehicle with its features. A driver's first vehicle becomes their active vehicle.
+func (r *VehicleRepository) CreateVehicle(vehicle *models.Vehicle) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var active int64
+		if err := tx.Model(&models.Vehicle{}).Where("driver_id = ? AND is_active = ?", vehicle.DriverID, true).Count(&active).Error; err != nil {
+			return err
+		}
+		vehicle.IsActive = active == 0
+		return tx.Create(vehicle).Error
+	})
+}
+
+// GetVehicleByID retrieves a vehicle with its features
+func (r *VehicleRepository) GetVehicleByID(id uint) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	if err := r.db.Preload("Features").First(&vehicle, id).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("vehicle not found")
+		}
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// GetVehiclesByDriverID retrieves a driver's vehicles, active vehicle first
+func (r *VehicleRepository) GetVehiclesByDriverID(driverID uint) ([]models.Vehicle, error) {
+	var vehicles []models.Vehicle
+	if err := r.db.Where("driver_id = ?", driverID).
+		Order("is_active DESC, created_at").
+		Preload("Features").
+		Find(&vehicles).Error; err != nil {
+		return nil, err
+	}
+	return vehicles, nil
+}
+
+// GetActiveVehicle retrieves the vehicle a driver is currently driving
+func (r *VehicleRepository) GetActiveVehicle(driverID uint) (*models.Vehicle, error) {
+	var vehicle models.Vehicle
+	if err := r.db.Where("driver_id = ? AND is_active = ?", driverID, true).Preload("Features").First(&vehicle).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, models.ErrNoActiveVehicle
+		}
+		return nil, err
+	}
+	return &vehicle, nil
+}
+
+// UpdateVehicle saves a vehicle's details and replaces its features. The seat capacity cannot drop below
+// the seats offered on open shared rides driven with the vehicle.
+func (r *VehicleRepository) UpdateVehicle(vehicle *models.Vehicle) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the vehicle so rides cannot be created against the old capacity meanwhile
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Select("id").First(&models.Vehicle{}, vehicle.ID).Error; err != nil {
+			return err
+		}
+
+		var overbooked int64
+		if err := tx.Model(&models.Ride{}).
+			Where("vehicle_id = ? AND ride_type = ? AND status IN ? AND seats_available > ?",
+				vehicle.ID, models.RideTypeShared, openRideStatuses, vehicle.SeatCapacity).
+			Count(&overbooked).Error; err != nil {
+			return err
+		}
+		if overbooked > 0 {
+			return models.ErrVehicleOverbooked
+		}
+
+		if err := tx.Model(vehicle).Select("make", "model", "year", "color", "plate", "seat_capacity", "class").Updates(vehicle).Error; err != nil {
+			return err
+		}
+
+		if err := tx.Where("vehicle_id = ?", vehicle.ID).Delete(&models.VehicleFeature{}).Error; err != nil {
+			return err
+		}
+		for i := range vehicle.Features {
+			vehicle.Features[i].ID = 0
+			vehicle.Features[i].VehicleID = vehicle.ID
+		}
+		if len(vehicle.Features) > 0 {
+			return tx.Create(&vehicle.Features).Error
+		}
+		return nil
+	})
+}
+
+// SetActiveVehicle makes the given vehicle the driver's active vehicle
+func (r *VehicleRepository) SetActiveVehicle(driverID, vehicleID uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Vehicle{}).
+			Where("driver_id = ? AND is_active = ?", driverID, true).
+			Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Vehicle{}).
+			Where("id = ? AND driver_id = ?", vehicleID, driverID).
+			Update("is_active", true).Error
+	})
+}
+
+// DeleteVehicle removes a vehicle that is not assigned to any open ride. Past rides keep referring to it.
+func (r *VehicleRepository) DeleteVehicle(vehicle *models.Vehicle) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var open int64
+		if err := tx.Model(&models.Ride{}).
+			Where("vehicle_id = ? AND status IN ?", vehicle.ID, openRideStatuses).
+			Count(&open).Error; err != nil {
+			return err
+		}
+		if open > 0 {
+			return models.ErrVehicleInUse
+		}
+
+		if err := tx.Model(vehicle).Update("is_active", false).Error; err != nil {
+			return err
+		}
+		return tx.Delete(vehicle).Error
+	})
+}
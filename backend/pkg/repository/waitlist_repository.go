@@ -0,0 +1,289 @@
+package repository
+
+import (
+	"errors"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/database"
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type WaitlistRepository struct {
+	db *gorm.DB
+}
+
+func NewWaitlistRepository() *WaitlistRepository {
+	return &WaitlistRepository{
+		db: database.GetDB(),
+	}
+}
+
+// openWaitlistStatuses are the statuses of entries still waiting for or holding seats
+var openWaitlistStatuses = []models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered}
+
+// AddToWaitlist puts a passenger at the end of a ride's waitlist
+func (r *WaitlistRepository) AddToWaitlist(entry *models.RideWaitlistEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		// Lock the ride so the same passenger cannot be added twice concurrently
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Ride{}, entry.RideID).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.RideWaitlistEntry{}).
+			Where("ride_id = ? AND user_id = ? AND status IN ?", entry.RideID, entry.UserID, openWaitlistStatuses).
+			Count(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return models.ErrAlreadyWaitlisted
+		}
+
+		entry.Status = models.WaitlistWaiting
+		return tx.Create(entry).Error
+	})
+}
+
+// position sets the entry's place in line among the entries still waiting
+func (r *WaitlistRepository) position(entry *models.RideWaitlistEntry) error {
+	if entry.Status != models.WaitlistWaiting {
+		entry.Position = 0
+		return nil
+	}
+	var ahead int64
+	if err := r.db.Model(&models.RideWaitlistEntry{}).
+		Where("ride_id = ? AND status = ? AND (created_at < ? OR (created_at = ? AND id < ?))",
+			entry.RideID, models.WaitlistWaiting, entry.CreatedAt, entry.CreatedAt, entry.ID).
+		Count(&ahead).Error; err != nil {
+		return err
+	}
+	entry.Position = ahead + 1
+	return nil
+}
+
+// GetOpenEntry retrieves a passenger's entry on a ride's waitlist that is still waiting or on offer, with
+// its position
+func (r *WaitlistRepository) GetOpenEntry(rideID, userID uint) (*models.RideWaitlistEntry, error) {
+	var entry models.RideWaitlistEntry
+	if err := r.db.Where("ride_id = ? AND user_id = ? AND status IN ?", rideID, userID, openWaitlistStatuses).
+		First(&entry).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, errors.New("waitlist entry not found")
+		}
+		return nil, err
+	}
+	if err := r.position(&entry); err != nil {
+		return nil, err
+	}
+	return &entry, nil
+}
+
+// GetOpenEntriesByUserID retrieves the waitlist entries of a user that are still waiting for or holding seats
+func (r *WaitlistRepository) GetOpenEntriesByUserID(userID uint) ([]models.RideWaitlistEntry, error) {
+	var entries []models.RideWaitlistEntry
+	if err := r.db.Where("user_id = ? AND status IN ?", userID,
+		[]models.WaitlistStatus{models.WaitlistWaiting, models.WaitlistOffered}).
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// GetWaitlist retrieves the open entries of a ride's waitlist in order, with their positions
+func (r *WaitlistRepository) GetWaitlist(rideID uint) ([]models.RideWaitlistEntry, error) {
+	var entries []models.RideWaitlistEntry
+	if err := r.db.Where("ride_id = ? AND status IN ?", rideID, openWaitlistStatuses).
+		Order("created_at, id").
+		Preload("User").
+		Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	var position int64
+	for i := range entries {
+		if entries[i].Status == models.WaitlistWaiting {
+			position++
+			entries[i].Position = position
+		}
+	}
+	return entries, nil
+}
+
+// Promote offers the seats free on a ride to its waitlist in order. Entries needing more seats than are left
+// keep their place while later, smaller entries are served. Offered seats are held until the offer is
+// confirmed or expires, at the latest at departure. Returns the entries offered seats.
+func (r *WaitlistRepository) Promote(rideID uint, window time.Duration) ([]models.RideWaitlistEntry, error) {
+	var offered []models.RideWaitlistEntry
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var ride models.Ride
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ride, rideID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if ride.Status != models.RideStatusPending || !ride.DepartureTime.After(now) {
+			return nil
+		}
+
+		free := ride.SeatsAvailable - ride.SeatsBooked
+		if free <= 0 {
+			return nil
+		}
+
+		var waiting []models.RideWaitlistEntry
+		if err := tx.Where("ride_id = ? AND status = ?", rideID, models.WaitlistWaiting).
+			Order("created_at, id").
+			Find(&waiting).Error; err != nil {
+			return err
+		}
+
+		expiresAt := now.Add(window)
+		if ride.DepartureTime.Before(expiresAt) {
+			expiresAt = ride.DepartureTime
+		}
+		for _, entry := range waiting {
+			if free == 0 {
+				break
+			}
+			if entry.Seats > free {
+				continue
+			}
+			if err := tx.Model(&entry).Updates(map[string]interface{}{
+				"status":           models.WaitlistOffered,
+				"offer_expires_at": expiresAt,
+			}).Error; err != nil {
+				return err
+			}
+			free -= entry.Seats
+			entry.Status = models.WaitlistOffered
+			entry.OfferExpiresAt = &expiresAt
+			entry.Ride = ride
+			offered = append(offered, entry)
+		}
+		if len(offered) == 0 {
+			return nil
+		}
+
+		return tx.Model(&ride).Update("seats_booked", ride.SeatsAvailable-free).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return offered, nil
+}
+
+// ConfirmOffer turns the seats held for a waitlist entry into a booking on the ride, as long as the ride
+// has not been cancelled or departed in the meantime
+func (r *WaitlistRepository) ConfirmOffer(entry *models.RideWaitlistEntry, passenger *models.RidePassenger) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var current models.RideWaitlistEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, entry.ID).Error; err != nil {
+			return err
+		}
+		now := time.Now()
+		if current.Status != models.WaitlistOffered || current.OfferExpiresAt == nil || current.OfferExpiresAt.Before(now) {
+			return models.ErrNoWaitlistOffer
+		}
+
+		// The seats are already booked on the ride
+		var ride models.Ride
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&ride, current.RideID).Error; err != nil {
+			return err
+		}
+		if ride.Status != models.RideStatusPending || !ride.DepartureTime.After(now) {
+			return models.ErrNoWaitlistOffer
+		}
+		if err := tx.Create(passenger).Error; err != nil {
+			return err
+		}
+		if err := addStops(tx, &ride, passenger); err != nil {
+			return err
+		}
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"status":           models.WaitlistConfirmed,
+			"offer_expires_at": nil,
+			"passenger_id":     passenger.ID,
+		}).Error; err != nil {
+			return err
+		}
+		entry.Status = models.WaitlistConfirmed
+		entry.OfferExpiresAt = nil
+		entry.PassengerID = &passenger.ID
+		return nil
+	})
+}
+
+// LeaveWaitlist takes a passenger off a waitlist, releasing the seats on offer to them. Returns whether seats
+// were released.
+func (r *WaitlistRepository) LeaveWaitlist(entry *models.RideWaitlistEntry) (bool, error) {
+	released := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		var current models.RideWaitlistEntry
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, entry.ID).Error; err != nil {
+			return err
+		}
+		if !current.IsOpen() {
+			return errors.New("waitlist entry not found")
+		}
+		wasOffered := current.Status == models.WaitlistOffered
+
+		if err := tx.Model(&current).Updates(map[string]interface{}{
+			"status":           models.WaitlistCancelled,
+			"offer_expires_at": nil,
+		}).Error; err != nil {
+			return err
+		}
+		if wasOffered {
+			released = true
+			return releaseSeats(tx, current.RideID, current.Seats)
+		}
+		return nil
+	})
+	return released, err
+}
+
+// ExpireOffers expires the waitlist offers not confirmed in time, releasing their seats. Returns the expired
+// entries with their rides.
+func (r *WaitlistRepository) ExpireOffers(now time.Time) ([]models.RideWaitlistEntry, error) {
+	var due []models.RideWaitlistEntry
+	if err := r.db.Where("status = ? AND offer_expires_at <= ?", models.WaitlistOffered, now).
+		Preload("Ride").
+		Find(&due).Error; err != nil {
+		return nil, err
+	}
+
+	var expired []models.RideWaitlistEntry
+	for _, entry := range due {
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			// The passenger may have confirmed since the offers were loaded
+			result := tx.Model(&models.RideWaitlistEntry{}).
+				Where("id = ? AND status = ?", entry.ID, models.WaitlistOffered).
+				Updates(map[string]interface{}{"status": models.WaitlistExpired, "offer_expires_at": nil})
+			if result.Error != nil || result.RowsAffected == 0 {
+				return result.Error
+			}
+			if err := releaseSeats(tx, entry.RideID, entry.Seats); err != nil {
+				return err
+			}
+			entry.Status = models.WaitlistExpired
+			expired = append(expired, entry)
+			return nil
+		})
+		if err != nil {
+			return expired, err
+		}
+	}
+	return expired, nil
+}
+
+// GetRidesWithFreeSeatsAndWaitlist returns the open rides that have free seats and passengers waiting for them
+func (r *WaitlistRepository) GetRidesWithFreeSeatsAndWaitlist() ([]uint, error) {
+	var rideIDs []uint
+	if err := r.db.Model(&models.Ride{}).
+		Where("status = ? AND departure_time > ? AND seats_available > seats_booked", models.RideStatusPending, time.Now()).
+		Where("id IN (?)", r.db.Model(&models.RideWaitlistEntry{}).Select("ride_id").Where("status = ?", models.WaitlistWaiting)).
+		Pluck("id", &rideIDs).Error; err != nil {
+		return nil, err
+	}
+	return rideIDs, nil
+}
@@ -0,0 +1,113 @@
+package services
+
+import (
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// ProcessAccountDeletions anonymizes accounts whose deletion cooling-off period has ended
+func ProcessAccountDeletions() error {
+	userRepo := repository.NewUserRepository()
+	users, err := userRepo.GetUsersDueForDeletion()
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		if err := DeleteAccount(user.ID); err != nil {
+			log.Printf("Failed to anonymize user %d: %v", user.ID, err)
+			continue
+		}
+		log.Printf("Anonymized user %d after account deletion request", user.ID)
+	}
+	return nil
+}
+
+// DeleteAccount removes a user's personal data once nothing they take part in depends on them: rides they
+// requested, offer or were assigned to drive that have not started are cancelled, and the seats they booked,
+// hold or were offered go back to the rides. Their tokens stop working once the account is anonymized.
+func DeleteAccount(userID uint) error {
+	if err := releaseAccountRides(userID); err != nil {
+		return err
+	}
+
+	// Uploaded driver documents are deleted once their records are gone
+	var documentFiles []string
+	if app, err := repository.NewDriverRepository().GetApplicationByUserID(userID); err == nil {
+		for _, doc := range app.Documents {
+			documentFiles = append(documentFiles, doc.FilePath)
+		}
+	}
+
+	userRepo := repository.NewUserRepository()
+	if err := userRepo.AnonymizeUser(userID); err != nil {
+		return err
+	}
+	for _, path := range documentFiles {
+		if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Printf("Failed to remove driver document %s of user %d: %v", path, userID, err)
+		}
+	}
+	return nil
+}
+
+// releaseAccountRides cancels the user's rides that have not started and releases their seats on others',
+// telling the people affected
+func releaseAccountRides(userID uint) error {
+	rideRepo := repository.NewRideRepository()
+	rides, err := rideRepo.GetOpenRidesByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for i := range rides {
+		ride := &rides[i]
+		// Rides that started or were cancelled since they were loaded are left alone
+		if err := CancelRide(ride, []models.RideStatus{ride.Status}, userID); err != nil && !errors.Is(err, models.ErrRideStatusTransition) {
+			return err
+		}
+	}
+
+	bookings, err := rideRepo.GetUpcomingBookings(userID)
+	if err != nil {
+		return err
+	}
+	for _, passenger := range bookings {
+		if err := rideRepo.RemovePassenger(passenger.ID); err != nil {
+			return err
+		}
+		PromoteWaitlist(passenger.RideID)
+	}
+
+	holdRepo := repository.NewHoldRepository()
+	holds, err := holdRepo.GetActiveHoldsByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for i := range holds {
+		if err := holdRepo.ReleaseHold(&holds[i]); err != nil && !errors.Is(err, models.ErrHoldNotActive) {
+			return err
+		}
+		PromoteWaitlist(holds[i].RideID)
+	}
+
+	waitlistRepo := repository.NewWaitlistRepository()
+	entries, err := waitlistRepo.GetOpenEntriesByUserID(userID)
+	if err != nil {
+		return err
+	}
+	for i := range entries {
+		released, err := waitlistRepo.LeaveWaitlist(&entries[i])
+		if err != nil {
+			return err
+		}
+		if released {
+			PromoteWaitlist(entries[i].RideID)
+		}
+	}
+	return nil
+}
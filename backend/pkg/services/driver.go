@@ -0,0 +1,41 @@
+package services
+
+import (
+	"log"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// EnforceHoursOfService takes drivers offline once they reach the continuous driving or daily online limit.
+// Trips in progress are not interrupted; the driver just stops receiving new rides.
+func EnforceHoursOfService() error {
+	driverRepo := repository.NewDriverRepository()
+	sessions, err := driverRepo.GetOpenSessions()
+	if err != nil {
+		return err
+	}
+
+	for i := range sessions {
+		session := &sessions[i]
+		hours, err := driverRepo.GetHoursOfService(session.DriverID)
+		if err != nil {
+			log.Printf("Failed to check hours of service for driver %d: %v", session.DriverID, err)
+			continue
+		}
+		if hours.RemainingMinutes > 0 {
+			continue
+		}
+
+		reason := models.SessionEndForcedBreak
+		if hours.RemainingDailyMinutes == 0 {
+			reason = models.SessionEndDailyLimit
+		}
+		if err := driverRepo.EndSession(session, reason); err != nil {
+			log.Printf("Failed to take driver %d offline: %v", session.DriverID, err)
+			continue
+		}
+		log.Printf("Took driver %d offline: %s", session.DriverID, reason)
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package services
+
+import (
+	"errors"
+	"net/smtp"
+	"os"
+)
+
+// ErrEmailUnavailable is returned when no mail server is configured
+var ErrEmailUnavailable = errors.New("email delivery is not configured")
+
+// SendEmail sends a plain text email through the mail server configured with SMTP_HOST, SMTP_PORT (default
+// 587), SMTP_USERNAME, SMTP_PASSWORD and SMTP_FROM. The recipient must already be a validated address.
+func SendEmail(to, subject, body string) error {
+	host := os.Getenv("SMTP_HOST")
+	from := os.Getenv("SMTP_FROM")
+	if host == "" || from == "" {
+		return ErrEmailUnavailable
+	}
+	port := os.Getenv("SMTP_PORT")
+	if port == "" {
+		port = "587"
+	}
+
+	var auth smtp.Auth
+	if username := os.Getenv("SMTP_USERNAME"); username != "" {
+		auth = smtp.PlainAuth("", username, os.Getenv("SMTP_PASSWORD"), host)
+	}
+
+	message := "From: " + from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body + "\r\n"
+	return smtp.SendMail(host+":"+port, auth, from, []string{to}, []byte(message))
+}
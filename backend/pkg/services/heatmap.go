@@ -0,0 +1,62 @@
+package services
+
+import (
+	"math"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+	"github.com/rakeshkumar/ridesapp/pkg/utils"
+)
+
+// envMinutes reads a duration in minutes from the environment
+func envMinutes(name string, fallback int) time.Duration {
+	m, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || m <= 0 {
+		m = fallback
+	}
+	return time.Duration(m) * time.Minute
+}
+
+// RefreshDemandHeatmap recomputes the demand heatmap from recent ride requests. Each request counts
+// less as it ages, halving every HEATMAP_HALF_LIFE_MINUTES, and requests older than
+// HEATMAP_WINDOW_MINUTES are ignored.
+func RefreshDemandHeatmap() error {
+	window := envMinutes("HEATMAP_WINDOW_MINUTES", 60)
+	halfLife := envMinutes("HEATMAP_HALF_LIFE_MINUTES", 15)
+
+	heatmapRepo := repository.NewHeatmapRepository()
+	now := time.Now()
+	pickups, err := heatmapRepo.GetPickupsSince(now.Add(-window))
+	if err != nil {
+		return err
+	}
+
+	cells := make(map[string]*models.DemandCell)
+	maxWeight := 0.0
+	for _, p := range pickups {
+		hash := utils.EncodeGeohash(p.PickupLat, p.PickupLng, models.HeatmapPrecision)
+		cell, ok := cells[hash]
+		if !ok {
+			lat, lng := utils.DecodeGeohash(hash)
+			cell = &models.DemandCell{Geohash: hash, Lat: lat, Lng: lng, UpdatedAt: now}
+			cells[hash] = cell
+		}
+
+		age := now.Sub(p.CreatedAt)
+		cell.Requests++
+		cell.Weight += math.Exp2(-age.Minutes() / halfLife.Minutes())
+		if cell.Weight > maxWeight {
+			maxWeight = cell.Weight
+		}
+	}
+
+	result := make([]models.DemandCell, 0, len(cells))
+	for _, cell := range cells {
+		cell.Intensity = cell.Weight / maxWeight
+		result = append(result, *cell)
+	}
+	return heatmapRepo.ReplaceDemandCells(result)
+}
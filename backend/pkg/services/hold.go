@@ -0,0 +1,24 @@
+package services
+
+import (
+	"log"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// SeatHoldDuration returns how long seats stay held while a passenger pays
+func SeatHoldDuration() time.Duration {
+	return envMinutes("SEAT_HOLD_MINUTES", 10)
+}
+
+// ExpireSeatHolds releases the seats of holds not confirmed in time and offers them to the rides' waitlists
+func ExpireSeatHolds() error {
+	holdRepo := repository.NewHoldRepository()
+	expired, err := holdRepo.ExpireHolds(time.Now())
+	for _, hold := range expired {
+		log.Printf("Expired seat hold %d on ride %d", hold.ID, hold.RideID)
+		PromoteWaitlist(hold.RideID)
+	}
+	return err
+}
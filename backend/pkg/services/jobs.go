@@ -0,0 +1,21 @@
+package services
+
+import (
+	"log"
+	"time"
+)
+
+// RunPeriodically runs job immediately and then every interval in the background
+func RunPeriodically(name string, interval time.Duration, job func() error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			if err := job(); err != nil {
+				log.Printf("Job %s failed: %v", name, err)
+			}
+			<-ticker.C
+		}
+	}()
+}
@@ -1 +1 @@
-a
\ No newline at end of file
+package services
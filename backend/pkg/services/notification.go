@@ -1 +1,22 @@
-s
\ No newline at end of file
+package services
+
+import (
+	"log"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// Notify adds a notification to the user's inbox. Notifications are best effort: failures are logged and
+// never fail the action that triggered them.
+func Notify(userID uint, kind models.NotificationType, rideID *uint, message string) {
+	notification := &models.Notification{
+		UserID:  userID,
+		Type:    kind,
+		Message: message,
+		RideID:  rideID,
+	}
+	if err := repository.NewNotificationRepository().CreateNotification(notification); err != nil {
+		log.Printf("Failed to notify user %d (%s): %v", userID, kind, err)
+	}
+}
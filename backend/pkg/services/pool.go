@@ -0,0 +1,53 @@
+package services
+
+import (
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// poolSettings returns the most riders sharing a pool and the longest detour, in minutes, pooling may add to
+// a rider's trip
+func poolSettings() (int, float64) {
+	riders, err := strconv.Atoi(os.Getenv("POOL_MAX_RIDERS"))
+	if err != nil || riders < 2 || riders > 4 {
+		riders = 3
+	}
+	detour, err := strconv.ParseFloat(os.Getenv("POOL_MAX_DETOUR_MINUTES"), 64)
+	if err != nil || detour <= 0 {
+		detour = 10
+	}
+	return riders, detour
+}
+
+// PoolDiscount returns the share of the fare pooled riders save
+func PoolDiscount() float64 {
+	percent, err := strconv.ParseFloat(os.Getenv("POOL_DISCOUNT_PERCENT"), 64)
+	if err != nil || percent < 0 || percent >= 100 {
+		percent = 25
+	}
+	return percent / 100
+}
+
+// CreatePooledRide saves a pooled on-demand ride and matches it into a pool with other riders heading the
+// same way, or starts a new pool for it. Nothing is saved when matching fails. The ride's rider must be loaded.
+func CreatePooledRide(ride *models.Ride) error {
+	maxRiders, maxDetourMinutes := poolSettings()
+	poolRepo := repository.NewPoolRepository()
+	if _, err := poolRepo.CreatePooledRide(ride, maxRiders, maxDetourMinutes); err != nil {
+		log.Printf("Failed to create pooled ride for user %d: %v", ride.RiderID, err)
+		return err
+	}
+	return nil
+}
+
+// LeavePool takes a cancelled pooled ride's stops out of its pool
+func LeavePool(ride *models.Ride) {
+	poolRepo := repository.NewPoolRepository()
+	if err := poolRepo.LeavePool(ride); err != nil {
+		log.Printf("Failed to take ride %d out of pool %d: %v", ride.ID, *ride.PoolID, err)
+	}
+}
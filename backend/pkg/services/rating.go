@@ -0,0 +1,12 @@
+package services
+
+import (
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// RefreshUserRatings recalculates every rated user's stored ratings, dropping ratings that left the
+// RATING_WINDOW_DAYS window
+func RefreshUserRatings() error {
+	ratingRepo := repository.NewRatingRepository()
+	return ratingRepo.RefreshUserRatings()
+}
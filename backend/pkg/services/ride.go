@@ -0,0 +1,58 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// JoinRequestExpiry returns how long ride owners have to answer a join request
+func JoinRequestExpiry() time.Duration {
+	return envMinutes("JOIN_REQUEST_EXPIRY_MINUTES", 60)
+}
+
+// CancelRide cancels a ride on behalf of userID if it is still in one of the given statuses. Seat holds,
+// waitlist entries and join requests on the ride end, passengers and the rider are told, and pooled rides
+// leave their pool. Passengers must be loaded.
+func CancelRide(ride *models.Ride, from []models.RideStatus, userID uint) error {
+	rideRepo := repository.NewRideRepository()
+	if err := rideRepo.CancelRide(ride.ID, from, userID); err != nil {
+		return err
+	}
+
+	if ride.RideType == models.RideTypeShared {
+		NotifyRideCancelled(ride)
+		return nil
+	}
+	if ride.RiderID != userID {
+		message := fmt.Sprintf("Your ride to %s was cancelled", ride.DropoffAddress)
+		if ride.DriverID != nil && *ride.DriverID == userID {
+			message += " by the driver"
+		}
+		Notify(ride.RiderID, models.NotificationRideCancelled, &ride.ID, message)
+	}
+	if ride.PoolID != nil {
+		LeavePool(ride)
+	}
+	return nil
+}
+
+// ExpireJoinRequests expires join requests that ride owners did not answer in time, releasing their seats
+// and letting both sides know
+func ExpireJoinRequests() error {
+	rideRepo := repository.NewRideRepository()
+	expired, err := rideRepo.ExpireJoinRequests(time.Now())
+	for _, passenger := range expired {
+		rideID := passenger.RideID
+		Notify(passenger.UserID, models.NotificationJoinExpired, &rideID,
+			fmt.Sprintf("Your request to join the ride to %s expired without an answer", passenger.Ride.DropoffAddress))
+		Notify(passenger.Ride.RiderID, models.NotificationJoinExpired, &rideID,
+			fmt.Sprintf("A request for %d seat(s) on your ride to %s expired without an answer", passenger.Seats, passenger.Ride.DropoffAddress))
+		log.Printf("Expired join request %d on ride %d", passenger.ID, passenger.RideID)
+		PromoteWaitlist(passenger.RideID)
+	}
+	return err
+}
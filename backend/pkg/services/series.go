@@ -0,0 +1,136 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// envDays reads a number of days from the environment, falling back to a default when unset or invalid
+func envDays(name string, fallback int) time.Duration {
+	d, err := strconv.Atoi(os.Getenv(name))
+	if err != nil || d <= 0 {
+		d = fallback
+	}
+	return time.Duration(d) * 24 * time.Hour
+}
+
+// MaterializeRecurringRides creates the rides of every active series departing within the next
+// RECURRING_RIDES_HORIZON_DAYS days (default 14)
+func MaterializeRecurringRides() error {
+	seriesRepo := repository.NewSeriesRepository()
+	series, err := seriesRepo.GetActiveSeries()
+	if err != nil {
+		return err
+	}
+
+	for i := range series {
+		if err := MaterializeSeries(&series[i]); err != nil {
+			log.Printf("Failed to create rides for series %d: %v", series[i].ID, err)
+		}
+	}
+	return nil
+}
+
+// MaterializeSeries creates the rides of a series departing within the horizon that do not exist yet, and
+// books its subscribers on them. Rides are driven with the owner's active vehicle, and none are created
+// while the owner is not eligible to drive.
+func MaterializeSeries(series *models.RideSeries) error {
+	driverRepo := repository.NewDriverRepository()
+	if err := driverRepo.CheckDriverEligibility(series.OwnerID); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	horizon := envDays("RECURRING_RIDES_HORIZON_DAYS", 14)
+	occurrences := series.Occurrences(now, now.Add(horizon))
+	if len(occurrences) == 0 {
+		return nil
+	}
+
+	seriesRepo := repository.NewSeriesRepository()
+	created, err := seriesRepo.GetOccurrenceDates(series.ID, occurrences[0].Date)
+	if err != nil {
+		return err
+	}
+	subscriptions, err := seriesRepo.GetActiveSubscriptions(series.ID)
+	if err != nil {
+		return err
+	}
+
+	// Rides take the owner's current preferences
+	userRepo := repository.NewUserRepository()
+	owner, err := userRepo.GetUserByID(series.OwnerID)
+	if err != nil {
+		return err
+	}
+
+	vehicleRepo := repository.NewVehicleRepository()
+	rideRepo := repository.NewRideRepository()
+	for _, occurrence := range occurrences {
+		if created[occurrence.Date.Format(models.DateLayout)] {
+			continue
+		}
+
+		vehicle, err := vehicleRepo.GetActiveVehicle(series.OwnerID)
+		if err != nil {
+			return models.ErrNoActiveVehicle
+		}
+		ride := series.NewRide(occurrence)
+		ride.VehicleID = &vehicle.ID
+		ride.Preferences = owner.Preferences
+		if err := rideRepo.CreateRide(ride); err != nil {
+			return err
+		}
+
+		for _, subscription := range subscriptions {
+			BookSubscriber(ride, &subscription)
+		}
+	}
+	return nil
+}
+
+// BookSubscriber books a series subscriber on one of its rides. On manually approved series this sends the
+// owner a join request. Subscribers are told when a ride has no room for them.
+func BookSubscriber(ride *models.Ride, subscription *models.RideSeriesSubscription) {
+	passenger := ride.NewPassenger(subscription.UserID, subscription.Seats, JoinRequestExpiry())
+	passenger.SubscriptionID = &subscription.ID
+
+	// Women-only rides only take women, including subscribers from before the owner asked for them
+	if ride.Preferences.WomenOnly {
+		userRepo := repository.NewUserRepository()
+		if user, err := userRepo.GetUserByID(subscription.UserID); err != nil || user.Gender != models.GenderFemale {
+			Notify(subscription.UserID, models.NotificationSeriesRideFull, &ride.ID,
+				fmt.Sprintf("Your ride to %s on %s could not be booked: it only takes women riders", ride.DropoffAddress, ride.DepartureTime.Format(models.DateLayout)))
+			return
+		}
+	}
+
+	rideRepo := repository.NewRideRepository()
+	if err := rideRepo.AddPassenger(passenger); err != nil {
+		log.Printf("Failed to book subscriber %d on ride %d: %v", subscription.UserID, ride.ID, err)
+		Notify(subscription.UserID, models.NotificationSeriesRideFull, &ride.ID,
+			fmt.Sprintf("Your ride to %s on %s could not be booked", ride.DropoffAddress, ride.DepartureTime.Format(models.DateLayout)))
+		return
+	}
+
+	if passenger.Status == models.RideStatusPending {
+		Notify(ride.RiderID, models.NotificationJoinRequested, &ride.ID,
+			fmt.Sprintf("A subscriber requested %d seat(s) on your ride to %s on %s", passenger.Seats, ride.DropoffAddress, ride.DepartureTime.Format(models.DateLayout)))
+	}
+}
+
+// NotifyRideCancelled tells the passengers holding seats on a ride that its owner cancelled it
+func NotifyRideCancelled(ride *models.Ride) {
+	for _, passenger := range ride.Passengers {
+		if passenger.Status.HoldsSeats() {
+			Notify(passenger.UserID, models.NotificationRideCancelled, &ride.ID,
+				fmt.Sprintf("Your ride to %s on %s was cancelled", ride.DropoffAddress, ride.DepartureTime.Format(models.DateLayout)))
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package services
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+	"github.com/rakeshkumar/ridesapp/pkg/repository"
+)
+
+// WaitlistOfferWindow returns how long promoted waitlist passengers have to confirm the seats offered to them
+func WaitlistOfferWindow() time.Duration {
+	return envMinutes("WAITLIST_OFFER_MINUTES", 15)
+}
+
+// PromoteWaitlist offers the free seats of a ride to its waitlist and lets the promoted passengers know
+func PromoteWaitlist(rideID uint) {
+	waitlistRepo := repository.NewWaitlistRepository()
+	offered, err := waitlistRepo.Promote(rideID, WaitlistOfferWindow())
+	if err != nil {
+		log.Printf("Failed to promote waitlist of ride %d: %v", rideID, err)
+		return
+	}
+	for _, entry := range offered {
+		Notify(entry.UserID, models.NotificationWaitlistOffer, &entry.RideID,
+			fmt.Sprintf("%d seat(s) opened up on the ride to %s. Confirm by %s to keep them.",
+				entry.Seats, entry.Ride.DropoffAddress, entry.OfferExpiresAt.Format(time.RFC3339)))
+	}
+}
+
+// ProcessWaitlists expires waitlist offers that were not confirmed in time and offers free seats to the next
+// passengers in line
+func ProcessWaitlists() error {
+	waitlistRepo := repository.NewWaitlistRepository()
+	expired, err := waitlistRepo.ExpireOffers(time.Now())
+	for _, entry := range expired {
+		Notify(entry.UserID, models.NotificationWaitlistExpired, &entry.RideID,
+			fmt.Sprintf("The seat(s) offered to you on the ride to %s were released because they were not confirmed in time", entry.Ride.DropoffAddress))
+	}
+	if err != nil {
+		return err
+	}
+
+	// Seats freed by expired offers, departing passengers or declined requests go to the next in line
+	rideIDs, err := waitlistRepo.GetRidesWithFreeSeatsAndWaitlist()
+	if err != nil {
+		return err
+	}
+	for _, rideID := range rideIDs {
+		PromoteWaitlist(rideID)
+	}
+	return nil
+}
@@ -0,0 +1,329 @@
+package utils
+
+import (
+	"errors"
+	"math"
+	"strings"
+)
+
+// geohashAlphabet is the base32 alphabet used by geohashes
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// EncodeGeohash returns the geohash of the given precision (number of characters) containing the point
+func EncodeGeohash(lat, lng float64, precision int) string {
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+
+	var hash strings.Builder
+	bits, ch := 0, 0
+	even := true
+	for hash.Len() < precision {
+		if even {
+			mid := (minLng + maxLng) / 2
+			if lng >= mid {
+				ch = ch<<1 | 1
+				minLng = mid
+			} else {
+				ch <<= 1
+				maxLng = mid
+			}
+		} else {
+			mid := (minLat + maxLat) / 2
+			if lat >= mid {
+				ch = ch<<1 | 1
+				minLat = mid
+			} else {
+				ch <<= 1
+				maxLat = mid
+			}
+		}
+		even = !even
+
+		bits++
+		if bits == 5 {
+			hash.WriteByte(geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return hash.String()
+}
+
+// DecodeGeohash returns the center of a geohash cell
+func DecodeGeohash(hash string) (lat, lng float64) {
+	minLat, maxLat := -90.0, 90.0
+	minLng, maxLng := -180.0, 180.0
+
+	even := true
+	for _, c := range hash {
+		idx := strings.IndexRune(geohashAlphabet, c)
+		if idx < 0 {
+			break
+		}
+		for bit := 4; bit >= 0; bit-- {
+			set := idx>>uint(bit)&1 == 1
+			if even {
+				mid := (minLng + maxLng) / 2
+				if set {
+					minLng = mid
+				} else {
+					maxLng = mid
+				}
+			} else {
+				mid := (minLat + maxLat) / 2
+				if set {
+					minLat = mid
+				} else {
+					maxLat = mid
+				}
+			}
+			even = !even
+		}
+	}
+	return (minLat + maxLat) / 2, (minLng + maxLng) / 2
+}
+
+// earthRadiusKm is the mean radius of the Earth
+const earthRadiusKm = 6371.0
+
+// DistanceKm returns the great-circle distance between two points in kilometers
+func DistanceKm(lat1, lng1, lat2, lng2 float64) float64 {
+	toRad := math.Pi / 180
+	dLat := (lat2 - lat1) * toRad
+	dLng := (lng2 - lng1) * toRad
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1*toRad)*math.Cos(lat2*toRad)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusKm * math.Asin(math.Sqrt(a))
+}
+
+// BringsCloser reports whether travelling from one point to another reduces the distance to a destination
+// by at least minProgress, a share of the starting distance between 0 and 1
+func BringsCloser(fromLat, fromLng, toLat, toLng, destLat, destLng, minProgress float64) bool {
+	before := DistanceKm(fromLat, fromLng, destLat, destLng)
+	after := DistanceKm(toLat, toLng, destLat, destLng)
+	return after <= before*(1-minProgress)
+}
+
+// LatLng is a point on the map
+type LatLng struct {
+	Lat float64 `json:"lat"`
+	Lng float64 `json:"lng"`
+}
+
+// EncodePolyline encodes points in the encoded polyline format used by common map APIs
+func EncodePolyline(points []LatLng) string {
+	var b strings.Builder
+	var prevLat, prevLng int64
+	encode := func(v int64) {
+		v <<= 1
+		if v < 0 {
+			v = ^v
+		}
+		for v >= 0x20 {
+			b.WriteByte(byte((0x20 | (v & 0x1f)) + 63))
+			v >>= 5
+		}
+		b.WriteByte(byte(v + 63))
+	}
+	for _, p := range points {
+		lat := int64(math.Round(p.Lat * 1e5))
+		lng := int64(math.Round(p.Lng * 1e5))
+		encode(lat - prevLat)
+		encode(lng - prevLng)
+		prevLat, prevLng = lat, lng
+	}
+	return b.String()
+}
+
+// DecodePolyline decodes a polyline in the encoded polyline format
+func DecodePolyline(polyline string) ([]LatLng, error) {
+	var points []LatLng
+	var lat, lng int64
+	i := 0
+	decode := func() (int64, error) {
+		var result int64
+		var shift uint
+		for {
+			if i >= len(polyline) {
+				return 0, errors.New("truncated polyline")
+			}
+			c := int64(polyline[i]) - 63
+			i++
+			if c < 0 || c > 0x3f {
+				return 0, errors.New("invalid polyline character")
+			}
+			result |= (c & 0x1f) << shift
+			shift += 5
+			if c < 0x20 {
+				break
+			}
+		}
+		if result&1 != 0 {
+			return ^(result >> 1), nil
+		}
+		return result >> 1, nil
+	}
+
+	for i < len(polyline) {
+		dLat, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		dLng, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		lat += dLat
+		lng += dLng
+		points = append(points, LatLng{Lat: float64(lat) / 1e5, Lng: float64(lng) / 1e5})
+	}
+	return points, nil
+}
+
+// distance returns the great-circle distance between two points in kilometers
+func distance(a, b LatLng) float64 {
+	return DistanceKm(a.Lat, a.Lng, b.Lat, b.Lng)
+}
+
+// InsertionDetourKm returns the extra distance travelled along a route to pick up at origin and drop off at
+// destination, inserting each stop into the segment of the route where it adds the least. The pickup is
+// always inserted before the dropoff. Distances are straight-line.
+func InsertionDetourKm(route []LatLng, origin, destination LatLng) float64 {
+	_, _, detour := InsertionPoints(route, origin, destination)
+	return detour
+}
+
+// InsertionPoints returns the segments of a route (by index of their first point) where picking up at origin
+// and dropping off at destination adds the least distance, and the distance added. The dropoff segment is
+// never before the pickup segment; when they are the same, the pickup comes first.
+func InsertionPoints(route []LatLng, origin, destination LatLng) (originSegment, destinationSegment int, detourKm float64) {
+	if len(route) < 2 {
+		return 0, 0, math.Inf(1)
+	}
+
+	// Cost of a stop in each segment, and of both stops in the same segment
+	segments := len(route) - 1
+	originCost := make([]float64, segments)
+	destinationCost := make([]float64, segments)
+	best := math.Inf(1)
+	for i := 0; i < segments; i++ {
+		a, b := route[i], route[i+1]
+		direct := distance(a, b)
+		originCost[i] = distance(a, origin) + distance(origin, b) - direct
+		destinationCost[i] = distance(a, destination) + distance(destination, b) - direct
+		both := distance(a, origin) + distance(origin, destination) + distance(destination, b) - direct
+		if both < best {
+			best, originSegment, destinationSegment = both, i, i
+		}
+	}
+
+	// Pickup in an earlier segment than the dropoff: keep the cheapest pickup seen so far
+	cheapestOrigin, cheapestSegment := math.Inf(1), 0
+	for j := 0; j < segments; j++ {
+		if j > 0 && cheapestOrigin+destinationCost[j] < best {
+			best, originSegment, destinationSegment = cheapestOrigin+destinationCost[j], cheapestSegment, j
+		}
+		if originCost[j] < cheapestOrigin {
+			cheapestOrigin, cheapestSegment = originCost[j], j
+		}
+	}
+	return originSegment, destinationSegment, best
+}
+
+// ProgressKm returns how far along a route the point nearest to p lies, in kilometers from the start
+func ProgressKm(route []LatLng, p LatLng) float64 {
+	best, progress, travelled := math.Inf(1), 0.0, 0.0
+	for i := 0; i+1 < len(route); i++ {
+		a, b := route[i], route[i+1]
+		length := distance(a, b)
+
+		// Project p onto the segment on a local flat approximation
+		scale := math.Cos(a.Lat * math.Pi / 180)
+		dx, dy := (b.Lng-a.Lng)*scale, b.Lat-a.Lat
+		t := 0.0
+		if dx != 0 || dy != 0 {
+			t = ((p.Lng-a.Lng)*scale*dx + (p.Lat-a.Lat)*dy) / (dx*dx + dy*dy)
+			t = math.Min(math.Max(t, 0), 1)
+		}
+		nearest := LatLng{Lat: a.Lat + t*(b.Lat-a.Lat), Lng: a.Lng + t*(b.Lng-a.Lng)}
+		if d := distance(nearest, p); d < best {
+			best, progress = d, travelled+t*length
+		}
+		travelled += length
+	}
+	return progress
+}
+
+// Leg is a trip from a pickup to a dropoff made in a vehicle shared with other legs
+type Leg struct {
+	Pickup     LatLng
+	Dropoff    LatLng
+	MaxExtraKm float64 // How much later than going straight from pickup to dropoff the leg may end, in distance
+}
+
+// SequenceLegs returns the order to visit the stops of the legs in that travels the least, with every pickup
+// before its dropoff. No leg's dropoff may be reached after travelling more than the leg's direct distance
+// plus its MaxExtraKm from the first stop, which limits both the detours of legs on board and the wait of
+// legs picked up later. Stop 2i is the pickup and stop 2i+1 the dropoff of leg i. Returns false when no order
+// keeps every leg within its limit. Orders are searched exhaustively, so only a handful of legs should be
+// sequenced at once.
+func SequenceLegs(legs []Leg) (order []int, totalKm float64, ok bool) {
+	stops := make([]LatLng, 2*len(legs))
+	for i, leg := range legs {
+		stops[2*i], stops[2*i+1] = leg.Pickup, leg.Dropoff
+	}
+
+	best := math.Inf(1)
+	current := make([]int, 0, len(stops))
+	visited := make([]int, len(legs)) // Stops of each leg visited so far
+	var visit func(travelled float64)
+	visit = func(travelled float64) {
+		if travelled >= best {
+			return
+		}
+		if len(current) == len(stops) {
+			best = travelled
+			order = append(order[:0], current...)
+			return
+		}
+		for i, leg := range legs {
+			if visited[i] == 2 {
+				continue
+			}
+			stop := 2*i + visited[i]
+			next := travelled
+			if len(current) > 0 {
+				next += distance(stops[current[len(current)-1]], stops[stop])
+			}
+			if visited[i] == 1 && next-distance(leg.Pickup, leg.Dropoff) > leg.MaxExtraKm {
+				continue
+			}
+			current = append(current, stop)
+			visited[i]++
+			visit(next)
+			visited[i]--
+			current = current[:len(current)-1]
+		}
+	}
+	visit(0)
+
+	if order == nil {
+		return nil, 0, false
+	}
+	return order, best, true
+}
+
+// Bounds returns the bounding box of a route
+func Bounds(route []LatLng) (minLat, maxLat, minLng, maxLng float64) {
+	if len(route) == 0 {
+		return 0, 0, 0, 0
+	}
+	minLat, maxLat = route[0].Lat, route[0].Lat
+	minLng, maxLng = route[0].Lng, route[0].Lng
+	for _, p := range route[1:] {
+		minLat = math.Min(minLat, p.Lat)
+		maxLat = math.Max(maxLat, p.Lat)
+		minLng = math.Min(minLng, p.Lng)
+		maxLng = math.Max(maxLng, p.Lng)
+	}
+	return minLat, maxLat, minLng, maxLng
+}
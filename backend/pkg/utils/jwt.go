@@ -14,6 +14,13 @@ var (
 	ErrExpiredToken = errors.New("token has expired")
 )
 
+// Audiences of the tokens signed with the server's key, so that a token issued for one purpose cannot be
+// used for another
+const (
+	accessTokenAudience = "ridesapp:access"
+	oauthStateAudience  = "ridesapp:oauth_state"
+)
+
 type Claims struct {
 	UserID uint   `json:"user_id"`
 	Email  string `json:"email"`
@@ -21,13 +28,19 @@ type Claims struct {
 	jwt.RegisteredClaims
 }
 
-// GenerateToken creates a new JWT token for the given user
-func GenerateToken(user *models.User) (string, error) {
-	// Get secret key from environment variable
+// getSecretKey returns the key used to sign tokens issued by this server
+func getSecretKey() string {
 	secretKey := os.Getenv("JWT_SECRET_KEY")
 	if secretKey == "" {
 		secretKey = "your-secret-key" // Default secret key for development
 	}
+	return secretKey
+}
+
+// GenerateToken creates a new JWT token for the given user
+func GenerateToken(user *models.User) (string, error) {
+	// Get secret key from environment variable
+	secretKey := getSecretKey()
 
 	// Set expiration time
 	expirationTime := time.Now().Add(24 * time.Hour)
@@ -38,6 +51,7 @@ func GenerateToken(user *models.User) (string, error) {
 		Email:  user.Email,
 		Role:   string(user.Role),
 		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{accessTokenAudience},
 			ExpiresAt: jwt.NewNumericDate(expirationTime),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 		},
@@ -55,18 +69,19 @@ func GenerateToken(user *models.User) (string, error) {
 	return tokenString, nil
 }
 
-// ValidateToken validates the JWT token and returns the claims
+// ValidateToken validates the JWT access token and returns the claims. Other tokens signed with the same key,
+// such as OAuth states, are rejected.
 func ValidateToken(tokenString string) (*Claims, error) {
 	// Get secret key from environment variable
-	secretKey := os.Getenv("JWT_SECRET_KEY")
-	if secretKey == "" {
-		secretKey = "your-secret-key" // Default secret key for development
-	}
+	secretKey := getSecretKey()
 
 	// Parse token
 	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
 		return []byte(secretKey), nil
-	})
+	},
+		jwt.WithValidMethods([]string{"HS256"}),
+		jwt.WithAudience(accessTokenAudience),
+	)
 
 	if err != nil {
 		if errors.Is(err, jwt.ErrTokenExpired) {
@@ -76,7 +91,7 @@ func ValidateToken(tokenString string) (*Claims, error) {
 	}
 
 	// Get claims
-	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
+	if claims, ok := token.Claims.(*Claims); ok && token.Valid && claims.UserID != 0 {
 		return claims, nil
 	}
 
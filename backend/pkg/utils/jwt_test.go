@@ -0,0 +1,40 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/rakeshkumar/ridesapp/pkg/models"
+)
+
+func TestValidateToken(t *testing.T) {
+	token, err := GenerateToken(&models.User{ID: 3, Email: "rider@example.com", Role: models.RoleRider})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+
+	claims, err := ValidateToken(token)
+	if err != nil {
+		t.Fatalf("ValidateToken: %v", err)
+	}
+	if claims.UserID != 3 || claims.Role != string(models.RoleRider) {
+		t.Errorf("got claims %+v", claims)
+	}
+}
+
+func TestTokensCannotBeUsedForAnotherPurpose(t *testing.T) {
+	state, _, err := GenerateOAuthState("google", 0)
+	if err != nil {
+		t.Fatalf("GenerateOAuthState: %v", err)
+	}
+	if _, err := ValidateToken(state); err == nil {
+		t.Errorf("an OAuth state was accepted as an access token")
+	}
+
+	token, err := GenerateToken(&models.User{ID: 3, Role: models.RoleRider})
+	if err != nil {
+		t.Fatalf("GenerateToken: %v", err)
+	}
+	if _, err := ValidateOAuthState(token, "google"); err == nil {
+		t.Errorf("an access token was accepted as an OAuth state")
+	}
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"os"
+	"regexp"
+	"strings"
+)
+
+var (
+	commentEmailRegex = regexp.MustCompile(`(?i)[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,}`)
+	commentPhoneRegex = regexp.MustCompile(`\+?\d[\d\s().\-]{8,}\d`)
+	commentURLRegex   = regexp.MustCompile(`(?i)\b(https?://|www\.)\S+`)
+	commentWordRegex  = regexp.MustCompile(`[\p{L}\p{N}']+`)
+)
+
+// defaultBlockedWords are words that always send a comment to review; extend with MODERATION_BLOCKLIST
+var defaultBlockedWords = []string{
+	"idiot", "stupid", "moron", "retard", "bitch", "bastard", "asshole", "fuck", "shit", "cunt", "whore", "slut",
+}
+
+// ModerateComment checks a rating comment for blocked words and personal information.
+// It returns the reasons the comment should be held for review, or nil if it can be shown.
+func ModerateComment(comment string) []string {
+	if strings.TrimSpace(comment) == "" {
+		return nil
+	}
+
+	var reasons []string
+
+	blocked := blockedWords()
+	for _, word := range commentWordRegex.FindAllString(strings.ToLower(comment), -1) {
+		if blocked[word] {
+			reasons = append(reasons, "blocked_word")
+			break
+		}
+	}
+
+	if commentEmailRegex.MatchString(comment) {
+		reasons = append(reasons, "email_address")
+	}
+	if commentPhoneRegex.MatchString(comment) {
+		reasons = append(reasons, "phone_number")
+	}
+	if commentURLRegex.MatchString(comment) {
+		reasons = append(reasons, "link")
+	}
+
+	return reasons
+}
+
+func blockedWords() map[string]bool {
+	words := make(map[string]bool)
+	for _, w := range defaultBlockedWords {
+		words[w] = true
+	}
+	for _, w := range strings.Split(os.Getenv("MODERATION_BLOCKLIST"), ",") {
+		if w = strings.ToLower(strings.TrimSpace(w)); w != "" {
+			words[w] = true
+		}
+	}
+	return words
+}
@@ -0,0 +1,48 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestModerateComment(t *testing.T) {
+	t.Setenv("MODERATION_BLOCKLIST", " Jerk ,,creep")
+
+	tests := []struct {
+		name    string
+		comment string
+		want    []string
+	}{
+		// Shown as written
+		{"empty", "", nil},
+		{"whitespace", "  \n\t", nil},
+		{"clean", "Friendly driver, clean car and smooth ride.", nil},
+		{"rating with numbers", "10/10, arrived at 8:45 and we made the 9:30 train. 5 stars!", nil},
+		{"distance and price", "Took 25 min for 18 km, paid 12.50 EUR.", nil},
+		{"abbreviations", "Helpful with luggage, e.g. carried my bags, i.e. above and beyond.", nil},
+		{"domain without scheme", "Booked through ridesapp.com, no issues.", nil},
+		{"at sign without domain", "Met @ the station entrance.", nil},
+		{"blocked word inside another", "Stopped in Scunthorpe for classic shitake soup.", nil},
+		{"short number", "Call me on 12345.", nil},
+
+		// Held for review
+		{"blocked word", "The driver was an idiot.", []string{"blocked_word"}},
+		{"blocked word in capitals", "STUPID route choice", []string{"blocked_word"}},
+		{"blocked word from the environment", "What a jerk.", []string{"blocked_word"}},
+		{"second blocked word from the environment", "Total creep", []string{"blocked_word"}},
+		{"email address", "Write to jane.doe+rides@example.co.uk anytime", []string{"email_address"}},
+		{"phone number", "Call me on +49 (30) 123-4567", []string{"phone_number"}},
+		{"phone number without separators", "Text 07700900123 for rides", []string{"phone_number"}},
+		{"http link", "See https://example.com/profile", []string{"link"}},
+		{"www link", "Reviews at www.example.com", []string{"link"}},
+		{"several reasons", "Idiot. Mail me at a@b.io or call 030 1234 5678",
+			[]string{"blocked_word", "email_address", "phone_number"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ModerateComment(tt.comment); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ModerateComment(%q) = %v, want %v", tt.comment, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,361 @@
+package utils
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var (
+	ErrUnknownProvider = errors.New("unknown identity provider")
+	ErrInvalidState    = errors.New("invalid or expired oauth state")
+	ErrInvalidIDToken  = errors.New("invalid id token")
+)
+
+// OAuthStateLifetime is how long a sign in can take between starting it and the provider redirecting back
+const OAuthStateLifetime = 10 * time.Minute
+
+// Default issuers for well-known providers; any provider can be overridden with OIDC_<NAME>_ISSUER
+var defaultIssuers = map[string]string{
+	"google": "https://accounts.google.com",
+	"apple":  "https://appleid.apple.com",
+}
+
+var (
+	oidcProviders   = map[string]*OIDCProvider{}
+	oidcProvidersMu sync.Mutex
+	oidcHTTPClient  = &http.Client{Timeout: 10 * time.Second}
+)
+
+// OIDCProvider is an OpenID Connect identity provider configured from the environment
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+
+	mu        sync.Mutex
+	discovery *oidcDiscovery
+	keys      map[string]*rsa.PublicKey
+}
+
+type oidcDiscovery struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// OIDCClaims are the ID token claims used to find or create a user
+type OIDCClaims struct {
+	Email         string   `json:"email"`
+	EmailVerified flexBool `json:"email_verified"`
+	GivenName     string   `json:"given_name"`
+	FamilyName    string   `json:"family_name"`
+	Picture       string   `json:"picture"`
+	Nonce         string   `json:"nonce"`
+	jwt.RegisteredClaims
+}
+
+// OAuthStateClaims are carried in the signed state parameter of an authorization request
+type OAuthStateClaims struct {
+	Provider   string `json:"provider"`
+	Nonce      string `json:"nonce"`
+	LinkUserID uint   `json:"link_user_id,omitempty"` // Set when an authenticated user is linking a new identity
+	jwt.RegisteredClaims
+}
+
+// flexBool accepts both JSON booleans and the string form some providers (e.g. Apple) use
+type flexBool bool
+
+func (b *flexBool) UnmarshalJSON(data []byte) error {
+	s := strings.Trim(string(data), `"`)
+	*b = flexBool(s == "true")
+	return nil
+}
+
+// GetOIDCProvider returns the configured provider with the given name
+func GetOIDCProvider(name string) (*OIDCProvider, error) {
+	name = strings.ToLower(name)
+
+	oidcProvidersMu.Lock()
+	defer oidcProvidersMu.Unlock()
+
+	if provider, ok := oidcProviders[name]; ok {
+		return provider, nil
+	}
+
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	clientID := os.Getenv(prefix + "CLIENT_ID")
+	if clientID == "" {
+		return nil, ErrUnknownProvider
+	}
+
+	issuer := os.Getenv(prefix + "ISSUER")
+	if issuer == "" {
+		issuer = defaultIssuers[name]
+	}
+	if issuer == "" {
+		return nil, ErrUnknownProvider
+	}
+
+	provider := &OIDCProvider{
+		Name:         name,
+		Issuer:       strings.TrimSuffix(issuer, "/"),
+		ClientID:     clientID,
+		ClientSecret: os.Getenv(prefix + "CLIENT_SECRET"),
+		RedirectURL:  os.Getenv(prefix + "REDIRECT_URL"),
+	}
+	oidcProviders[name] = provider
+	return provider, nil
+}
+
+// AuthCodeURL builds the URL the user is sent to in order to sign in with the provider
+func (p *OIDCProvider) AuthCodeURL(ctx context.Context, state, nonce string) (string, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	params := url.Values{}
+	params.Set("response_type", "code")
+	params.Set("client_id", p.ClientID)
+	params.Set("redirect_uri", p.RedirectURL)
+	params.Set("scope", "openid email profile")
+	params.Set("state", state)
+	params.Set("nonce", nonce)
+
+	return discovery.AuthorizationEndpoint + "?" + params.Encode(), nil
+}
+
+// Exchange trades an authorization code for an ID token and verifies it
+func (p *OIDCProvider) Exchange(ctx context.Context, code, nonce string) (*OIDCClaims, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "authorization_code")
+	form.Set("code", code)
+	form.Set("redirect_uri", p.RedirectURL)
+	form.Set("client_id", p.ClientID)
+	form.Set("client_secret", p.ClientSecret)
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, discovery.TokenEndpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to exchange code: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("token endpoint returned status %d", resp.StatusCode)
+	}
+
+	var tokenResp struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&tokenResp); err != nil {
+		return nil, fmt.Errorf("failed to decode token response: %v", err)
+	}
+	if tokenResp.IDToken == "" {
+		return nil, ErrInvalidIDToken
+	}
+
+	return p.VerifyIDToken(ctx, tokenResp.IDToken, nonce)
+}
+
+// VerifyIDToken checks the signature, issuer, audience, expiry and nonce of an ID token
+func (p *OIDCProvider) VerifyIDToken(ctx context.Context, rawToken, nonce string) (*OIDCClaims, error) {
+	discovery, err := p.getDiscovery(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := &OIDCClaims{}
+	_, err = jwt.ParseWithClaims(rawToken, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return p.getKey(ctx, kid)
+	},
+		jwt.WithValidMethods([]string{"RS256"}),
+		jwt.WithIssuer(discovery.Issuer),
+		jwt.WithAudience(p.ClientID),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		return nil, ErrInvalidIDToken
+	}
+
+	if claims.Subject == "" || claims.Nonce != nonce {
+		return nil, ErrInvalidIDToken
+	}
+
+	return claims, nil
+}
+
+// getDiscovery loads and caches the provider's discovery document
+func (p *OIDCProvider) getDiscovery(ctx context.Context) (*oidcDiscovery, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.discovery != nil {
+		return p.discovery, nil
+	}
+
+	var discovery oidcDiscovery
+	if err := fetchJSON(ctx, p.Issuer+"/.well-known/openid-configuration", &discovery); err != nil {
+		return nil, fmt.Errorf("failed to load provider configuration: %v", err)
+	}
+	if strings.TrimSuffix(discovery.Issuer, "/") != p.Issuer {
+		return nil, fmt.Errorf("provider issuer mismatch: %s", discovery.Issuer)
+	}
+
+	p.discovery = &discovery
+	return p.discovery, nil
+}
+
+// getKey returns the signing key with the given ID, refreshing the key set once if it is unknown
+func (p *OIDCProvider) getKey(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	p.mu.Lock()
+	key, ok := p.keys[kid]
+	jwksURI := p.discovery.JWKSURI
+	p.mu.Unlock()
+
+	if ok {
+		return key, nil
+	}
+
+	var jwks struct {
+		Keys []struct {
+			Kid string `json:"kid"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+			E   string `json:"e"`
+		} `json:"keys"`
+	}
+	if err := fetchJSON(ctx, jwksURI, &jwks); err != nil {
+		return nil, fmt.Errorf("failed to load provider keys: %v", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range jwks.Keys {
+		if k.Kty != "RSA" {
+			continue
+		}
+		n, err := base64.RawURLEncoding.DecodeString(k.N)
+		if err != nil {
+			continue
+		}
+		e, err := base64.RawURLEncoding.DecodeString(k.E)
+		if err != nil {
+			continue
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+
+	p.mu.Lock()
+	p.keys = keys
+	p.mu.Unlock()
+
+	if key, ok := keys[kid]; ok {
+		return key, nil
+	}
+	return nil, ErrInvalidIDToken
+}
+
+func fetchJSON(ctx context.Context, url string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := oidcHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// GenerateOAuthState creates a signed, short-lived state parameter and the nonce bound to it
+func GenerateOAuthState(provider string, linkUserID uint) (string, string, error) {
+	nonce, err := randomString(16)
+	if err != nil {
+		return "", "", err
+	}
+
+	claims := &OAuthStateClaims{
+		Provider:   provider,
+		Nonce:      nonce,
+		LinkUserID: linkUserID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oauthStateAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(OAuthStateLifetime)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	state, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(getSecretKey()))
+	if err != nil {
+		return "", "", err
+	}
+	return state, nonce, nil
+}
+
+// ValidateOAuthState validates a state parameter returned by the provider
+func ValidateOAuthState(state, provider string) (*OAuthStateClaims, error) {
+	claims := &OAuthStateClaims{}
+	token, err := jwt.ParseWithClaims(state, claims, func(token *jwt.Token) (interface{}, error) {
+		return []byte(getSecretKey()), nil
+	}, jwt.WithValidMethods([]string{"HS256"}), jwt.WithAudience(oauthStateAudience))
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidState
+	}
+
+	if claims.Provider != provider || claims.Nonce == "" {
+		return nil, ErrInvalidState
+	}
+	return claims, nil
+}
+
+// RandomPassword returns an unusable random password for accounts created through social login
+func RandomPassword() (string, error) {
+	return randomString(32)
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rakeshkumar/ridesapp/pkg/utils/oidctest"
+)
+
+// signIn starts a sign in with the provider and returns the code and nonce it completes with
+func signIn(t *testing.T, idp *oidctest.Provider, provider *OIDCProvider) (string, string) {
+	t.Helper()
+
+	state, nonce, err := GenerateOAuthState(provider.Name, 0)
+	if err != nil {
+		t.Fatalf("GenerateOAuthState: %v", err)
+	}
+	authURL, err := provider.AuthCodeURL(context.Background(), state, nonce)
+	if err != nil {
+		t.Fatalf("AuthCodeURL: %v", err)
+	}
+	u, _ := url.Parse(authURL)
+	if u.Query().Get("state") != state {
+		t.Fatalf("authorization URL does not carry the state")
+	}
+	return idp.Authorize(authURL), nonce
+}
+
+func TestExchangeVerifiesIDToken(t *testing.T) {
+	idp := oidctest.NewProvider(t)
+	idp.Configure(t, "mockexchange")
+	provider, err := GetOIDCProvider("mockexchange")
+	if err != nil {
+		t.Fatalf("GetOIDCProvider: %v", err)
+	}
+
+	code, nonce := signIn(t, idp, provider)
+	claims, err := provider.Exchange(context.Background(), code, nonce)
+	if err != nil {
+		t.Fatalf("Exchange: %v", err)
+	}
+	if claims.Subject != idp.Subject || claims.Email != idp.Email || !bool(claims.EmailVerified) {
+		t.Errorf("got claims %+v", claims)
+	}
+
+	// Codes are single use
+	if _, err := provider.Exchange(context.Background(), code, nonce); err == nil {
+		t.Errorf("Exchange accepted a code twice")
+	}
+}
+
+func TestExchangeRejectsWrongNonce(t *testing.T) {
+	idp := oidctest.NewProvider(t)
+	idp.Configure(t, "mocknonce")
+	provider, err := GetOIDCProvider("mocknonce")
+	if err != nil {
+		t.Fatalf("GetOIDCProvider: %v", err)
+	}
+
+	idp.Nonce = "replayed-nonce"
+	code, nonce := signIn(t, idp, provider)
+	if _, err := provider.Exchange(context.Background(), code, nonce); !errors.Is(err, ErrInvalidIDToken) {
+		t.Errorf("got %v, want ErrInvalidIDToken", err)
+	}
+}
+
+func TestValidateOAuthState(t *testing.T) {
+	state, nonce, err := GenerateOAuthState("google", 7)
+	if err != nil {
+		t.Fatalf("GenerateOAuthState: %v", err)
+	}
+
+	claims, err := ValidateOAuthState(state, "google")
+	if err != nil {
+		t.Fatalf("ValidateOAuthState: %v", err)
+	}
+	if claims.Nonce != nonce || claims.LinkUserID != 7 {
+		t.Errorf("got claims %+v", claims)
+	}
+
+	if _, err := ValidateOAuthState(state, "apple"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("state for another provider: got %v, want ErrInvalidState", err)
+	}
+}
+
+func TestValidateOAuthStateRejectsExpiredState(t *testing.T) {
+	claims := &OAuthStateClaims{
+		Provider: "google",
+		Nonce:    "nonce",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Audience:  jwt.ClaimStrings{oauthStateAudience},
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(-time.Minute)),
+			IssuedAt:  jwt.NewNumericDate(time.Now().Add(-OAuthStateLifetime - time.Minute)),
+		},
+	}
+	state, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(getSecretKey()))
+	if err != nil {
+		t.Fatalf("failed to sign state: %v", err)
+	}
+
+	if _, err := ValidateOAuthState(state, "google"); !errors.Is(err, ErrInvalidState) {
+		t.Errorf("got %v, want ErrInvalidState", err)
+	}
+}
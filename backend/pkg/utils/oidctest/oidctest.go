@@ -0,0 +1,168 @@
+// Package oidctest runs a mock OpenID Connect identity provider for tests
+package oidctest
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// keyID is the ID of the provider's only signing key
+const keyID = "test-key"
+
+// Provider is a mock identity provider serving discovery, keys and a token endpoint. Authorization codes are
+// handed out by Authorize instead of a sign in page.
+type Provider struct {
+	URL           string
+	ClientID      string
+	Subject       string // Identity the ID tokens are issued for
+	Email         string
+	EmailVerified bool
+	Nonce         string // When set, ID tokens carry this nonce instead of the one the sign in was started with
+
+	t     testing.TB
+	key   *rsa.PrivateKey
+	mu    sync.Mutex
+	codes map[string]string // Nonce of the sign in each authorization code was issued for
+}
+
+// NewProvider starts a mock identity provider that is stopped when the test ends
+func NewProvider(t testing.TB) *Provider {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("failed to generate signing key: %v", err)
+	}
+	p := &Provider{
+		ClientID:      "test-client",
+		Subject:       "subject-1",
+		Email:         "user@example.com",
+		EmailVerified: true,
+		t:             t,
+		key:           key,
+		codes:         make(map[string]string),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.serveDiscovery)
+	mux.HandleFunc("/jwks", p.serveKeys)
+	mux.HandleFunc("/token", p.serveToken)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	p.URL = server.URL
+	return p
+}
+
+// Configure points the identity provider with the given name at the mock for the rest of the test. Providers
+// are cached by name, so each test should use its own.
+func (p *Provider) Configure(t testing.TB, name string) {
+	prefix := "OIDC_" + strings.ToUpper(name) + "_"
+	t.Setenv(prefix+"CLIENT_ID", p.ClientID)
+	t.Setenv(prefix+"CLIENT_SECRET", "test-secret")
+	t.Setenv(prefix+"ISSUER", p.URL)
+	t.Setenv(prefix+"REDIRECT_URL", "http://localhost/api/v1/auth/oauth/"+name+"/callback")
+}
+
+// Authorize signs the user in at the authorization URL and returns the code the provider would redirect
+// back with
+func (p *Provider) Authorize(authURL string) string {
+	p.t.Helper()
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		p.t.Fatalf("invalid authorization URL: %v", err)
+	}
+	query := u.Query()
+	if query.Get("client_id") != p.ClientID || query.Get("nonce") == "" || query.Get("state") == "" {
+		p.t.Fatalf("authorization URL is missing parameters: %s", authURL)
+	}
+
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		p.t.Fatalf("failed to generate code: %v", err)
+	}
+	code := hex.EncodeToString(b)
+
+	p.mu.Lock()
+	p.codes[code] = query.Get("nonce")
+	p.mu.Unlock()
+	return code
+}
+
+func (p *Provider) serveDiscovery(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]string{
+		"issuer":                 p.URL,
+		"authorization_endpoint": p.URL + "/authorize",
+		"token_endpoint":         p.URL + "/token",
+		"jwks_uri":               p.URL + "/jwks",
+	})
+}
+
+func (p *Provider) serveKeys(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kid": keyID,
+			"kty": "RSA",
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *Provider) serveToken(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil || r.PostForm.Get("grant_type") != "authorization_code" ||
+		r.PostForm.Get("client_id") != p.ClientID {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+
+	// Codes can only be used once
+	code := r.PostForm.Get("code")
+	p.mu.Lock()
+	nonce, ok := p.codes[code]
+	delete(p.codes, code)
+	p.mu.Unlock()
+	if !ok {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+	if p.Nonce != "" {
+		nonce = p.Nonce
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":            p.URL,
+		"aud":            p.ClientID,
+		"sub":            p.Subject,
+		"email":          p.Email,
+		"email_verified": p.EmailVerified,
+		"nonce":          nonce,
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(5 * time.Minute).Unix(),
+	})
+	token.Header["kid"] = keyID
+	idToken, err := token.SignedString(p.key)
+	if err != nil {
+		http.Error(w, "server_error", http.StatusInternalServerError)
+		return
+	}
+	writeJSON(w, map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}